@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+
+	"service-poll/migrations"
+	"service-poll/pkg/api"
+	"service-poll/pkg/audit"
+	"service-poll/pkg/client"
+	"service-poll/pkg/db"
+	"service-poll/pkg/definition"
+	"service-poll/pkg/fixtures"
+	"service-poll/pkg/models"
+	"service-poll/pkg/service"
+)
+
+// backend операции, которые pollctl выполняет через HTTP API или напрямую с базой данных.
+// revision в Results — пусто для текущих вопросов, номер ревизии или "merged".
+type backend interface {
+	ListPolls(ctx context.Context, req api.ListPollsRequest) (*api.PollListResponse, error)
+	GetPoll(ctx context.Context, pollID uint) (*api.PollResponse, error)
+	CreatePoll(ctx context.Context, req api.CreatePollRequest) (*models.Poll, error)
+	DeletePoll(ctx context.Context, pollID uint) error
+	AddQuestion(ctx context.Context, pollID uint, req api.AddQuestionRequest) (*models.Question, error)
+	UpdateQuestion(ctx context.Context, pollID, questionID uint, req api.UpdateQuestionRequest, force bool) (*models.Question, error)
+	DeleteQuestion(ctx context.Context, pollID, questionID uint) error
+	ImportPolls(ctx context.Context, definitions []definition.Definition) (*api.ImportPollsResponse, error)
+	PollDefinition(ctx context.Context, pollID uint) (*definition.Definition, error)
+	Results(ctx context.Context, pollID uint, revision string) ([]api.RevisionResult, error)
+	ListTemplates(ctx context.Context) (*api.TemplatesResponse, error)
+	GetTemplate(ctx context.Context, templateID uint) (*api.Template, error)
+	CreateTemplate(ctx context.Context, req api.CreateTemplateRequest) (*api.Template, error)
+	DeleteTemplate(ctx context.Context, templateID uint) error
+	InstantiateTemplate(ctx context.Context, templateID uint, req api.InstantiateTemplateRequest) (*api.PollSummary, error)
+	Migrate(ctx context.Context) error
+	Seed(ctx context.Context, req api.SeedRequest) (*api.SeedResponse, error)
+}
+
+// httpBackend выполняет команды через HTTP API сервиса
+type httpBackend struct {
+	client *client.Client
+}
+
+func (b httpBackend) ListPolls(ctx context.Context, req api.ListPollsRequest) (*api.PollListResponse, error) {
+	return b.client.ListPolls(ctx, req)
+}
+
+func (b httpBackend) GetPoll(ctx context.Context, pollID uint) (*api.PollResponse, error) {
+	return b.client.GetPoll(ctx, pollID)
+}
+
+func (b httpBackend) CreatePoll(ctx context.Context, req api.CreatePollRequest) (*models.Poll, error) {
+	return b.client.CreatePoll(ctx, req)
+}
+
+func (b httpBackend) DeletePoll(ctx context.Context, pollID uint) error {
+	return b.client.DeletePoll(ctx, pollID)
+}
+
+func (b httpBackend) AddQuestion(ctx context.Context, pollID uint, req api.AddQuestionRequest) (*models.Question, error) {
+	return b.client.AddQuestion(ctx, pollID, req)
+}
+
+func (b httpBackend) UpdateQuestion(ctx context.Context, pollID, questionID uint, req api.UpdateQuestionRequest, force bool) (*models.Question, error) {
+	return b.client.UpdateQuestion(ctx, pollID, questionID, req, force)
+}
+
+func (b httpBackend) DeleteQuestion(ctx context.Context, pollID, questionID uint) error {
+	return b.client.DeleteQuestion(ctx, pollID, questionID)
+}
+
+func (b httpBackend) ImportPolls(ctx context.Context, definitions []definition.Definition) (*api.ImportPollsResponse, error) {
+	return b.client.ImportPolls(ctx, definitions)
+}
+
+func (b httpBackend) PollDefinition(ctx context.Context, pollID uint) (*definition.Definition, error) {
+	return b.client.GetPollDefinition(ctx, pollID)
+}
+
+func (b httpBackend) Results(ctx context.Context, pollID uint, revision string) ([]api.RevisionResult, error) {
+	if revision == "" {
+		results, err := b.client.GetPollResults(ctx, pollID)
+		if err != nil {
+			return nil, err
+		}
+		return currentResults(results.Results), nil
+	}
+
+	var results *api.RevisionResultsResponse
+	var err error
+	if revision == service.MergedRevision {
+		results, err = b.client.GetMergedResults(ctx, pollID)
+	} else {
+		var number int
+		if number, err = parseRevision(revision); err == nil {
+			results, err = b.client.GetRevisionResults(ctx, pollID, number)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return results.Results, nil
+}
+
+func (b httpBackend) ListTemplates(ctx context.Context) (*api.TemplatesResponse, error) {
+	return b.client.ListTemplates(ctx)
+}
+
+func (b httpBackend) GetTemplate(ctx context.Context, templateID uint) (*api.Template, error) {
+	return b.client.GetTemplate(ctx, templateID)
+}
+
+func (b httpBackend) CreateTemplate(ctx context.Context, req api.CreateTemplateRequest) (*api.Template, error) {
+	return b.client.CreateTemplate(ctx, req)
+}
+
+func (b httpBackend) DeleteTemplate(ctx context.Context, templateID uint) error {
+	return b.client.DeleteTemplate(ctx, templateID)
+}
+
+func (b httpBackend) InstantiateTemplate(ctx context.Context, templateID uint, req api.InstantiateTemplateRequest) (*api.PollSummary, error) {
+	return b.client.InstantiateTemplate(ctx, templateID, req)
+}
+
+func (b httpBackend) Migrate(ctx context.Context) error {
+	return b.client.Migrate(ctx)
+}
+
+func (b httpBackend) Seed(ctx context.Context, req api.SeedRequest) (*api.SeedResponse, error) {
+	return b.client.Seed(ctx, req)
+}
+
+// dbBackend выполняет команды напрямую с базой данных через pkg/service,
+// изменения записываются в журнал аудита от имени source
+type dbBackend struct {
+	source audit.Source
+}
+
+// audit возвращает контекст с автором изменений для журнала аудита
+func (b dbBackend) audit(ctx context.Context) context.Context {
+	return audit.NewContext(ctx, b.source)
+}
+
+func (b dbBackend) ListPolls(ctx context.Context, req api.ListPollsRequest) (*api.PollListResponse, error) {
+	polls, err := service.ListPolls(ctx, req)
+	return &polls, err
+}
+
+func (b dbBackend) GetPoll(ctx context.Context, pollID uint) (*api.PollResponse, error) {
+	poll, err := service.GetPoll(ctx, pollID)
+	return &poll, err
+}
+
+func (b dbBackend) CreatePoll(ctx context.Context, req api.CreatePollRequest) (*models.Poll, error) {
+	poll, err := service.CreatePoll(b.audit(ctx), req)
+	return &poll, err
+}
+
+func (b dbBackend) DeletePoll(ctx context.Context, pollID uint) error {
+	_, err := service.DeletePoll(b.audit(ctx), pollID)
+	return err
+}
+
+func (b dbBackend) AddQuestion(ctx context.Context, pollID uint, req api.AddQuestionRequest) (*models.Question, error) {
+	question, err := service.AddQuestion(b.audit(ctx), pollID, req)
+	return &question, err
+}
+
+func (b dbBackend) UpdateQuestion(ctx context.Context, pollID, questionID uint, req api.UpdateQuestionRequest, force bool) (*models.Question, error) {
+	question, err := service.UpdateQuestion(b.audit(ctx), pollID, questionID, req, force)
+	return &question, err
+}
+
+func (b dbBackend) DeleteQuestion(ctx context.Context, pollID, questionID uint) error {
+	_, err := service.DeleteQuestion(b.audit(ctx), pollID, questionID)
+	return err
+}
+
+func (b dbBackend) ImportPolls(ctx context.Context, definitions []definition.Definition) (*api.ImportPollsResponse, error) {
+	imported, err := service.ImportPolls(b.audit(ctx), definitions)
+	return &imported, err
+}
+
+func (b dbBackend) PollDefinition(ctx context.Context, pollID uint) (*definition.Definition, error) {
+	pollDefinition, err := service.PollDefinition(ctx, pollID)
+	return &pollDefinition, err
+}
+
+func (b dbBackend) Results(ctx context.Context, pollID uint, revision string) ([]api.RevisionResult, error) {
+	if revision == "" {
+		results, err := service.PollResults(ctx, pollID)
+		if err != nil {
+			return nil, err
+		}
+		return currentResults(results.Results), nil
+	}
+
+	results, err := service.RevisionResults(ctx, pollID, revision)
+	if err != nil {
+		return nil, err
+	}
+	return results.Results, nil
+}
+
+func (b dbBackend) ListTemplates(ctx context.Context) (*api.TemplatesResponse, error) {
+	templates, err := service.ListTemplates(ctx)
+	return &templates, err
+}
+
+func (b dbBackend) GetTemplate(ctx context.Context, templateID uint) (*api.Template, error) {
+	template, err := service.GetTemplate(ctx, templateID)
+	return &template, err
+}
+
+func (b dbBackend) CreateTemplate(ctx context.Context, req api.CreateTemplateRequest) (*api.Template, error) {
+	template, err := service.CreateTemplate(b.audit(ctx), req)
+	return &template, err
+}
+
+func (b dbBackend) DeleteTemplate(ctx context.Context, templateID uint) error {
+	_, err := service.DeleteTemplate(b.audit(ctx), templateID)
+	return err
+}
+
+func (b dbBackend) InstantiateTemplate(ctx context.Context, templateID uint, req api.InstantiateTemplateRequest) (*api.PollSummary, error) {
+	poll, err := service.InstantiateTemplate(b.audit(ctx), templateID, req)
+	return &poll, err
+}
+
+func (b dbBackend) Migrate(ctx context.Context) error {
+	return migrations.Apply()
+}
+
+func (b dbBackend) Seed(ctx context.Context, req api.SeedRequest) (*api.SeedResponse, error) {
+	seeded, err := fixtures.Seed(ctx, db.DB, req)
+	return &seeded, err
+}
+
+// currentResults приводит результаты по текущим вопросам к строкам результатов ревизии
+// (без идентификаторов вопросов и вариантов, которых в них нет)
+func currentResults(results []api.PollResult) []api.RevisionResult {
+	rows := make([]api.RevisionResult, 0, len(results))
+	for _, result := range results {
+		rows = append(rows, api.RevisionResult{
+			Question:         result.Question,
+			Answer:           result.Answer,
+			AnswerCnt:        result.AnswerCnt,
+			AnswerPercentage: result.AnswerPercentage,
+		})
+	}
+	return rows
+}
@@ -0,0 +1,727 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"service-poll/pkg/api"
+	"service-poll/pkg/definition"
+	"service-poll/pkg/fixtures"
+	"service-poll/pkg/service"
+)
+
+// errUsage ошибка аргументов команды; справка уже выведена
+var errUsage = errors.New("invalid usage")
+
+// environment окружение выполнения команды
+type environment struct {
+	backend backend
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+	format  string
+}
+
+// command команда pollctl
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(ctx context.Context, env *environment, args []string) error
+}
+
+// commands команды pollctl в порядке вывода в справке
+var commands = []command{
+	{"poll list", "[-limit N] [-offset N]", "list polls", pollList},
+	{"poll show", "<poll-id>", "show a poll with its questions and options", pollShow},
+	{"poll create", "-title T -url U", "create a poll", pollCreate},
+	{"poll delete", "<poll-id>", "delete a poll with its questions and answers", pollDelete},
+	{"question add", "-text T -type single|multiple -option O... <poll-id>", "add a question to a poll", questionAdd},
+	{"question edit", "[-text T] [-type T] [-option O...] [-force] <poll-id> <question-id>", "edit a question and its options", questionEdit},
+	{"question delete", "<poll-id> <question-id>", "delete a question", questionDelete},
+	{"definition import", "[-format json|yaml] <file|->...", "create polls from definition files", definitionImport},
+	{"definition export", "[-format json|yaml] <poll-id>", "print a poll definition", definitionExport},
+	{"template list", "", "list poll templates", templateList},
+	{"template show", "<template-id>", "show a template with its questions and parameters", templateShow},
+	{"template create", templateCreateArgs, "create a template from a poll or a definition file", templateCreate},
+	{"template delete", "<template-id>", "delete a template", templateDelete},
+	{"template instantiate", "-url U [-param name=value...] [-publish] <template-id>", "create a poll from a template", templateInstantiate},
+	{"results", "[-revision N|merged] <poll-id>", "print poll results", results},
+	{"migrate", "", "create database tables and seed fixtures", migrate},
+	{"seed", seedArgs, "generate polls with synthetic respondents from a fixed seed", seed},
+}
+
+// findCommand находит команду по первым одному или двум словам аргументов
+func findCommand(args []string) (command, []string, bool) {
+	for _, cmd := range commands {
+		words := strings.Fields(cmd.name)
+		if len(args) >= len(words) && strings.Join(args[:len(words)], " ") == cmd.name {
+			return cmd, args[len(words):], true
+		}
+	}
+	return command{}, nil, false
+}
+
+// commandFlags создает набор флагов команды; справка выводится в stderr окружения
+func commandFlags(env *environment, name, args string) *flag.FlagSet {
+	flags := flag.NewFlagSet("pollctl "+name, flag.ContinueOnError)
+	flags.SetOutput(env.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(env.stderr, "Usage: pollctl %s %s\n", name, args)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parseArgs разбирает флаги команды и проверяет количество позиционных аргументов
+func parseArgs(flags *flag.FlagSet, args []string, count int) ([]string, error) {
+	if err := flags.Parse(args); err != nil {
+		return nil, errUsage
+	}
+	if count >= 0 && flags.NArg() != count {
+		flags.Usage()
+		return nil, errUsage
+	}
+	return flags.Args(), nil
+}
+
+// parseID разбирает идентификатор из позиционного аргумента
+func parseID(value, name string) (uint, error) {
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("%s must be a positive integer, got %q", name, value)
+	}
+	return uint(id), nil
+}
+
+// parseRevision разбирает номер ревизии
+func parseRevision(value string) (int, error) {
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 1 {
+		return 0, fmt.Errorf("revision must be a positive integer or %q, got %q", service.MergedRevision, value)
+	}
+	return number, nil
+}
+
+// formatUint форматирует идентификатор для таблицы
+func formatUint(value uint) string {
+	return strconv.FormatUint(uint64(value), 10)
+}
+
+// stringList флаг, который можно указать несколько раз
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// floatList числовой флаг, который можно указать несколько раз
+type floatList []float64
+
+func (l *floatList) String() string {
+	values := make([]string, len(*l))
+	for i, value := range *l {
+		values[i] = strconv.FormatFloat(value, 'g', -1, 64)
+	}
+	return strings.Join(values, ", ")
+}
+
+func (l *floatList) Set(value string) error {
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("must be a number, got %q", value)
+	}
+	*l = append(*l, number)
+	return nil
+}
+
+func pollList(ctx context.Context, env *environment, args []string) error {
+	flags := commandFlags(env, "poll list", "[-limit N] [-offset N]")
+	limit := flags.Int("limit", service.DefaultPollsPageSize, "maximum number of polls (1-100)")
+	offset := flags.Int("offset", 0, "number of polls to skip")
+	if _, err := parseArgs(flags, args, 0); err != nil {
+		return err
+	}
+
+	polls, err := env.backend.ListPolls(ctx, api.ListPollsRequest{Limit: *limit, Offset: *offset})
+	if err != nil {
+		return err
+	}
+
+	t := table{header: []string{"ID", "TITLE", "URL", "REVISION", "QUESTIONS"}, value: polls}
+	for _, poll := range polls.Polls {
+		t.rows = append(t.rows, []string{
+			formatUint(poll.ID), poll.Title, poll.URL, formatUint(poll.Revision), strconv.Itoa(poll.Questions),
+		})
+	}
+	if err := t.write(env.stdout, env.format); err != nil {
+		return err
+	}
+
+	// Сведения о странице не должны попадать в CSV и JSON
+	if env.format == formatTable && int64(*offset+len(polls.Polls)) < polls.Total {
+		fmt.Fprintf(env.stderr, "showing %d-%d of %d polls, use -offset %d for more\n",
+			*offset+1, *offset+len(polls.Polls), polls.Total, *offset+len(polls.Polls))
+	}
+	return nil
+}
+
+func pollShow(ctx context.Context, env *environment, args []string) error {
+	flags := commandFlags(env, "poll show", "<poll-id>")
+	positional, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	pollID, err := parseID(positional[0], "poll-id")
+	if err != nil {
+		return err
+	}
+
+	poll, err := env.backend.GetPoll(ctx, pollID)
+	if err != nil {
+		return err
+	}
+
+	if env.format == formatTable {
+		fmt.Fprintf(env.stdout, "Poll %d: %s\nURL: %s\nRevision: %d\n\n", poll.ID, poll.Title, poll.URL, poll.Revision)
+	}
+
+	t := table{header: []string{"QUESTION_ID", "TYPE", "QUESTION", "OPTION_ID", "OPTION"}, value: poll}
+	for _, question := range poll.Questions {
+		if len(question.PossibleAnswers) == 0 {
+			t.rows = append(t.rows, []string{formatUint(question.ID), question.Type, question.Text, "", ""})
+		}
+		for _, option := range question.PossibleAnswers {
+			t.rows = append(t.rows, []string{
+				formatUint(question.ID), question.Type, question.Text, formatUint(option.ID), option.Text,
+			})
+		}
+	}
+	return t.write(env.stdout, env.format)
+}
+
+func pollCreate(ctx context.Context, env *environment, args []string) error {
+	flags := commandFlags(env, "poll create", "-title T -url U")
+	title := flags.String("title", "", "poll title")
+	url := flags.String("url", "", "poll URL")
+	if _, err := parseArgs(flags, args, 0); err != nil {
+		return err
+	}
+
+	poll, err := env.backend.CreatePoll(ctx, api.CreatePollRequest{Title: *title, URL: *url})
+	if err != nil {
+		return err
+	}
+
+	t := table{header: []string{"ID", "TITLE", "URL"}, value: poll}
+	t.rows = append(t.rows, []string{formatUint(poll.ID), poll.Title, poll.URL})
+	return t.write(env.stdout, env.format)
+}
+
+func pollDelete(ctx context.Context, env *environment, args []string) error {
+	flags := commandFlags(env, "poll delete", "<poll-id>")
+	positional, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	pollID, err := parseID(positional[0], "poll-id")
+	if err != nil {
+		return err
+	}
+
+	if err := env.backend.DeletePoll(ctx, pollID); err != nil {
+		return err
+	}
+	fmt.Fprintf(env.stderr, "poll %d deleted\n", pollID)
+	return nil
+}
+
+func questionAdd(ctx context.Context, env *environment, args []string) error {
+	flags := commandFlags(env, "question add", "-text T -type single|multiple -option O... <poll-id>")
+	text := flags.String("text", "", "question text")
+	questionType := flags.String("type", "single", "question type: single or multiple")
+	var options stringList
+	flags.Var(&options, "option", "answer option text; repeat for each option")
+	positional, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	pollID, err := parseID(positional[0], "poll-id")
+	if err != nil {
+		return err
+	}
+
+	question, err := env.backend.AddQuestion(ctx, pollID, api.AddQuestionRequest{
+		Text:    *text,
+		Type:    *questionType,
+		Answers: options,
+	})
+	if err != nil {
+		return err
+	}
+
+	t := table{header: []string{"ID", "TYPE", "TEXT"}, value: question}
+	t.rows = append(t.rows, []string{formatUint(question.ID), question.Type, question.Text})
+	return t.write(env.stdout, env.format)
+}
+
+func questionEdit(ctx context.Context, env *environment, args []string) error {
+	flags := commandFlags(env, "question edit", "[-text T] [-type T] [-option O...] [-force] <poll-id> <question-id>")
+	text := flags.String("text", "", "new question text; empty keeps the current text")
+	questionType := flags.String("type", "", "new question type; empty keeps the current type")
+	var options stringList
+	flags.Var(&options, "option", "complete ordered option list; repeat for each option. "+
+		"\"#ID\" keeps an option, \"#ID=text\" renames it, any other value adds a new option. "+
+		"Without -option the options are kept")
+	force := flags.Bool("force", false, "allow removing options that already have votes")
+	positional, err := parseArgs(flags, args, 2)
+	if err != nil {
+		return err
+	}
+	pollID, err := parseID(positional[0], "poll-id")
+	if err != nil {
+		return err
+	}
+	questionID, err := parseID(positional[1], "question-id")
+	if err != nil {
+		return err
+	}
+
+	// Незаданные поля берем из текущего состояния вопроса
+	poll, err := env.backend.GetPoll(ctx, pollID)
+	if err != nil {
+		return err
+	}
+	request := api.UpdateQuestionRequest{Text: *text, Type: *questionType}
+	for _, question := range poll.Questions {
+		if question.ID != questionID {
+			continue
+		}
+		if request.Text == "" {
+			request.Text = question.Text
+		}
+		if request.Type == "" {
+			request.Type = question.Type
+		}
+		if len(options) == 0 {
+			for _, option := range question.PossibleAnswers {
+				request.Options = append(request.Options, api.OptionUpdate{ID: option.ID})
+			}
+		}
+	}
+	for _, value := range options {
+		option, err := parseOption(value)
+		if err != nil {
+			return err
+		}
+		request.Options = append(request.Options, option)
+	}
+
+	question, err := env.backend.UpdateQuestion(ctx, pollID, questionID, request, *force)
+	if err != nil {
+		return err
+	}
+
+	t := table{header: []string{"ID", "TYPE", "TEXT"}, value: question}
+	t.rows = append(t.rows, []string{formatUint(question.ID), question.Type, question.Text})
+	return t.write(env.stdout, env.format)
+}
+
+// parseOption разбирает значение -option команды question edit
+func parseOption(value string) (api.OptionUpdate, error) {
+	if !strings.HasPrefix(value, "#") {
+		return api.OptionUpdate{Text: value}, nil
+	}
+
+	idText, text, _ := strings.Cut(value[1:], "=")
+	id, err := parseID(idText, "option id")
+	if err != nil {
+		return api.OptionUpdate{}, err
+	}
+	return api.OptionUpdate{ID: id, Text: text}, nil
+}
+
+func questionDelete(ctx context.Context, env *environment, args []string) error {
+	flags := commandFlags(env, "question delete", "<poll-id> <question-id>")
+	positional, err := parseArgs(flags, args, 2)
+	if err != nil {
+		return err
+	}
+	pollID, err := parseID(positional[0], "poll-id")
+	if err != nil {
+		return err
+	}
+	questionID, err := parseID(positional[1], "question-id")
+	if err != nil {
+		return err
+	}
+
+	if err := env.backend.DeleteQuestion(ctx, pollID, questionID); err != nil {
+		return err
+	}
+	fmt.Fprintf(env.stderr, "question %d deleted\n", questionID)
+	return nil
+}
+
+func definitionImport(ctx context.Context, env *environment, args []string) error {
+	flags := commandFlags(env, "definition import", "[-format json|yaml] <file|->...")
+	format := flags.String("format", "", "definition format; by default taken from the file extension, json for stdin")
+	files, err := parseArgs(flags, args, -1)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		flags.Usage()
+		return errUsage
+	}
+
+	// Все описания из всех файлов создаются в одной транзакции
+	var definitions []definition.Definition
+	for _, file := range files {
+		data, err := readInput(env, file)
+		if err != nil {
+			return err
+		}
+
+		fileFormat := *format
+		if fileFormat == "" {
+			fileFormat = formatFromPath(file)
+		}
+		parsed, err := definition.Parse(data, fileFormat)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		definitions = append(definitions, parsed...)
+	}
+
+	imported, err := env.backend.ImportPolls(ctx, definitions)
+	if err != nil {
+		return err
+	}
+
+	t := table{header: []string{"ID", "TITLE", "URL", "REVISION", "QUESTIONS"}, value: imported}
+	for _, poll := range imported.Polls {
+		t.rows = append(t.rows, []string{
+			formatUint(poll.ID), poll.Title, poll.URL, formatUint(poll.Revision), strconv.Itoa(poll.Questions),
+		})
+	}
+	return t.write(env.stdout, env.format)
+}
+
+// readInput читает файл или стандартный ввод, если указан "-"
+func readInput(env *environment, file string) ([]byte, error) {
+	if file == "-" {
+		return io.ReadAll(env.stdin)
+	}
+	return os.ReadFile(file)
+}
+
+// formatFromPath определяет формат описания по расширению файла
+func formatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return definition.FormatYAML
+	}
+	return definition.FormatJSON
+}
+
+func definitionExport(ctx context.Context, env *environment, args []string) error {
+	flags := commandFlags(env, "definition export", "[-format json|yaml] <poll-id>")
+	format := flags.String("format", definition.FormatYAML, "definition format: json or yaml")
+	positional, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	pollID, err := parseID(positional[0], "poll-id")
+	if err != nil {
+		return err
+	}
+	if *format != definition.FormatJSON && *format != definition.FormatYAML {
+		return fmt.Errorf("unknown definition format %q", *format)
+	}
+
+	pollDefinition, err := env.backend.PollDefinition(ctx, pollID)
+	if err != nil {
+		return err
+	}
+
+	data, err := definition.Marshal(*pollDefinition, *format)
+	if err != nil {
+		return err
+	}
+	_, err = env.stdout.Write(data)
+	return err
+}
+
+func templateList(ctx context.Context, env *environment, args []string) error {
+	flags := commandFlags(env, "template list", "")
+	if _, err := parseArgs(flags, args, 0); err != nil {
+		return err
+	}
+
+	templates, err := env.backend.ListTemplates(ctx)
+	if err != nil {
+		return err
+	}
+
+	t := table{header: []string{"ID", "NAME", "QUESTIONS", "PARAMETERS", "DESCRIPTION"}, value: templates}
+	for _, template := range templates.Templates {
+		t.rows = append(t.rows, []string{
+			formatUint(template.ID), template.Name, strconv.Itoa(template.Questions),
+			strings.Join(template.Parameters, ", "), template.Description,
+		})
+	}
+	return t.write(env.stdout, env.format)
+}
+
+func templateShow(ctx context.Context, env *environment, args []string) error {
+	flags := commandFlags(env, "template show", "<template-id>")
+	positional, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	templateID, err := parseID(positional[0], "template-id")
+	if err != nil {
+		return err
+	}
+
+	template, err := env.backend.GetTemplate(ctx, templateID)
+	if err != nil {
+		return err
+	}
+
+	if env.format == formatTable {
+		fmt.Fprintf(env.stdout, "Template %d: %s\nTitle: %s\nParameters: %s\n\n",
+			template.ID, template.Name, template.Definition.Title, strings.Join(template.Parameters, ", "))
+	}
+
+	t := table{header: []string{"TYPE", "QUESTION", "OPTION"}, value: template}
+	for _, question := range template.Definition.Questions {
+		for _, option := range question.Options {
+			t.rows = append(t.rows, []string{question.Type, question.Text, option})
+		}
+	}
+	return t.write(env.stdout, env.format)
+}
+
+// templateCreateArgs аргументы команды template create
+const templateCreateArgs = "-name N [-description D] [-format json|yaml] (-poll <poll-id> | <file|->)"
+
+func templateCreate(ctx context.Context, env *environment, args []string) error {
+	flags := commandFlags(env, "template create", templateCreateArgs)
+	name := flags.String("name", "", "template name")
+	description := flags.String("description", "", "template description")
+	pollID := flags.Uint("poll", 0, "create the template from this poll instead of a definition file")
+	format := flags.String("format", "", "definition format; by default taken from the file extension, json for stdin")
+	files, err := parseArgs(flags, args, -1)
+	if err != nil {
+		return err
+	}
+	if (*pollID == 0) == (len(files) == 0) || len(files) > 1 {
+		flags.Usage()
+		return errUsage
+	}
+
+	request := api.CreateTemplateRequest{Name: *name, Description: *description, PollID: *pollID}
+	if len(files) == 1 {
+		data, err := readInput(env, files[0])
+		if err != nil {
+			return err
+		}
+
+		fileFormat := *format
+		if fileFormat == "" {
+			fileFormat = formatFromPath(files[0])
+		}
+		parsed, err := definition.Parse(data, fileFormat)
+		if err != nil {
+			return fmt.Errorf("%s: %w", files[0], err)
+		}
+		if len(parsed) != 1 {
+			return fmt.Errorf("%s: a template needs exactly one poll definition, got %d", files[0], len(parsed))
+		}
+		request.Definition = &parsed[0]
+	}
+
+	template, err := env.backend.CreateTemplate(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	t := table{header: []string{"ID", "NAME", "QUESTIONS", "PARAMETERS"}, value: template}
+	t.rows = append(t.rows, []string{
+		formatUint(template.ID), template.Name, strconv.Itoa(template.Questions), strings.Join(template.Parameters, ", "),
+	})
+	return t.write(env.stdout, env.format)
+}
+
+func templateDelete(ctx context.Context, env *environment, args []string) error {
+	flags := commandFlags(env, "template delete", "<template-id>")
+	positional, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	templateID, err := parseID(positional[0], "template-id")
+	if err != nil {
+		return err
+	}
+
+	if err := env.backend.DeleteTemplate(ctx, templateID); err != nil {
+		return err
+	}
+	fmt.Fprintf(env.stderr, "template %d deleted\n", templateID)
+	return nil
+}
+
+func templateInstantiate(ctx context.Context, env *environment, args []string) error {
+	flags := commandFlags(env, "template instantiate", "-url U [-param name=value...] [-publish] <template-id>")
+	url := flags.String("url", "", "URL of the new poll")
+	var params stringList
+	flags.Var(&params, "param", "template parameter as name=value; repeat for each parameter")
+	publish := flags.Bool("publish", false, "publish the new poll")
+	positional, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	templateID, err := parseID(positional[0], "template-id")
+	if err != nil {
+		return err
+	}
+
+	request := api.InstantiateTemplateRequest{URL: *url, Publish: *publish, Params: make(map[string]string, len(params))}
+	for _, param := range params {
+		name, value, ok := strings.Cut(param, "=")
+		if !ok || name == "" {
+			return fmt.Errorf("param must be name=value, got %q", param)
+		}
+		request.Params[name] = value
+	}
+
+	poll, err := env.backend.InstantiateTemplate(ctx, templateID, request)
+	if err != nil {
+		return err
+	}
+
+	t := table{header: []string{"ID", "TITLE", "URL", "REVISION", "QUESTIONS"}, value: poll}
+	t.rows = append(t.rows, []string{
+		formatUint(poll.ID), poll.Title, poll.URL, formatUint(poll.Revision), strconv.Itoa(poll.Questions),
+	})
+	return t.write(env.stdout, env.format)
+}
+
+func results(ctx context.Context, env *environment, args []string) error {
+	flags := commandFlags(env, "results", "[-revision N|merged] <poll-id>")
+	revision := flags.String("revision", "", "revision number, or \"merged\" to combine all revisions; by default current questions")
+	positional, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	pollID, err := parseID(positional[0], "poll-id")
+	if err != nil {
+		return err
+	}
+	if *revision != "" && *revision != service.MergedRevision {
+		if _, err := parseRevision(*revision); err != nil {
+			return err
+		}
+	}
+
+	rows, err := env.backend.Results(ctx, pollID, *revision)
+	if err != nil {
+		return err
+	}
+
+	// Результаты по текущим вопросам приходят в произвольном порядке
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Question != rows[j].Question {
+			return rows[i].Question < rows[j].Question
+		}
+		return rows[i].Answer < rows[j].Answer
+	})
+
+	t := table{header: []string{"QUESTION", "ANSWER", "VOTES", "PERCENTAGE"}, value: rows}
+	for _, row := range rows {
+		t.rows = append(t.rows, []string{row.Question, row.Answer, strconv.Itoa(row.AnswerCnt), row.AnswerPercentage})
+	}
+	return t.write(env.stdout, env.format)
+}
+
+func migrate(ctx context.Context, env *environment, args []string) error {
+	flags := commandFlags(env, "migrate", "")
+	if _, err := parseArgs(flags, args, 0); err != nil {
+		return err
+	}
+
+	if err := env.backend.Migrate(ctx); err != nil {
+		return err
+	}
+	fmt.Fprintln(env.stderr, "migrations applied")
+	return nil
+}
+
+// seedArgs аргументы команды seed
+const seedArgs = "[-seed N] [-polls N] [-questions N] [-options N] [-respondents N] [-distribution uniform|skewed|custom] [-weight W...] [-publish]"
+
+func seed(ctx context.Context, env *environment, args []string) error {
+	defaults := fixtures.DefaultSeedRequest()
+	flags := commandFlags(env, "seed", seedArgs)
+	seedValue := flags.Int64("seed", defaults.Seed, "generator seed; the same seed and parameters give the same polls and answers")
+	polls := flags.Int("polls", defaults.Polls, "number of polls (1-1000)")
+	questions := flags.Int("questions", defaults.Questions, "questions per poll (1-100)")
+	options := flags.Int("options", defaults.Options, "options per question (2-20)")
+	respondents := flags.Int("respondents", defaults.Respondents, "synthetic respondents per poll (0-100000)")
+	firstUser := flags.Uint("first-user", defaults.FirstUserID, "user ID of the first respondent")
+	multiple := flags.Float64("multiple", defaults.MultipleRatio, "share of multiple choice questions (0-1)")
+	responseRate := flags.Float64("response-rate", defaults.ResponseRate, "probability that a respondent answers a poll (0-1)")
+	distribution := flags.String("distribution", defaults.Distribution, "how respondents choose options: uniform, skewed or custom")
+	var weights floatList
+	flags.Var(&weights, "weight", "weight of the next option for the custom distribution (repeatable)")
+	publish := flags.Bool("publish", false, "publish the polls before answering")
+	if _, err := parseArgs(flags, args, 0); err != nil {
+		return err
+	}
+
+	seeded, err := env.backend.Seed(ctx, api.SeedRequest{
+		Seed:          *seedValue,
+		Polls:         *polls,
+		Questions:     *questions,
+		Options:       *options,
+		Respondents:   *respondents,
+		FirstUserID:   *firstUser,
+		MultipleRatio: *multiple,
+		ResponseRate:  *responseRate,
+		Distribution:  *distribution,
+		Weights:       weights,
+		Publish:       *publish,
+	})
+	if err != nil {
+		return err
+	}
+
+	t := table{header: []string{"ID", "TITLE", "URL", "REVISION", "QUESTIONS"}, value: seeded}
+	for _, poll := range seeded.Polls {
+		t.rows = append(t.rows, []string{
+			formatUint(poll.ID), poll.Title, poll.URL, formatUint(poll.Revision), strconv.Itoa(poll.Questions),
+		})
+	}
+	if err := t.write(env.stdout, env.format); err != nil {
+		return err
+	}
+	if env.format == formatTable {
+		fmt.Fprintf(env.stderr, "created %d polls with %d answers from seed %d\n", len(seeded.Polls), seeded.Answers, seeded.Seed)
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+// Команда pollctl управляет опросами сервиса из командной строки: создает, показывает
+// и удаляет опросы, добавляет и изменяет вопросы, импортирует и экспортирует описания,
+// управляет шаблонами опросов, выводит результаты таблицей или в CSV и применяет миграции.
+//
+// По умолчанию pollctl работает через HTTP API сервиса (-api или POLLCTL_API).
+// С флагом -db команды выполняются напрямую с базой данных; подключение настраивается
+// так же, как у сервиса: файлом -config (CONFIG_FILE) и переменными окружения DB_*.
+//
+//	pollctl poll list
+//	pollctl -o json poll show 1
+//	pollctl question add -text "Color" -type single -option Red -option Green 1
+//	pollctl template instantiate -url spring -param season=Spring 1
+//	pollctl -db results -o csv 1 > results.csv
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"os/user"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"service-poll/pkg/apperror"
+	"service-poll/pkg/audit"
+	"service-poll/pkg/client"
+	"service-poll/pkg/config"
+	"service-poll/pkg/db"
+
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// Переменные окружения с настройками по умолчанию
+const (
+	apiEnv   = "POLLCTL_API"
+	actorEnv = "POLLCTL_ACTOR"
+)
+
+// defaultAPI адрес сервиса по умолчанию
+const defaultAPI = "http://localhost:5000"
+
+// options глобальные флаги pollctl
+type options struct {
+	api        string
+	direct     bool
+	configFile string
+	actor      string
+	output     string
+	timeout    time.Duration
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run разбирает флаги, выполняет команду и возвращает код завершения:
+// 0 — успешно, 1 — ошибка выполнения, 2 — неверные аргументы
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var opts options
+	flags := flag.NewFlagSet("pollctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&opts.api, "api", envOr(apiEnv, defaultAPI), "service base URL (env "+apiEnv+")")
+	flags.BoolVar(&opts.direct, "db", false, "work with the database directly instead of the HTTP API")
+	flags.StringVar(&opts.configFile, "config", os.Getenv(config.ConfigFileEnv), "service config file for -db (env "+config.ConfigFileEnv+")")
+	flags.StringVar(&opts.actor, "actor", envOr(actorEnv, currentUser()), "author of changes in the audit log (env "+actorEnv+")")
+	flags.StringVar(&opts.output, "o", formatTable, "output format: table, json or csv")
+	flags.DurationVar(&opts.timeout, "timeout", time.Minute, "timeout of the whole command")
+	flags.Usage = func() { usage(flags) }
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if !validFormat(opts.output) {
+		fmt.Fprintf(stderr, "pollctl: unknown output format %q\n", opts.output)
+		return 2
+	}
+
+	cmd, cmdArgs, ok := findCommand(flags.Args())
+	if !ok {
+		usage(flags)
+		return 2
+	}
+
+	// Команда прерывается по Ctrl+C и по истечении -timeout
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+
+	b, closeBackend, err := newBackend(ctx, opts, cmd.name)
+	if err != nil {
+		fmt.Fprintf(stderr, "pollctl: %v\n", err)
+		return 1
+	}
+	defer closeBackend()
+
+	env := &environment{backend: b, stdin: stdin, stdout: stdout, stderr: stderr, format: opts.output}
+	if err := cmd.run(ctx, env, cmdArgs); err != nil {
+		if errors.Is(err, errUsage) {
+			return 2
+		}
+		printError(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// newBackend подключается к HTTP API или, с флагом -db, к базе данных сервиса
+func newBackend(ctx context.Context, opts options, command string) (backend, func(), error) {
+	if !opts.direct {
+		c, err := client.New(opts.api, client.WithActor(opts.actor))
+		if err != nil {
+			return nil, nil, err
+		}
+		return httpBackend{client: c}, func() {}, nil
+	}
+
+	var configArgs []string
+	if opts.configFile != "" {
+		configArgs = []string{"-config", opts.configFile}
+	}
+	cfg, err := config.Load(configArgs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Журнал запросов GORM не нужен в выводе команды
+	if err := db.InitDB(ctx, cfg.Database, gormlogger.Discard); err != nil {
+		return nil, nil, fmt.Errorf("connect to database: %w", err)
+	}
+
+	source := audit.Source{Actor: opts.actor, Method: "CLI", Endpoint: "pollctl " + command}
+	return dbBackend{source: source}, func() { db.Close() }, nil
+}
+
+// printError выводит ошибку; для ошибок сервиса — код и поля, не прошедшие проверку
+func printError(w io.Writer, err error) {
+	var apiErr *client.Error
+	var appErr *apperror.Error
+	var fields []apperror.FieldError
+	switch {
+	case errors.As(err, &apiErr):
+		fields = apiErr.Problem.Errors
+	case errors.As(err, &appErr):
+		fields = appErr.Fields
+		if appErr.Err != nil {
+			err = fmt.Errorf("%s: %s (%w)", appErr.Code, appErr.Detail, appErr.Err)
+		} else {
+			err = fmt.Errorf("%s: %s", appErr.Code, appErr.Detail)
+		}
+	}
+
+	fmt.Fprintf(w, "pollctl: %v\n", err)
+	for _, field := range fields {
+		fmt.Fprintf(w, "  %s: %s\n", field.Field, field.Message)
+	}
+}
+
+// usage выводит справку по флагам и командам
+func usage(flags *flag.FlagSet) {
+	w := flags.Output()
+	fmt.Fprintln(w, "Usage: pollctl [flags] <command> [command flags] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", cmd.name, cmd.summary)
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Flags:")
+	flags.PrintDefaults()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "pollctl <command> -h" for command flags.`)
+}
+
+// envOr возвращает значение переменной окружения или value, если она не задана
+func envOr(name, value string) string {
+	if env := strings.TrimSpace(os.Getenv(name)); env != "" {
+		return env
+	}
+	return value
+}
+
+// currentUser возвращает имя пользователя ОС для журнала аудита
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return "pollctl"
+}
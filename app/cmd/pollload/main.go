@@ -0,0 +1,181 @@
+// Команда pollload нагружает запущенный сервис ответами на опросы и запросами результатов
+// и выводит пропускную способность и процентили задержки по каждой операции.
+//
+// По умолчанию pollload создает новые опросы генератором POST /seed с фиксированным seed,
+// поэтому запуски с одинаковыми флагами сравнимы между собой; маршрут должен быть включен
+// в сервисе настройкой server.seed_endpoint. С флагом -poll нагрузка идет на существующие опросы.
+//
+//	pollload -concurrency 50 -duration 1m
+//	pollload -requests 10000 -read-ratio 0.5 -poll 1 -poll 2
+//	pollload -o json > baseline.json
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"service-poll/pkg/client"
+	"service-poll/pkg/loadtest"
+)
+
+// apiEnv переменная окружения с адресом сервиса, общая с pollctl
+const apiEnv = "POLLCTL_API"
+
+// defaultAPI адрес сервиса по умолчанию
+const defaultAPI = "http://localhost:5000"
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run разбирает флаги, выполняет нагрузку и возвращает код завершения:
+// 0 — без ошибок, 1 — были ошибки запросов или нагрузку не удалось выполнить,
+// 2 — неверные аргументы
+func run(args []string, stdout, stderr io.Writer) int {
+	cfg := loadtest.DefaultConfig()
+	var pollIDs idList
+	flags := flag.NewFlagSet("pollload", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	api := flags.String("api", envOr(apiEnv, defaultAPI), "service base URL (env "+apiEnv+")")
+	flags.IntVar(&cfg.Concurrency, "concurrency", cfg.Concurrency, "number of concurrent workers")
+	flags.DurationVar(&cfg.Duration, "duration", cfg.Duration, "test duration; 0 to stop after -requests")
+	flags.IntVar(&cfg.Requests, "requests", cfg.Requests, "total number of requests; 0 to stop after -duration")
+	flags.Float64Var(&cfg.ReadRatio, "read-ratio", cfg.ReadRatio, "share of results requests (0-1)")
+	flags.Int64Var(&cfg.Seed, "seed", cfg.Seed, "seed of the poll generator and of the request mix")
+	flags.IntVar(&cfg.Polls, "polls", cfg.Polls, "number of polls to generate")
+	flags.IntVar(&cfg.Questions, "questions", cfg.Questions, "questions per generated poll")
+	flags.IntVar(&cfg.Options, "options", cfg.Options, "options per generated question")
+	flags.UintVar(&cfg.FirstUserID, "first-user", cfg.FirstUserID, "user ID of the first answer; every answer comes from a new user")
+	flags.Var(&pollIDs, "poll", "answer existing poll `ID` instead of generating polls (repeatable)")
+	timeout := flags.Duration("timeout", 10*time.Second, "timeout of a single request")
+	output := flags.String("o", "table", "output format: table or json")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: pollload [flags]")
+		fmt.Fprintln(stderr)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() > 0 || *output != "table" && *output != "json" {
+		flags.Usage()
+		return 2
+	}
+	cfg.PollIDs = pollIDs
+
+	// Повторы исказили бы задержки, а соединений в пуле должно хватать всем исполнителям
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = cfg.Concurrency
+	c, err := client.New(*api,
+		client.WithHTTPClient(&http.Client{Transport: transport, Timeout: *timeout}),
+		client.WithRetry(client.RetryPolicy{MaxAttempts: 1}),
+		client.WithActor("pollload"),
+	)
+	if err != nil {
+		fmt.Fprintf(stderr, "pollload: %v\n", err)
+		return 2
+	}
+
+	// По Ctrl+C выводится отчет по уже выполненным запросам
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	report, err := loadtest.Run(ctx, c, cfg)
+	if err != nil && report.Operations == nil {
+		fmt.Fprintf(stderr, "pollload: %v\n", err)
+		return 1
+	}
+
+	if *output == "json" {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintf(stderr, "pollload: %v\n", err)
+			return 1
+		}
+	} else {
+		writeTable(stdout, report)
+	}
+
+	for _, operation := range report.Operations {
+		if operation.Operation == loadtest.OperationTotal && operation.Errors > 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+// writeTable выводит отчет таблицей
+func writeTable(w io.Writer, report loadtest.Report) {
+	fmt.Fprintf(w, "polls %v, %d workers, %.1fs\n\n", report.Polls, report.Concurrency, report.ElapsedMs/1000)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "OPERATION\tREQUESTS\tERRORS\tREQ/S\tMEAN\tP50\tP90\tP95\tP99\tMAX\t")
+	for _, op := range report.Operations {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%s\t%s\t%s\t%s\t%s\t%s\t\n", op.Operation, op.Requests, op.Errors, op.Throughput,
+			formatMs(op.MeanMs), formatMs(op.P50Ms), formatMs(op.P90Ms), formatMs(op.P95Ms), formatMs(op.P99Ms), formatMs(op.MaxMs))
+	}
+	tw.Flush()
+
+	for _, op := range report.Operations {
+		if op.Operation == loadtest.OperationTotal || len(op.ErrorCodes) == 0 {
+			continue
+		}
+		codes := make([]string, 0, len(op.ErrorCodes))
+		for code, count := range op.ErrorCodes {
+			codes = append(codes, fmt.Sprintf("%s %d", code, count))
+		}
+		sort.Strings(codes)
+		fmt.Fprintf(w, "\n%s errors: %s\n", op.Operation, strings.Join(codes, ", "))
+	}
+}
+
+// formatMs форматирует задержку в миллисекундах
+func formatMs(ms float64) string {
+	return strconv.FormatFloat(ms, 'f', 2, 64) + "ms"
+}
+
+// idList флаг с идентификатором, который можно указать несколько раз
+type idList []uint
+
+func (l *idList) String() string {
+	ids := make([]string, len(*l))
+	for i, id := range *l {
+		ids[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	return strings.Join(ids, ", ")
+}
+
+func (l *idList) Set(value string) error {
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || id == 0 {
+		return fmt.Errorf("must be a positive integer, got %q", value)
+	}
+	*l = append(*l, uint(id))
+	return nil
+}
+
+// envOr возвращает значение переменной окружения или value, если она не задана
+func envOr(name, value string) string {
+	if env := strings.TrimSpace(os.Getenv(name)); env != "" {
+		return env
+	}
+	return value
+}
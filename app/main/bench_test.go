@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"service-poll/pkg/client"
+	"service-poll/pkg/fixtures"
+	"service-poll/pkg/loadtest"
+	"service-poll/pkg/service"
+)
+
+// Бенчмарки приема ответов и подсчета результатов выполняют запросы к маршрутизатору
+// сервиса без сети, поэтому их результаты зависят только от обработчиков и базы данных.
+// Для отслеживания регрессий их удобно сравнивать через benchstat:
+//
+//	go test ./main -run '^$' -bench . -count 10 > new.txt
+//	TEST_DB_DRIVER=sqlite go test ./main -run '^$' -bench RegisterAnswer
+
+// answerBody тело ответа пользователя userID на вопросы опроса 1 или 2 standardPolls
+func answerBody(pollID int, userID int64) string {
+	if pollID == 2 {
+		return fmt.Sprintf(`{"attributes":{"access_token":"t","user_id":%d,"results":[{"question":"Size","answer":"M"}]}}`, userID)
+	}
+	return fmt.Sprintf(`{"attributes":{"access_token":"t","user_id":%d,"results":[{"question":"Favorite color","answer":"Blue"},{"question":"Colors you wear","answer":"Red,Green"}]}}`, userID)
+}
+
+// BenchmarkRegisterAnswer ответы на неопубликованный опрос (вопросы опроса)
+// и на опубликованный (вопросы ревизии)
+func BenchmarkRegisterAnswer(b *testing.B) {
+	for _, bench := range []struct {
+		name   string
+		pollID int
+	}{{"draft", 1}, {"published", 2}} {
+		b.Run(bench.name, func(b *testing.B) {
+			s := newTestServer(b, standardPolls()...)
+			path := v1(fmt.Sprintf("/poll/%d/answer", bench.pollID))
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if rec := s.do(http.MethodPost, path, "", answerBody(bench.pollID, int64(i+100))); rec.Code != http.StatusOK {
+					b.Fatalf("status = %d; body: %s", rec.Code, rec.Body.String())
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkRegisterAnswerParallel одновременные ответы разных пользователей на один опрос
+func BenchmarkRegisterAnswerParallel(b *testing.B) {
+	s := newTestServer(b, standardPolls()...)
+	path := v1("/poll/1/answer")
+	var users atomic.Int64
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if rec := s.do(http.MethodPost, path, "", answerBody(1, users.Add(1)+100)); rec.Code != http.StatusOK {
+				b.Errorf("status = %d; body: %s", rec.Code, rec.Body.String())
+				return
+			}
+		}
+	})
+}
+
+// BenchmarkRegisterAnswerAsync одновременные ответы разных пользователей на один опрос
+// через асинхронный прием: учитывается время до сохранения всех ответов фоновым обработчиком,
+// который сохраняет ответы и прирост счетчиков результатов пачками. Если очередь
+// заполнена, ответ повторяется, как это делает клиент.
+func BenchmarkRegisterAnswerAsync(b *testing.B) {
+	s := newTestServer(b, standardPolls()...)
+	s.startIngest(true)
+	path := v1("/poll/1/answer")
+	var users atomic.Int64
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			body := answerBody(1, users.Add(1)+100)
+			rec := s.do(http.MethodPost, path, "", body)
+			for rec.Code == http.StatusServiceUnavailable {
+				time.Sleep(time.Millisecond)
+				rec = s.do(http.MethodPost, path, "", body)
+			}
+			if rec.Code != http.StatusAccepted {
+				b.Errorf("status = %d; body: %s", rec.Code, rec.Body.String())
+				return
+			}
+		}
+	})
+	s.stopIngest()
+}
+
+// resultsPoll опрос из трех вопросов с четырьмя вариантами, на который ответили respondents пользователей
+func resultsPoll(respondents int) []*fixtures.PollBuilder {
+	generator := fixtures.DefaultGenerator()
+	generator.Polls = 1
+	generator.Options = 4
+	generator.Respondents = respondents
+	return generator.Generate()
+}
+
+// BenchmarkGetPollResults чтение результатов опроса из счетчиков с разным количеством ответов
+// без кэша и чтение тех же результатов из кэша (cached)
+func BenchmarkGetPollResults(b *testing.B) {
+	for _, respondents := range []int{100, 1000} {
+		for _, cached := range []bool{false, true} {
+			name := fmt.Sprintf("respondents=%d", respondents)
+			if cached {
+				name += "/cached"
+			}
+			b.Run(name, func(b *testing.B) {
+				s := newTestServer(b, resultsPoll(respondents)...)
+				if !cached {
+					service.SetCache(nil, 0)
+				}
+				path := v1("/poll/1/results")
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if rec := s.do(http.MethodGet, path, "", ""); rec.Code != http.StatusOK {
+						b.Fatalf("status = %d; body: %s", rec.Code, rec.Body.String())
+					}
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkGetPollResultsParallel одновременные запросы результатов одного опроса без кэша
+func BenchmarkGetPollResultsParallel(b *testing.B) {
+	s := newTestServer(b, resultsPoll(1000)...)
+	service.SetCache(nil, 0)
+	path := v1("/poll/1/results")
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if rec := s.do(http.MethodGet, path, "", ""); rec.Code != http.StatusOK {
+				b.Errorf("status = %d; body: %s", rec.Code, rec.Body.String())
+				return
+			}
+		}
+	})
+}
+
+// TestLoadGenerator выполняет короткий нагрузочный тест через HTTP и проверяет отчет
+func TestLoadGenerator(t *testing.T) {
+	if testing.Short() {
+		t.Skip("load test is skipped in short mode")
+	}
+
+	s := newTestServer(t)
+	server := httptest.NewServer(s.router)
+	defer server.Close()
+
+	c, err := client.New(server.URL, client.WithRetry(client.RetryPolicy{MaxAttempts: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := loadtest.DefaultConfig()
+	cfg.Concurrency = 4
+	cfg.Duration = 0
+	cfg.Requests = 200
+	cfg.Polls = 2
+	report, err := loadtest.Run(context.Background(), c, cfg)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	if len(report.Polls) != cfg.Polls {
+		t.Errorf("polls = %v, want %d polls", report.Polls, cfg.Polls)
+	}
+	operations := make(map[string]loadtest.OperationReport)
+	for _, operation := range report.Operations {
+		operations[operation.Operation] = operation
+		if operation.Errors > 0 {
+			t.Errorf("%s errors: %v", operation.Operation, operation.ErrorCodes)
+		}
+		if operation.Requests > 0 && !(operation.P50Ms <= operation.P99Ms && operation.P99Ms <= operation.MaxMs) {
+			t.Errorf("%s latencies are not ordered: %+v", operation.Operation, operation)
+		}
+	}
+
+	answers, results := operations[loadtest.OperationAnswer].Requests, operations[loadtest.OperationResults].Requests
+	if total := operations[loadtest.OperationTotal].Requests; total != cfg.Requests || answers+results != total {
+		t.Errorf("requests: answer %d + results %d, total %d, want %d", answers, results, total, cfg.Requests)
+	}
+	if answers == 0 || results == 0 {
+		t.Errorf("requests: answer %d, results %d, want both operations", answers, results)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"service-poll/pkg/api"
+	"service-poll/pkg/config"
+	"service-poll/pkg/db"
+	"service-poll/pkg/health"
+	"service-poll/pkg/metrics"
+	"service-poll/pkg/models"
+	"service-poll/pkg/service"
+)
+
+// TestReconcileCounters проверяет, что сверка находит и исправляет расхождения счетчиков
+// результатов с ответами и сбрасывает кэш результатов
+func TestReconcileCounters(t *testing.T) {
+	s := newTestServer(t, standardPolls()...)
+	ctx := context.Background()
+
+	// Счетчики фикстур совпадают с ответами: вопросы 1-3, варианты Red и Green вопроса 1,
+	// Red и Blue вопроса 2 и M вопроса 3
+	report, err := service.ReconcileCounters(ctx)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if report.Questions != 3 || report.Options != 5 || len(report.Drift) != 0 {
+		t.Fatalf("report = %+v, want 3 question and 5 option counters without drift", report)
+	}
+
+	want := decode[api.PollResultsResponse](t, s.mustDo(http.MethodGet, v1("/poll/1/results"), ""))
+
+	// Портим счетчики: неверное количество ответов, пропавший счетчик Green
+	// и лишний счетчик Green вопроса 2
+	if err := db.DB.Model(&models.QuestionCounter{}).Where("question_id = ?", 1).Update("answers", 10).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DB.Where("possible_answer_id = ?", 2).Delete(&models.OptionCounter{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DB.Create(&models.OptionCounter{PossibleAnswerID: 5, QuestionID: 2, Votes: 4}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	report, err = service.ReconcileCounters(ctx)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	wantDrift := []service.CounterDrift{
+		{Counter: metrics.CounterQuestion, ID: 1, QuestionID: 1, Stored: 10, Actual: 3},
+		{Counter: metrics.CounterOption, ID: 2, QuestionID: 1, Stored: 0, Actual: 1},
+		{Counter: metrics.CounterOption, ID: 5, QuestionID: 2, Stored: 4, Actual: 0},
+	}
+	if !reflect.DeepEqual(report.Drift, wantDrift) {
+		t.Errorf("drift = %+v, want %+v", report.Drift, wantDrift)
+	}
+
+	// Исправленные счетчики дают прежние результаты, хотя они были в кэше
+	got := decode[api.PollResultsResponse](t, s.mustDo(http.MethodGet, v1("/poll/1/results"), ""))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("results after reconcile = %+v, want %+v", got, want)
+	}
+
+	if report, err := service.ReconcileCounters(ctx); err != nil || len(report.Drift) != 0 {
+		t.Errorf("second reconcile: drift = %+v, err = %v", report.Drift, err)
+	}
+}
+
+// TestCounterReconciliationWorker проверяет, что сверка учитывается в проверке готовности,
+// пока работает, и убирается из нее после остановки
+func TestCounterReconciliationWorker(t *testing.T) {
+	newTestServer(t, standardPolls()...)
+
+	workers := func() health.ComponentReport {
+		return health.Ready(context.Background(), time.Second).Components["workers"]
+	}
+
+	run := service.StartCounterReconciliation(config.CountersConfig{ReconcileInterval: config.Duration{Duration: 10 * time.Millisecond}})
+	if got := workers(); got.Status != health.StatusFail {
+		t.Errorf("workers before start = %+v, want fail", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		run(ctx)
+	}()
+
+	// Несколько сверок подряд: задача отчитывается и не считается зависшей
+	time.Sleep(50 * time.Millisecond)
+	if got := workers(); got.Status != health.StatusOK {
+		t.Errorf("workers while running = %+v, want ok", got)
+	}
+
+	cancel()
+	<-done
+	if got := workers(); got.Status != health.StatusOK {
+		t.Errorf("workers after stop = %+v, want ok", got)
+	}
+}
+
+// TestReconcileAfterDelete проверяет, что ответы после удаления вопроса и варианта
+// опубликованного опроса не увеличивают счетчики удаленных записей и сверка
+// не находит расхождений
+func TestReconcileAfterDelete(t *testing.T) {
+	s := newTestServer(t, standardPolls()...)
+
+	// Вариант S удален из вопроса 3 опубликованного опроса 2, но остался в ревизии
+	s.mustDo(http.MethodPatch, v1("/poll/2/question/3?force=true"), `{"text":"Size","type":"single","options":[{"id":8},{"id":9}]}`)
+	if rec := s.do(http.MethodPost, v1("/poll/2/answer"), "", `{"attributes":{"access_token":"t","user_id":5,"results":[{"question":"Size","answer":"S"}]}}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("answer with deleted option: status = %d, want 400; body: %s", rec.Code, rec.Body.String())
+	}
+	s.mustDo(http.MethodPost, v1("/poll/2/answer"), answerBody(2, 6))
+
+	// Вопрос 3 удален целиком
+	s.mustDo(http.MethodDelete, v1("/poll/2/question/3"), "")
+	if rec := s.do(http.MethodPost, v1("/poll/2/answer"), "", answerBody(2, 7)); rec.Code != http.StatusBadRequest {
+		t.Errorf("answer to deleted question: status = %d, want 400; body: %s", rec.Code, rec.Body.String())
+	}
+	s.mustDo(http.MethodPost, v1("/poll/1/answer"), answerBody(1, 8))
+
+	report, err := service.ReconcileCounters(context.Background())
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if len(report.Drift) != 0 {
+		t.Errorf("drift = %+v, want none", report.Drift)
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"service-poll/migrations"
+	"service-poll/pkg/apperror"
+	"service-poll/pkg/cache"
+	"service-poll/pkg/config"
+	"service-poll/pkg/db"
+	"service-poll/pkg/fixtures"
+	"service-poll/pkg/logger"
+	"service-poll/pkg/routes"
+	"service-poll/pkg/service"
+
+	"github.com/gin-gonic/gin"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// testDriverEnv переменная окружения с драйвером базы интеграционных тестов:
+// memory (по умолчанию) или sqlite (файл во временном каталоге теста)
+const testDriverEnv = "TEST_DB_DRIVER"
+
+// TestMain переводит gin в тестовый режим и отключает журнал запросов;
+// с -v журнал пишется в stderr на уровне debug
+func TestMain(m *testing.M) {
+	flag.Parse()
+	gin.SetMode(gin.TestMode)
+
+	output, level := io.Discard, slog.LevelError
+	if testing.Verbose() {
+		output, level = os.Stderr, slog.LevelDebug
+	}
+	slog.SetDefault(logger.New(output, level))
+
+	os.Exit(m.Run())
+}
+
+// testServer маршрутизатор сервиса поверх отдельной пустой базы данных
+type testServer struct {
+	t      testing.TB
+	router *gin.Engine
+	// stopIngest выключает асинхронный прием ответов, сохранив принятые ответы
+	stopIngest func()
+}
+
+// newTestServer создает базу данных с таблицами, но без фикстур миграции, создает в ней
+// опросы builders и возвращает маршрутизатор со всеми маршрутами сервиса.
+// База закрывается по завершении теста.
+func newTestServer(t testing.TB, builders ...*fixtures.PollBuilder) *testServer {
+	t.Helper()
+
+	cfg := config.Default().Database
+	cfg.Driver = config.DriverMemory
+	if driver := os.Getenv(testDriverEnv); driver != "" {
+		cfg.Driver = driver
+	}
+	cfg.Path = filepath.Join(t.TempDir(), "poll.db")
+	cfg.ConnectRetryTimeout = config.Duration{}
+
+	if err := db.InitDB(context.Background(), cfg, gormlogger.Discard); err != nil {
+		t.Fatalf("open %s database: %v", cfg.Driver, err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := migrations.CreateSchema(); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	if _, err := fixtures.CreateAll(db.DB, builders...); err != nil {
+		t.Fatalf("create fixtures: %v", err)
+	}
+
+	// У каждой базы свой кэш: идентификаторы опросов в разных тестах совпадают
+	service.SetCache(cache.NewLRU(1000), time.Minute)
+
+	// Тесты проверяют все маршруты, в том числе включаемые настройками
+	registerChecks()
+	router, err := newRouter(routes.Options{Seed: true})
+	if err != nil {
+		t.Fatalf("create router: %v", err)
+	}
+
+	return &testServer{t: t, router: router}
+}
+
+// do выполняет запрос к маршрутизатору; тело в формате JSON, если не указан contentType
+func (s *testServer) do(method, path, contentType, body string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if body != "" {
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// conditionalGet выполняет запрос GET с заголовком If-None-Match
+func (s *testServer) conditionalGet(path, etag string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("If-None-Match", etag)
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// mustDo выполняет запрос и завершает тест, если код ответа не 200
+func (s *testServer) mustDo(method, path, body string) *httptest.ResponseRecorder {
+	s.t.Helper()
+
+	rec := s.do(method, path, "", body)
+	if rec.Code != http.StatusOK {
+		s.t.Fatalf("%s %s: status = %d, want %d; body: %s", method, path, rec.Code, http.StatusOK, rec.Body.String())
+	}
+	return rec
+}
+
+// startIngest включает асинхронный прием ответов с параметрами по умолчанию.
+// Если running равно false, фоновый обработчик не запускается до stopIngest, и в очереди
+// помещается только один ответ. Прием выключается по завершении теста, если stopIngest не вызван.
+func (s *testServer) startIngest(running bool) {
+	cfg := config.Default().Ingest
+	cfg.Enabled = true
+	if !running {
+		cfg.QueueSize = 1
+	}
+	run := service.StartIngest(cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	start := func() {
+		go func() {
+			defer close(done)
+			run(ctx)
+		}()
+	}
+	if running {
+		start()
+	}
+
+	var once sync.Once
+	s.stopIngest = func() {
+		once.Do(func() {
+			if !running {
+				start()
+			}
+			cancel()
+			<-done
+		})
+	}
+	s.t.Cleanup(s.stopIngest)
+}
+
+// decode разбирает тело ответа в формате JSON
+func decode[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+
+	var value T
+	if err := json.Unmarshal(rec.Body.Bytes(), &value); err != nil {
+		t.Fatalf("decode response: %v; body: %s", err, rec.Body.String())
+	}
+	return value
+}
+
+// problemCode возвращает код ошибки из ответа application/problem+json
+func problemCode(t *testing.T, rec *httptest.ResponseRecorder) apperror.Code {
+	t.Helper()
+
+	if contentType := rec.Header().Get("Content-Type"); !strings.HasPrefix(contentType, apperror.ContentType) {
+		t.Fatalf("Content-Type = %q, want %s; body: %s", contentType, apperror.ContentType, rec.Body.String())
+	}
+	return decode[apperror.Problem](t, rec).Code
+}
+
+// standardPolls опросы, с которыми выполняется каждый сценарий:
+//
+//   - опрос 1 "Colors" не опубликован: вопрос 1 (варианты 1-3) и вопрос 2 (варианты 4-6),
+//     пользователи 1 и 2 выбрали Red, пользователь 3 — Green, пользователь 1 носит Red и Blue;
+//   - опрос 2 "Sizes" опубликован (ревизия 1): вопрос 3 (варианты 7-9), пользователь 4 выбрал M;
+//   - опрос 3 "Empty" без вопросов.
+func standardPolls() []*fixtures.PollBuilder {
+	return []*fixtures.PollBuilder{
+		fixtures.Poll("Colors").
+			Single("Favorite color", "Red", "Green", "Blue").
+			Multiple("Colors you wear", "Red", "Green", "Blue").
+			Answer(1, 0, 0).
+			Answer(2, 0, 0).
+			Answer(3, 0, 1).
+			Answer(1, 1, 0, 2),
+		fixtures.Poll("Sizes").
+			Single("Size", "S", "M", "L").
+			Published().
+			Answer(4, 0, 1),
+		fixtures.Poll("Empty"),
+	}
+}
+
+// matchRoute возвращает шаблон маршрута gin, которому соответствует путь запроса
+func matchRoute(routes gin.RoutesInfo, method, path string) (string, bool) {
+	path, _, _ = strings.Cut(path, "?")
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	for _, route := range routes {
+		if route.Method == method && matchSegments(strings.Split(strings.Trim(route.Path, "/"), "/"), segments) {
+			return route.Path, true
+		}
+	}
+	return "", false
+}
+
+// matchSegments сравнивает сегменты шаблона маршрута с сегментами пути:
+// ":name" совпадает с любым непустым сегментом, "*name" — с остатком пути
+func matchSegments(pattern, segments []string) bool {
+	for i, part := range pattern {
+		if strings.HasPrefix(part, "*") {
+			return true
+		}
+		if i >= len(segments) {
+			return false
+		}
+		if strings.HasPrefix(part, ":") {
+			if segments[i] == "" {
+				return false
+			}
+			continue
+		}
+		if part != segments[i] {
+			return false
+		}
+	}
+	return len(pattern) == len(segments)
+}
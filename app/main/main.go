@@ -1,76 +1,151 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"io"
 	"log"
-	"net/http"
+	"log/slog"
+	"os"
+	"os/signal"
 	"service-poll/migrations"
+	"service-poll/pkg/cache"
+	"service-poll/pkg/config"
 	"service-poll/pkg/db"
-	"service-poll/pkg/handlers"
+	"service-poll/pkg/grpcserver"
+	"service-poll/pkg/logger"
+	"service-poll/pkg/metrics"
+	"service-poll/pkg/routes"
+	"service-poll/pkg/service"
+	"service-poll/pkg/tracing"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 func main() {
-	err := godotenv.Load()
+	// Загружаем настройки: значения по умолчанию, файл, переменные окружения и флаги
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	} else if err != nil {
+		log.Fatal(err)
+	}
+
+	// Структурированный JSON-журнал для сервиса, GORM и gin
+	if _, err := logger.Init(cfg.Log.Level); err != nil {
+		log.Fatal(err)
+	}
+
+	if cfg.Log.Level == "debug" {
+		gin.SetMode(gin.DebugMode)
+	} else {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	// Контекст отменяется по SIGTERM/SIGINT
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	// Трассировка OpenTelemetry: контекст W3C из входящих запросов и экспорт спанов в коллектор
+	shutdownTracing, err := tracing.Init(ctx, cfg.Tracing)
 	if err != nil {
-		log.Fatal("Error loading .env file")
+		fatal("failed to initialize tracing", err)
 	}
 
 	// инициализация DB
-	db.InitDB()
+	if err := db.InitDB(ctx, cfg.Database, logger.NewGormLogger(cfg.Log.SlowQueryThreshold.Duration)); err != nil {
+		fatal("failed to connect to database", err)
+	}
 
-	router := gin.Default()
+	// База в памяти пуста при каждом запуске, поэтому таблицы создаются сразу
+	if cfg.Database.Driver == config.DriverMemory {
+		if err := migrations.Apply(); err != nil {
+			fatal("failed to apply migrations", err)
+		}
+	}
 
-	// POST /migrate
-	// Создаёт базу данных и таблицы + наполняет их фикстурами
-	router.POST("/migrate", migrations.Migrate)
+	// Метрики запросов к базе данных и пула соединений
+	if err := db.DB.Use(metrics.GormPlugin{}); err != nil {
+		fatal("failed to register database metrics", err)
+	}
+	if sqlDB, err := db.DB.DB(); err == nil {
+		if err := metrics.RegisterDBStats(sqlDB); err != nil {
+			fatal("failed to register database pool metrics", err)
+		}
+	}
 
-	// GET /check_alive
-	// Проверяет, работает ли сервер.
-	router.GET("/check_alive", CheckAlive)
+	// Спаны запросов к базе данных
+	if err := db.DB.Use(tracing.GormPlugin{}); err != nil {
+		fatal("failed to register database tracing", err)
+	}
 
-	// POST /poll/create
-	// Создает новый опрос.
-	router.POST("/poll/create", handlers.CreatePoll)
+	// Кэш описаний опросов и результатов
+	cacheStore := cache.New(cfg.Cache)
+	service.SetCache(cacheStore, cfg.Cache.TTL.Duration)
 
-	// GET /poll/:id
-	// Получает опрос с вопросами по указанному идентификатору.
-	router.GET("/poll/:id", handlers.GetPoll)
+	// Фоновые задачи останавливаются вместе с сервером
+	background := newBackgroundTasks()
 
-	// PATCH /poll/:id
-	// Изменяет опрос по указанному идентификатору.
-	router.PATCH("/poll/:id", handlers.UpdatePoll)
+	// Асинхронный прием ответов: очередь сохраняется до остановки фоновых задач,
+	// которые останавливаются после сервера HTTP
+	if cfg.Ingest.Enabled {
+		background.Go(service.StartIngest(cfg.Ingest))
+	}
 
-	// DELETE /poll/:id
-	// Удаляет опрос по указанному идентификатору.
-	router.DELETE("/poll/:id", handlers.DeletePoll)
+	// Сверка счетчиков результатов с ответами
+	if cfg.Counters.ReconcileInterval.Duration > 0 {
+		background.Go(service.StartCounterReconciliation(cfg.Counters))
+	}
 
-	// GET /poll/:id/results
-	// Получает результаты опроса по указанному идентификатору.
-	router.GET("/poll/:id/results", handlers.GetPollResults)
+	// Проверки готовности и маршруты HTTP
+	registerChecks()
+	router, err := newRouter(routes.Options{Seed: cfg.Server.SeedEndpoint})
+	if err != nil {
+		fatal("failed to register routes", err)
+	}
 
-	// POST /poll/:id/question
-	// Добавляет вопрос к опросу по указанному идентификатору.
-	router.POST("/poll/:id/question", handlers.AddQuestion)
+	// Сервер gRPC на отдельном порту: те же операции через слой pkg/service, что и в HTTP API
+	var grpcServer *grpc.Server
+	if cfg.GRPC.Addr != "" {
+		var opts []grpc.ServerOption
+		if cfg.Server.TLS.Enabled() {
+			creds, err := credentials.NewServerTLSFromFile(cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile)
+			if err != nil {
+				fatal("failed to load gRPC TLS certificate", err)
+			}
+			opts = append(opts, grpc.Creds(creds))
+		}
+		grpcServer = grpcserver.New(ctx, cfg.GRPC.StreamInterval.Duration, opts...)
+	}
 
-	// PATCH /poll/:id/question/:qid
-	// Изменяет вопрос к опросу по указанному идентификатору.
-	router.PATCH("/poll/:id/question/:qid", handlers.UpdateQuestion)
+	// Обслуживаем запросы до SIGTERM/SIGINT, затем останавливаемся с ожиданием текущих запросов
+	if err := serve(ctx, cfg.Server, cfg.GRPC, router, grpcServer, background); err != nil {
+		slog.Error("shutdown failed", slog.String("error", err.Error()))
+	}
 
-	// DELETE /poll/:id/question/:qid
-	// Удаляет вопрос к опросу по указанному идентификатору.
-	router.DELETE("/poll/:id/question/:qid", handlers.DeleteQuestion)
+	if err := db.Close(); err != nil {
+		slog.Error("failed to close database", slog.String("error", err.Error()))
+	}
+	if closer, ok := cacheStore.(io.Closer); ok {
+		closer.Close()
+	}
 
-	// POST /poll/:id/answer
-	// Регистрирует ответ на вопрос к опросу по указанному идентификатору.
-	router.POST("/poll/:id/answer", handlers.RegisterAnswer)
+	// Отправляем в коллектор спаны, накопленные до остановки
+	tracingCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.Duration)
+	defer cancel()
+	if err := shutdownTracing(tracingCtx); err != nil {
+		slog.Error("failed to flush traces", slog.String("error", err.Error()))
+	}
 
-	router.HandleMethodNotAllowed = true
-	router.Run(":5000")
+	slog.Info("stopped")
 }
 
-// CheckAlive возвращает статус 200, чтобы проверить, работает ли сервер.
-func CheckAlive(c *gin.Context) {
-	c.Status(http.StatusOK)
+// fatal пишет ошибку запуска в журнал и завершает процесс
+func fatal(message string, err error) {
+	slog.Error(message, slog.String("error", err.Error()))
+	os.Exit(1)
 }
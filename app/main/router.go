@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/http"
+
+	"service-poll/migrations"
+	"service-poll/pkg/db"
+	"service-poll/pkg/handlers"
+	"service-poll/pkg/health"
+	"service-poll/pkg/logger"
+	"service-poll/pkg/metrics"
+	"service-poll/pkg/routes"
+	"service-poll/pkg/tracing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerChecks регистрирует проверки готовности сервиса. Фоновые задачи (прием ответов,
+// сверка счетчиков) и сервер gRPC учитываются в компоненте workers: они регистрируются
+// через health.RegisterWorker при запуске.
+func registerChecks() {
+	health.Register("database", db.Ping)
+	health.Register("migrations", migrations.CheckVersion)
+}
+
+// newRouter создает маршрутизатор HTTP со всеми маршрутами сервиса; маршруты,
+// которые включаются настройками, задает opts
+func newRouter(opts routes.Options) (*gin.Engine, error) {
+	// Идентификатор запроса, спан запроса, журнал запросов и перехват паник, затем метрики
+	router := gin.New()
+	router.Use(logger.RequestID(), tracing.Middleware(), logger.Middleware(), logger.Recovery())
+	router.Use(metrics.Middleware())
+
+	// Идентификаторы в пути проверяются до обработчиков
+	router.Use(handlers.ValidateIDs())
+
+	// Служебные маршруты проверок и метрик не версионируются
+
+	// GET /check_alive
+	// Проверяет, работает ли сервер.
+	router.GET("/check_alive", CheckAlive)
+
+	// GET /healthz
+	// Проверяет, что процесс сервиса жив.
+	router.GET("/healthz", handlers.Healthz)
+
+	// GET /readyz
+	// Проверяет готовность сервиса: базу данных, версию схемы и фоновые задачи.
+	router.GET("/readyz", handlers.Readyz)
+
+	// GET /metrics
+	// Отдает метрики сервиса в формате Prometheus.
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	// GET, POST /graphql
+	// Выполняет запрос GraphQL по опросам, вопросам, вариантам ответа, респондентам и результатам.
+	router.GET("/graphql", handlers.GraphQL)
+	router.POST("/graphql", handlers.GraphQL)
+
+	// Маршруты API версии 1 под /api/v1 с описанием OpenAPI (/api/v1/openapi.json)
+	// и Swagger UI (/api/v1/docs/). Прежние маршруты без версии помечаются устаревшими.
+	if err := routes.Register(router, opts); err != nil {
+		return nil, err
+	}
+
+	// Неизвестные маршруты и методы возвращают ошибку в формате application/problem+json
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(handlers.NoRoute)
+	router.NoMethod(handlers.NoMethod)
+
+	return router, nil
+}
+
+// CheckAlive возвращает статус 200, чтобы проверить, работает ли сервер.
+func CheckAlive(c *gin.Context) {
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,823 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"service-poll/pkg/api"
+	"service-poll/pkg/apperror"
+	"service-poll/pkg/db"
+	"service-poll/pkg/definition"
+	"service-poll/pkg/graphqlapi"
+	"service-poll/pkg/health"
+	"service-poll/pkg/models"
+	"service-poll/pkg/routes"
+)
+
+// routeCase запрос к сервису поверх опросов standardPolls и ожидаемый ответ
+type routeCase struct {
+	name        string
+	setup       func(s *testServer) // подготовка данных через API перед запросом
+	method      string
+	path        string
+	contentType string
+	body        string
+	status      int
+	code        apperror.Code // код ошибки для ответов с ошибкой
+	check       func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder)
+}
+
+// v1 возвращает путь маршрута API версии 1
+func v1(path string) string {
+	return routes.Prefix + path
+}
+
+// graphqlQuery возвращает путь запроса GraphQL методом GET
+func graphqlQuery(query string) string {
+	return "/graphql?query=" + url.QueryEscape(query)
+}
+
+// seedBody параметры генератора для сценария seed: два опубликованных опроса
+// с двумя вопросами и десятью респондентами, половина из которых отвечает
+const seedBody = `{"seed":42,"polls":2,"questions":2,"options":4,"respondents":10,"response_rate":0.5,"distribution":"custom","weights":[1,0,2,3],"publish":true}`
+
+// sortedResults упорядочивает результаты по вопросу и варианту: варианты с равным
+// количеством голосов возвращаются в произвольном порядке
+func sortedResults(results []api.PollResult) []api.PollResult {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Question != results[j].Question {
+			return results[i].Question < results[j].Question
+		}
+		return results[i].Answer < results[j].Answer
+	})
+	return results
+}
+
+// assertNoAnswers проверяет, что ответы пользователя userID не сохранены
+func assertNoAnswers(t *testing.T, userID uint) {
+	t.Helper()
+
+	var saved int64
+	if err := db.DB.Model(&models.Answer{}).Where("user_id = ?", userID).Count(&saved).Error; err != nil {
+		t.Fatal(err)
+	}
+	if saved != 0 {
+		t.Errorf("saved %d answers of user %d, want none", saved, userID)
+	}
+}
+
+// auditActions возвращает действия из журнала аудита опроса от новых к старым
+func auditActions(t *testing.T, s *testServer, pollID uint) []string {
+	t.Helper()
+
+	var actions []string
+	for _, entry := range decode[api.AuditResponse](t, s.mustDo(http.MethodGet, v1(fmt.Sprintf("/poll/%d/audit", pollID)), "")).Entries {
+		actions = append(actions, entry.Action)
+	}
+	return actions
+}
+
+// templateAuditActions возвращает типы изменений из журнала шаблона, новые первыми
+func templateAuditActions(t *testing.T, s *testServer, templateID uint) []string {
+	t.Helper()
+
+	var actions []string
+	for _, entry := range decode[api.TemplateAuditResponse](t, s.mustDo(http.MethodGet, v1(fmt.Sprintf("/templates/%d/audit", templateID)), "")).Entries {
+		actions = append(actions, entry.Action)
+	}
+	return actions
+}
+
+// createTemplate создает шаблон 1 "colors" из опроса 1
+func createTemplate(s *testServer) {
+	s.mustDo(http.MethodPost, v1("/templates"), `{"name":"colors","poll_id":1}`)
+}
+
+// routeCases сценарии для всех маршрутов сервиса, включая ответы с ошибками.
+// Каждый сценарий выполняется на новой базе с опросами standardPolls.
+var routeCases = []routeCase{
+	// Служебные маршруты
+	{name: "check alive", method: http.MethodGet, path: "/check_alive", status: http.StatusOK},
+	{name: "healthz", method: http.MethodGet, path: "/healthz", status: http.StatusOK},
+	{name: "readyz", method: http.MethodGet, path: "/readyz", status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			report := decode[health.Report](t, rec)
+			for _, component := range []string{"database", "migrations"} {
+				if got := report.Components[component].Status; got != health.StatusOK {
+					t.Errorf("%s status = %q, want %q", component, got, health.StatusOK)
+				}
+			}
+		}},
+	{name: "metrics", method: http.MethodGet, path: "/metrics", status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			if !strings.Contains(rec.Body.String(), "# HELP") {
+				t.Errorf("body is not in Prometheus format: %.200s", rec.Body.String())
+			}
+		}},
+
+	// GraphQL
+	{name: "graphql get", method: http.MethodGet, path: graphqlQuery(`{ poll(id: "1") { title questions { text } } }`), status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			if got := rec.Body.String(); !strings.Contains(got, `"title":"Colors"`) || !strings.Contains(got, `"text":"Colors you wear"`) {
+				t.Errorf("unexpected body: %s", got)
+			}
+		}},
+	{name: "graphql post", method: http.MethodPost, path: "/graphql", body: `{"query":"{ polls { id } }"}`, status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			response := decode[graphqlapi.Response](t, rec)
+			if len(response.Errors) > 0 {
+				t.Fatalf("errors: %+v", response.Errors)
+			}
+			if got := rec.Body.String(); strings.Count(got, `"id"`) != 3 {
+				t.Errorf("want 3 polls, got %s", got)
+			}
+		}},
+	{name: "graphql missing poll", method: http.MethodGet, path: graphqlQuery(`{ poll(id: "999") { id } }`), status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			response := decode[graphqlapi.Response](t, rec)
+			if len(response.Errors) != 1 || response.Errors[0].Extensions["code"] != string(apperror.CodePollNotFound) {
+				t.Errorf("errors = %+v, want one %s", response.Errors, apperror.CodePollNotFound)
+			}
+		}},
+	{name: "graphql invalid query", method: http.MethodPost, path: "/graphql", body: `{"query":"{ nope }"}`, status: http.StatusBadRequest},
+	{name: "graphql respondents page", method: http.MethodGet, path: graphqlQuery(`{ poll(id: "1") { respondentCount respondents(first: 2, offset: 1) { userId answers { options { text } } } } }`), status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			response := decode[struct {
+				Data struct {
+					Poll struct {
+						RespondentCount int
+						Respondents     []struct {
+							UserID  string `json:"userId"`
+							Answers []struct {
+								Options []struct{ Text string }
+							}
+						}
+					}
+				}
+				Errors []graphqlapi.Error
+			}](t, rec)
+			if len(response.Errors) > 0 {
+				t.Fatalf("errors: %+v", response.Errors)
+			}
+			poll := response.Data.Poll
+			if poll.RespondentCount != 3 {
+				t.Errorf("respondentCount = %d, want 3", poll.RespondentCount)
+			}
+			// Пользователи 1 и 2 выбрали Red, пользователь 3 — Green; первый пропущен
+			var got []string
+			for _, respondent := range poll.Respondents {
+				for _, answer := range respondent.Answers {
+					for _, option := range answer.Options {
+						got = append(got, respondent.UserID+":"+option.Text)
+					}
+				}
+			}
+			if want := []string{"2:Red", "3:Green"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("respondents = %v, want %v", got, want)
+			}
+		}},
+	{name: "graphql respondents pages", method: http.MethodGet, path: graphqlQuery(`{ a: poll(id: "1") { respondents(first: 2) { userId } } b: poll(id: "1") { respondents(first: 5, offset: 1) { userId } } c: poll(id: "2") { respondents(first: 1) { userId } } }`), status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			type page struct {
+				Respondents []struct {
+					UserID string `json:"userId"`
+				}
+			}
+			response := decode[struct {
+				Data   struct{ A, B, C page }
+				Errors []graphqlapi.Error
+			}](t, rec)
+			if len(response.Errors) > 0 {
+				t.Fatalf("errors: %+v", response.Errors)
+			}
+			// Страницы загружаются одним запросом: пересекающиеся страницы опроса 1 и страница опроса 2
+			userIDs := func(p page) []string {
+				var ids []string
+				for _, respondent := range p.Respondents {
+					ids = append(ids, respondent.UserID)
+				}
+				return ids
+			}
+			pages := []struct {
+				name string
+				page page
+				want []string
+			}{
+				{"a", response.Data.A, []string{"1", "2"}},
+				{"b", response.Data.B, []string{"2", "3"}},
+				{"c", response.Data.C, []string{"4"}},
+			}
+			for _, p := range pages {
+				if got := userIDs(p.page); !reflect.DeepEqual(got, p.want) {
+					t.Errorf("%s respondents = %v, want %v", p.name, got, p.want)
+				}
+			}
+		}},
+	{name: "graphql too complex", method: http.MethodGet, path: graphqlQuery(`{ polls(first: 100) { respondents(first: 100) { userId } } }`), status: http.StatusBadRequest,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			response := decode[graphqlapi.Response](t, rec)
+			if len(response.Errors) != 1 || response.Errors[0].Extensions["code"] != string(apperror.CodeInvalidRequest) {
+				t.Errorf("errors = %+v, want one %s", response.Errors, apperror.CodeInvalidRequest)
+			}
+		}},
+
+	// Схема и описание API
+	{name: "migrate", method: http.MethodPost, path: v1("/migrate"), status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			// В базе уже есть опросы, поэтому фикстуры миграции не создаются
+			if total := decode[api.PollListResponse](t, s.mustDo(http.MethodGet, v1("/polls"), "")).Total; total != 3 {
+				t.Errorf("total = %d after migrate, want 3", total)
+			}
+		}},
+	{name: "db stats", method: http.MethodGet, path: v1("/db/stats"), status: http.StatusOK},
+	{name: "openapi", method: http.MethodGet, path: v1("/openapi.json"), status: http.StatusOK},
+	{name: "docs", method: http.MethodGet, path: v1("/docs/"), status: http.StatusOK},
+
+	// Генератор синтетических данных
+	{name: "seed", setup: func(s *testServer) { s.mustDo(http.MethodPost, v1("/seed"), seedBody) },
+		method: http.MethodPost, path: v1("/seed"), body: seedBody, status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			seeded := decode[api.SeedResponse](t, rec)
+			if len(seeded.Polls) != 2 || seeded.Polls[0].ID != 6 || seeded.Polls[0].Revision != 1 || seeded.Polls[0].Questions != 2 {
+				t.Fatalf("polls = %+v", seeded.Polls)
+			}
+			if seeded.Answers == 0 || seeded.Answers > 2*2*10 {
+				t.Errorf("answers = %d, want 1-40", seeded.Answers)
+			}
+			// Тот же seed создает те же ответы, что и при первом запросе в setup
+			for i, poll := range seeded.Polls {
+				first := sortedResults(decode[api.PollResultsResponse](t, s.mustDo(http.MethodGet, v1(fmt.Sprintf("/poll/%d/results", 4+i)), "")).Results)
+				second := sortedResults(decode[api.PollResultsResponse](t, s.mustDo(http.MethodGet, v1(fmt.Sprintf("/poll/%d/results", poll.ID)), "")).Results)
+				if !reflect.DeepEqual(first, second) {
+					t.Errorf("poll %d results = %+v, want %+v", poll.ID, second, first)
+				}
+			}
+		}},
+	{name: "seed with defaults", method: http.MethodPost, path: v1("/seed"), status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			if seeded := decode[api.SeedResponse](t, rec); len(seeded.Polls) != 3 || seeded.Answers != 3*3*6 {
+				t.Errorf("polls = %d, answers = %d, want 3 and 54", len(seeded.Polls), seeded.Answers)
+			}
+		}},
+	{name: "seed with invalid distribution", method: http.MethodPost, path: v1("/seed"), body: `{"distribution":"normal"}`, status: http.StatusBadRequest, code: apperror.CodeValidationFailed},
+	{name: "seed with missing weights", method: http.MethodPost, path: v1("/seed"), body: `{"distribution":"custom","weights":[1]}`, status: http.StatusBadRequest, code: apperror.CodeValidationFailed},
+	{name: "seed too many answers", method: http.MethodPost, path: v1("/seed"), body: `{"polls":1000,"questions":100,"respondents":100}`, status: http.StatusBadRequest, code: apperror.CodeValidationFailed},
+
+	// Опросы
+	{name: "create poll", method: http.MethodPost, path: v1("/poll/create"), body: `{"title":"New","url":"new"}`, status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			poll := decode[api.PollResponse](t, s.mustDo(http.MethodGet, v1("/poll/4"), ""))
+			if poll.Title != "New" || poll.URL != "new" {
+				t.Errorf("poll = %+v", poll)
+			}
+			if actions := auditActions(t, s, 4); !reflect.DeepEqual(actions, []string{"create_poll"}) {
+				t.Errorf("audit actions = %v", actions)
+			}
+		}},
+	{name: "create poll without title", method: http.MethodPost, path: v1("/poll/create"), body: `{"url":"new"}`, status: http.StatusBadRequest, code: apperror.CodeValidationFailed},
+	{name: "create poll with malformed body", method: http.MethodPost, path: v1("/poll/create"), body: `{`, status: http.StatusBadRequest, code: apperror.CodeInvalidRequest},
+	{name: "list polls", method: http.MethodGet, path: v1("/polls?limit=2&offset=1"), status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			list := decode[api.PollListResponse](t, rec)
+			if list.Total != 3 || len(list.Polls) != 2 || list.Polls[0].ID != 2 || list.Polls[0].Questions != 1 {
+				t.Errorf("list = %+v", list)
+			}
+		}},
+	{name: "list polls with limit out of range", method: http.MethodGet, path: v1("/polls?limit=500"), status: http.StatusBadRequest, code: apperror.CodeValidationFailed},
+	{name: "list polls with malformed limit", method: http.MethodGet, path: v1("/polls?limit=x"), status: http.StatusBadRequest, code: apperror.CodeValidationFailed},
+	{name: "get poll", method: http.MethodGet, path: v1("/poll/1"), status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			poll := decode[api.PollResponse](t, rec)
+			if poll.Title != "Colors" || len(poll.Questions) != 2 || len(poll.Questions[1].PossibleAnswers) != 3 {
+				t.Errorf("poll = %+v", poll)
+			}
+		}},
+	{name: "get missing poll", method: http.MethodGet, path: v1("/poll/999"), status: http.StatusNotFound, code: apperror.CodePollNotFound},
+	{name: "get poll with invalid id", method: http.MethodGet, path: v1("/poll/abc"), status: http.StatusBadRequest, code: apperror.CodeValidationFailed},
+	{name: "update poll", method: http.MethodPatch, path: v1("/poll/1"), body: `{"title":"Colours"}`, status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			poll := decode[api.PollResponse](t, s.mustDo(http.MethodGet, v1("/poll/1"), ""))
+			if poll.Title != "Colours" || poll.URL != "colors" {
+				t.Errorf("poll = %+v", poll)
+			}
+		}},
+	{name: "update missing poll", method: http.MethodPatch, path: v1("/poll/999"), body: `{"title":"Colours"}`, status: http.StatusNotFound, code: apperror.CodePollNotFound},
+	{name: "update poll with malformed body", method: http.MethodPatch, path: v1("/poll/1"), body: `[`, status: http.StatusBadRequest, code: apperror.CodeInvalidRequest},
+	{name: "delete poll", method: http.MethodDelete, path: v1("/poll/1"), status: http.StatusOK,
+		setup: func(s *testServer) {
+			s.mustDo(http.MethodPost, v1("/poll/1/answer"), answerBody(1, 5))
+			if series := `answers_registered_total{mode="sync",poll_id="1"}`; !strings.Contains(s.mustDo(http.MethodGet, "/metrics", "").Body.String(), series) {
+				s.t.Errorf("metrics do not contain %s", series)
+			}
+		},
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			if got := s.do(http.MethodGet, v1("/poll/1"), "", "").Code; got != http.StatusNotFound {
+				t.Errorf("deleted poll status = %d, want %d", got, http.StatusNotFound)
+			}
+			// Ряды метрик удаленного опроса удаляются
+			if series := `poll_id="1"`; strings.Contains(s.mustDo(http.MethodGet, "/metrics", "").Body.String(), series) {
+				t.Errorf("metrics still contain %s after the poll is deleted", series)
+			}
+		}},
+	{name: "delete missing poll", method: http.MethodDelete, path: v1("/poll/999"), status: http.StatusNotFound, code: apperror.CodePollNotFound},
+
+	// Описания опросов
+	{name: "import definition", method: http.MethodPost, path: v1("/polls/import"), body: `{"title":"Imported","url":"imported","questions":[{"text":"Q","type":"single","options":["A","B"]}]}`, status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			imported := decode[api.ImportPollsResponse](t, rec)
+			if len(imported.Polls) != 1 || imported.Polls[0].ID != 4 || imported.Polls[0].Questions != 1 {
+				t.Errorf("imported = %+v", imported)
+			}
+			if actions := auditActions(t, s, 4); !reflect.DeepEqual(actions, []string{"import_poll"}) {
+				t.Errorf("audit actions = %v", actions)
+			}
+		}},
+	{name: "import yaml definitions", method: http.MethodPost, path: v1("/polls/import"), contentType: "application/yaml", body: "- title: A\n  url: a\n- title: B\n  url: b\n  settings:\n    publish: true\n", status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			imported := decode[api.ImportPollsResponse](t, rec)
+			if len(imported.Polls) != 2 || imported.Polls[0].Revision != 0 || imported.Polls[1].Revision != 1 {
+				t.Errorf("imported = %+v", imported)
+			}
+		}},
+	{name: "import invalid definition", method: http.MethodPost, path: v1("/polls/import"), body: `[{"title":""}]`, status: http.StatusBadRequest, code: apperror.CodeValidationFailed},
+	{name: "import malformed definition", method: http.MethodPost, path: v1("/polls/import"), body: `{`, status: http.StatusBadRequest, code: apperror.CodeInvalidRequest},
+	{name: "export definition", method: http.MethodGet, path: v1("/poll/1/definition"), status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			exported := decode[definition.Definition](t, rec)
+			if exported.Title != "Colors" || len(exported.Questions) != 2 || exported.Questions[0].Options[2] != "Blue" {
+				t.Errorf("definition = %+v", exported)
+			}
+		}},
+	{name: "export definition as yaml", method: http.MethodGet, path: v1("/poll/2/definition?format=yaml"), status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			if got := rec.Body.String(); !strings.Contains(got, "publish: true") {
+				t.Errorf("definition of a published poll: %s", got)
+			}
+		}},
+	{name: "export definition in unknown format", method: http.MethodGet, path: v1("/poll/1/definition?format=xml"), status: http.StatusBadRequest, code: apperror.CodeValidationFailed},
+	{name: "export definition of missing poll", method: http.MethodGet, path: v1("/poll/999/definition"), status: http.StatusNotFound, code: apperror.CodePollNotFound},
+	{name: "clone poll", method: http.MethodPost, path: v1("/poll/2/clone"), body: `{"url":"sizes-copy"}`, status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			clone := decode[api.PollSummary](t, rec)
+			if clone.ID != 4 || clone.Title != "Sizes" || clone.Revision != 0 || clone.Questions != 1 {
+				t.Errorf("clone = %+v", clone)
+			}
+			entries := decode[api.AuditResponse](t, s.mustDo(http.MethodGet, v1("/poll/4/audit"), "")).Entries
+			if len(entries) != 1 || entries[0].Action != "clone_poll" || !strings.Contains(string(entries[0].Diff), `"cloned_from":{"before":null,"after":2}`) {
+				t.Errorf("audit = %+v", entries)
+			}
+		}},
+	{name: "clone poll without url", method: http.MethodPost, path: v1("/poll/1/clone"), body: `{}`, status: http.StatusBadRequest, code: apperror.CodeValidationFailed},
+	{name: "clone missing poll", method: http.MethodPost, path: v1("/poll/999/clone"), body: `{"url":"copy"}`, status: http.StatusNotFound, code: apperror.CodePollNotFound},
+
+	// Вопросы
+	{name: "add question", method: http.MethodPost, path: v1("/poll/3/question"), body: `{"text":"Pets","type":"multiple","answers":["Cat","Dog"]}`, status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			poll := decode[api.PollResponse](t, s.mustDo(http.MethodGet, v1("/poll/3"), ""))
+			if len(poll.Questions) != 1 || poll.Questions[0].ID != 4 || len(poll.Questions[0].PossibleAnswers) != 2 {
+				t.Errorf("poll = %+v", poll)
+			}
+		}},
+	{name: "add question with invalid type", method: http.MethodPost, path: v1("/poll/3/question"), body: `{"text":"Pets","type":"any","answers":["Cat"]}`, status: http.StatusBadRequest, code: apperror.CodeValidationFailed},
+	{name: "add question to missing poll", method: http.MethodPost, path: v1("/poll/999/question"), body: `{"text":"Pets","type":"single","answers":["Cat"]}`, status: http.StatusNotFound, code: apperror.CodePollNotFound},
+	{name: "update question", method: http.MethodPatch, path: v1("/poll/1/question/1"), body: `{"text":"Best color","type":"single","options":[{"id":1},{"id":2},{"id":3,"text":"Navy"}]}`, status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			poll := decode[api.PollResponse](t, s.mustDo(http.MethodGet, v1("/poll/1"), ""))
+			question := poll.Questions[0]
+			if question.Text != "Best color" || question.PossibleAnswers[2].Text != "Navy" {
+				t.Errorf("question = %+v", question)
+			}
+		}},
+	{name: "update question removing voted option", method: http.MethodPatch, path: v1("/poll/1/question/1"), body: `{"text":"Favorite color","type":"single","options":[{"id":2},{"id":3}]}`, status: http.StatusConflict, code: apperror.CodeOptionsHaveVotes},
+	{name: "update question removing voted option with force", method: http.MethodPatch, path: v1("/poll/1/question/1?force=true"), body: `{"text":"Favorite color","type":"single","options":[{"id":2},{"id":3}]}`, status: http.StatusOK},
+	{name: "update question of another poll", method: http.MethodPatch, path: v1("/poll/1/question/3"), body: `{"text":"Size","type":"single","answers":["S"]}`, status: http.StatusNotFound, code: apperror.CodeQuestionNotFound},
+	{name: "delete question", method: http.MethodDelete, path: v1("/poll/1/question/2"), status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			poll := decode[api.PollResponse](t, s.mustDo(http.MethodGet, v1("/poll/1"), ""))
+			if len(poll.Questions) != 1 || poll.Questions[0].ID != 1 {
+				t.Errorf("questions = %+v", poll.Questions)
+			}
+		}},
+	{name: "delete question of another poll", method: http.MethodDelete, path: v1("/poll/1/question/3"), status: http.StatusNotFound, code: apperror.CodeQuestionNotFound},
+
+	// Ответы и результаты
+	{name: "register answer", method: http.MethodPost, path: v1("/poll/1/answer"), body: `{"attributes":{"access_token":"t","user_id":5,"results":[{"question":"Favorite color","answer":"Blue"},{"question":"Colors you wear","answer":"Red,Green"}]}}`, status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			answer := decode[api.AnswerResponse](t, rec)
+			if len(answer.Results) != 2 || answer.Results[0].QuestionID != 1 || len(answer.Results[1].Answers) != 2 {
+				t.Errorf("answer = %+v", answer)
+			}
+		}},
+	{name: "register answer to published poll", method: http.MethodPost, path: v1("/poll/2/answer"), body: `{"attributes":{"access_token":"t","user_id":5,"results":[{"question":"Size","answer":"L"}]}}`, status: http.StatusOK},
+	{name: "register answer to deleted question of published poll",
+		setup:  func(s *testServer) { s.mustDo(http.MethodDelete, v1("/poll/2/question/3"), "") },
+		method: http.MethodPost, path: v1("/poll/2/answer"), body: `{"attributes":{"access_token":"t","user_id":5,"results":[{"question":"Size","answer":"S"}]}}`, status: http.StatusBadRequest, code: apperror.CodeValidationFailed,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			assertNoAnswers(t, 5)
+		}},
+	{name: "register answer with deleted option of published poll",
+		setup: func(s *testServer) {
+			s.mustDo(http.MethodPatch, v1("/poll/2/question/3?force=true"), `{"text":"Size","type":"single","options":[{"id":8},{"id":9}]}`)
+		},
+		method: http.MethodPost, path: v1("/poll/2/answer"), body: `{"attributes":{"access_token":"t","user_id":5,"results":[{"question":"Size","answer":"S"}]}}`, status: http.StatusBadRequest, code: apperror.CodeValidationFailed,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			assertNoAnswers(t, 5)
+		}},
+	{name: "register answer to unknown question", method: http.MethodPost, path: v1("/poll/1/answer"), body: `{"attributes":{"access_token":"t","user_id":5,"results":[{"question":"Size","answer":"S"}]}}`, status: http.StatusBadRequest, code: apperror.CodeValidationFailed},
+	{name: "register several answers to single question", method: http.MethodPost, path: v1("/poll/1/answer"), body: `{"attributes":{"access_token":"t","user_id":5,"results":[{"question":"Favorite color","answer":"Red,Blue"}]}}`, status: http.StatusBadRequest, code: apperror.CodeValidationFailed},
+	{name: "register answer to missing poll", method: http.MethodPost, path: v1("/poll/999/answer"), body: `{}`, status: http.StatusNotFound, code: apperror.CodePollNotFound},
+
+	// Асинхронный прием ответов
+	{name: "register answer asynchronously", setup: func(s *testServer) { s.startIngest(true) },
+		method: http.MethodPost, path: v1("/poll/1/answer"), body: answerBody(1, 5), status: http.StatusAccepted,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			receipt := decode[api.ReceiptResponse](t, rec)
+			if receipt.Status != api.ReceiptPending || receipt.PollID != 1 || receipt.UserID != 5 || len(receipt.ID) != 32 {
+				t.Fatalf("receipt = %+v", receipt)
+			}
+
+			// После остановки приема ответы сохранены, а квитанция читается из базы
+			s.stopIngest()
+			receipt = decode[api.ReceiptResponse](t, s.mustDo(http.MethodGet, v1("/receipts/"+receipt.ID), ""))
+			if receipt.Status != api.ReceiptPersisted || receipt.PersistedAt == nil {
+				t.Errorf("receipt = %+v, want persisted", receipt)
+			}
+			for _, result := range decode[api.PollResultsResponse](t, s.mustDo(http.MethodGet, v1("/poll/1/results"), "")).Results {
+				if result.Question == "Favorite color" && result.Answer == "Blue" && result.AnswerCnt != 1 {
+					t.Errorf("Blue votes = %d, want 1", result.AnswerCnt)
+				}
+			}
+		}},
+	{name: "register answer asynchronously to changed poll",
+		setup: func(s *testServer) {
+			s.startIngest(true)
+			if rec := s.do(http.MethodPost, v1("/poll/3/answer"), "", `{"attributes":{"access_token":"t","user_id":5,"results":[{"question":"Pets","answer":"Cat"}]}}`); rec.Code != http.StatusBadRequest {
+				s.t.Fatalf("answer before the question is added: status = %d; body: %s", rec.Code, rec.Body.String())
+			}
+			s.mustDo(http.MethodPost, v1("/poll/3/question"), `{"text":"Pets","type":"single","answers":["Cat","Dog"]}`)
+		},
+		method: http.MethodPost, path: v1("/poll/3/answer"), body: `{"attributes":{"access_token":"t","user_id":5,"results":[{"question":"Pets","answer":"Cat"}]}}`, status: http.StatusAccepted},
+	{name: "delete question with queued answers",
+		setup: func(s *testServer) {
+			s.startIngest(false)
+			if rec := s.do(http.MethodPost, v1("/poll/1/answer"), "", answerBody(1, 5)); rec.Code != http.StatusAccepted {
+				s.t.Fatalf("queued answer: status = %d; body: %s", rec.Code, rec.Body.String())
+			}
+		},
+		method: http.MethodDelete, path: v1("/poll/1/question/1"), status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			// Ответы проверены по структуре опроса до удаления вопроса, но при сохранении
+			// отклоняются целиком, включая ответ на оставшийся вопрос
+			s.stopIngest()
+			assertNoAnswers(t, 5)
+		}},
+	{name: "register invalid answer asynchronously", setup: func(s *testServer) { s.startIngest(true) },
+		method: http.MethodPost, path: v1("/poll/1/answer"), body: `{"attributes":{"access_token":"t","user_id":5,"results":[{"question":"Favorite color","answer":"Red,Blue"}]}}`, status: http.StatusBadRequest, code: apperror.CodeValidationFailed},
+	{name: "register answer asynchronously to missing poll", setup: func(s *testServer) { s.startIngest(true) },
+		method: http.MethodPost, path: v1("/poll/999/answer"), body: `{}`, status: http.StatusNotFound, code: apperror.CodePollNotFound},
+	{name: "register answer to full queue",
+		setup: func(s *testServer) {
+			s.startIngest(false)
+			if rec := s.do(http.MethodPost, v1("/poll/1/answer"), "", answerBody(1, 5)); rec.Code != http.StatusAccepted {
+				s.t.Fatalf("first answer: status = %d; body: %s", rec.Code, rec.Body.String())
+			}
+		},
+		method: http.MethodPost, path: v1("/poll/1/answer"), body: answerBody(1, 6), status: http.StatusServiceUnavailable, code: apperror.CodeIngestUnavailable,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			if got := rec.Header().Get("Retry-After"); got != "1" {
+				t.Errorf("Retry-After = %q, want 1", got)
+			}
+		}},
+	{name: "missing receipt", method: http.MethodGet, path: v1("/receipts/00000000000000000000000000000000"), status: http.StatusNotFound, code: apperror.CodeReceiptNotFound},
+
+	{name: "results", method: http.MethodGet, path: v1("/poll/1/results"), status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			for _, result := range decode[api.PollResultsResponse](t, rec).Results {
+				if result.Question == "Favorite color" && result.Answer == "Red" && result.AnswerCnt != 2 {
+					t.Errorf("Red votes = %d, want 2", result.AnswerCnt)
+				}
+			}
+		}},
+
+	// Кэш и условные запросы: ответ с тем же ETag не меняется, изменения сбрасывают кэш
+	{name: "get poll not modified", method: http.MethodGet, path: v1("/poll/1"), status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			etag := rec.Header().Get("ETag")
+			if revalidated := s.conditionalGet(v1("/poll/1"), etag); revalidated.Code != http.StatusNotModified || revalidated.Body.Len() != 0 {
+				t.Fatalf("revalidate: status = %d, body = %q, want 304 without body", revalidated.Code, revalidated.Body.String())
+			}
+			if revalidated := s.conditionalGet(v1("/poll/1"), `"other", W/`+etag); revalidated.Code != http.StatusNotModified {
+				t.Errorf("revalidate with a weak ETag in a list: status = %d, want 304", revalidated.Code)
+			}
+
+			s.mustDo(http.MethodPatch, v1("/poll/1"), `{"title":"Colours"}`)
+			updated := s.conditionalGet(v1("/poll/1"), etag)
+			if updated.Code != http.StatusOK || updated.Header().Get("ETag") == etag {
+				t.Fatalf("after update: status = %d, ETag = %s, want 200 with a new ETag", updated.Code, updated.Header().Get("ETag"))
+			}
+			if poll := decode[api.PollResponse](t, updated); poll.Title != "Colours" {
+				t.Errorf("title = %q, want Colours", poll.Title)
+			}
+		}},
+	{name: "get poll after adding question",
+		setup: func(s *testServer) {
+			s.mustDo(http.MethodGet, v1("/poll/1"), "")
+			s.mustDo(http.MethodPost, v1("/poll/1/question"), `{"text":"Pets","type":"single","answers":["Cat","Dog"]}`)
+		},
+		method: http.MethodGet, path: v1("/poll/1"), status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			if poll := decode[api.PollResponse](t, rec); len(poll.Questions) != 3 {
+				t.Errorf("questions = %d, want 3", len(poll.Questions))
+			}
+		}},
+	{name: "results after answer", method: http.MethodGet, path: v1("/poll/1/results"), status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			etag := rec.Header().Get("ETag")
+			if revalidated := s.conditionalGet(v1("/poll/1/results"), etag); revalidated.Code != http.StatusNotModified {
+				t.Fatalf("revalidate: status = %d, want 304", revalidated.Code)
+			}
+
+			s.mustDo(http.MethodPost, v1("/poll/1/answer"), answerBody(1, 5))
+			updated := s.conditionalGet(v1("/poll/1/results"), etag)
+			if updated.Code != http.StatusOK {
+				t.Fatalf("after answer: status = %d, want 200", updated.Code)
+			}
+			for _, result := range decode[api.PollResultsResponse](t, updated).Results {
+				if result.Question == "Favorite color" && result.Answer == "Blue" && result.AnswerCnt != 1 {
+					t.Errorf("Blue votes = %d, want 1", result.AnswerCnt)
+				}
+			}
+		}},
+	{name: "revision results after answer", method: http.MethodGet, path: v1("/poll/2/results?revision=1"), status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			s.mustDo(http.MethodGet, v1("/poll/2/results?revision=merged"), "")
+			s.mustDo(http.MethodPost, v1("/poll/2/answer"), answerBody(2, 5))
+
+			results := decode[api.RevisionResultsResponse](t, s.mustDo(http.MethodGet, v1("/poll/2/results?revision=1"), "")).Results
+			if len(results) != 3 || results[1].Answer != "M" || results[1].AnswerCnt != 2 {
+				t.Errorf("revision results = %+v, want 2 votes for M", results)
+			}
+			merged := decode[api.RevisionResultsResponse](t, s.mustDo(http.MethodGet, v1("/poll/2/results?revision=merged"), "")).Results
+			if len(merged) != 3 || merged[1].AnswerCnt != 2 {
+				t.Errorf("merged results = %+v, want 2 votes for M", merged)
+			}
+		}},
+	{name: "results after asynchronous answer", setup: func(s *testServer) { s.startIngest(true) },
+		method: http.MethodGet, path: v1("/poll/1/results"), status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			if accepted := s.do(http.MethodPost, v1("/poll/1/answer"), "", answerBody(1, 5)); accepted.Code != http.StatusAccepted {
+				t.Fatalf("answer: status = %d; body: %s", accepted.Code, accepted.Body.String())
+			}
+			s.stopIngest()
+
+			updated := s.conditionalGet(v1("/poll/1/results"), rec.Header().Get("ETag"))
+			if updated.Code != http.StatusOK {
+				t.Fatalf("after answer: status = %d, want 200", updated.Code)
+			}
+			for _, result := range decode[api.PollResultsResponse](t, updated).Results {
+				if result.Question == "Favorite color" && result.Answer == "Blue" && result.AnswerCnt != 1 {
+					t.Errorf("Blue votes = %d, want 1", result.AnswerCnt)
+				}
+			}
+		}},
+	{name: "results after removing voted option",
+		setup: func(s *testServer) {
+			s.mustDo(http.MethodPatch, v1("/poll/1/question/1?force=true"), `{"text":"Favorite color","type":"single","options":[{"id":2},{"id":3}]}`)
+		},
+		method: http.MethodGet, path: v1("/poll/1/results"), status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			// Ответы на вопрос остаются, поэтому доля Green считается от трех ответов
+			want := []api.PollResult{
+				{Question: "Favorite color", Answer: "Green", AnswerCnt: 1, AnswerPercentage: "33.333"},
+				{Question: "Colors you wear", Answer: "Red", AnswerCnt: 1, AnswerPercentage: "100.000"},
+				{Question: "Colors you wear", Answer: "Blue", AnswerCnt: 1, AnswerPercentage: "100.000"},
+			}
+			if results := decode[api.PollResultsResponse](t, rec).Results; !reflect.DeepEqual(results, want) {
+				t.Errorf("results = %+v, want %+v", results, want)
+			}
+		}},
+	{name: "results after deleting question",
+		setup:  func(s *testServer) { s.mustDo(http.MethodDelete, v1("/poll/1/question/2"), "") },
+		method: http.MethodGet, path: v1("/poll/1/results"), status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			for _, result := range decode[api.PollResultsResponse](t, rec).Results {
+				if result.Question != "Favorite color" {
+					t.Errorf("result of deleted question: %+v", result)
+				}
+			}
+		}},
+	{name: "results of missing poll", method: http.MethodGet, path: v1("/poll/999/results"), status: http.StatusNotFound, code: apperror.CodePollNotFound},
+	{name: "revision results", method: http.MethodGet, path: v1("/poll/2/results?revision=1"), status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			results := decode[api.RevisionResultsResponse](t, rec).Results
+			if len(results) != 3 || results[1].Answer != "M" || results[1].AnswerCnt != 1 {
+				t.Errorf("results = %+v", results)
+			}
+		}},
+	{name: "merged results", method: http.MethodGet, path: v1("/poll/2/results?revision=merged"), status: http.StatusOK},
+	{name: "merged results of unpublished poll", method: http.MethodGet, path: v1("/poll/1/results?revision=merged"), status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			// Вариантов нет ни в одной ревизии: порядок задают идентификаторы, а не обход карты голосов
+			var got [][3]uint
+			for _, result := range decode[api.RevisionResultsResponse](t, rec).Results {
+				got = append(got, [3]uint{result.QuestionID, result.PossibleAnswerID, uint(result.AnswerCnt)})
+			}
+			want := [][3]uint{{1, 1, 2}, {1, 2, 1}, {2, 4, 1}, {2, 6, 1}}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("merged results (question, option, votes) = %v, want %v", got, want)
+			}
+		}},
+	{name: "results of missing revision", method: http.MethodGet, path: v1("/poll/2/results?revision=9"), status: http.StatusNotFound, code: apperror.CodeRevisionNotFound},
+	{name: "results with invalid revision", method: http.MethodGet, path: v1("/poll/2/results?revision=x"), status: http.StatusBadRequest, code: apperror.CodeValidationFailed},
+
+	// Ревизии и журнал изменений
+	{name: "publish", method: http.MethodPost, path: v1("/poll/1/publish"), status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			if revision := decode[api.Revision](t, rec); revision.Number != 1 || len(revision.Questions) != 2 {
+				t.Errorf("revision = %+v", revision)
+			}
+			if actions := auditActions(t, s, 1); !reflect.DeepEqual(actions, []string{"publish_poll"}) {
+				t.Errorf("audit actions = %v", actions)
+			}
+		}},
+	{name: "publish missing poll", method: http.MethodPost, path: v1("/poll/999/publish"), status: http.StatusNotFound, code: apperror.CodePollNotFound},
+	{name: "revisions", method: http.MethodGet, path: v1("/poll/2/revisions"), status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			if revisions := decode[api.RevisionsResponse](t, rec); revisions.Current != 1 || len(revisions.Revisions) != 1 {
+				t.Errorf("revisions = %+v", revisions)
+			}
+		}},
+	{name: "revisions of missing poll", method: http.MethodGet, path: v1("/poll/999/revisions"), status: http.StatusNotFound, code: apperror.CodePollNotFound},
+	{name: "revision", method: http.MethodGet, path: v1("/poll/2/revisions/1"), status: http.StatusOK},
+	{name: "missing revision", method: http.MethodGet, path: v1("/poll/2/revisions/9"), status: http.StatusNotFound, code: apperror.CodeRevisionNotFound},
+	{name: "audit", method: http.MethodGet, path: v1("/poll/1/audit"), status: http.StatusOK,
+		setup: func(s *testServer) {
+			s.mustDo(http.MethodPatch, v1("/poll/1"), `{"title":"Colours"}`)
+		},
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			if audit := decode[api.AuditResponse](t, rec); len(audit.Entries) != 1 || audit.Entries[0].Action != "update_poll" {
+				t.Errorf("audit = %+v", audit)
+			}
+		}},
+	{name: "audit of question", method: http.MethodGet, path: v1("/poll/1/audit?question_id=2"), status: http.StatusOK,
+		setup: func(s *testServer) {
+			s.mustDo(http.MethodPatch, v1("/poll/1"), `{"title":"Colours"}`)
+			s.mustDo(http.MethodDelete, v1("/poll/1/question/2"), "")
+		},
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			if audit := decode[api.AuditResponse](t, rec); len(audit.Entries) != 1 || audit.Entries[0].Action != "delete_question" {
+				t.Errorf("audit = %+v", audit)
+			}
+		}},
+	{name: "audit with invalid question id", method: http.MethodGet, path: v1("/poll/1/audit?question_id=abc"), status: http.StatusBadRequest, code: apperror.CodeValidationFailed},
+	{name: "update poll without audit log",
+		setup: func(s *testServer) {
+			if err := db.DB.Migrator().DropTable(&models.AuditLog{}); err != nil {
+				s.t.Fatal(err)
+			}
+		},
+		method: http.MethodPatch, path: v1("/poll/1"), body: `{"title":"Colours"}`, status: http.StatusInternalServerError, code: apperror.CodeInternal,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			// Изменение откатывается вместе с записью журнала аудита
+			if poll := decode[api.PollResponse](t, s.mustDo(http.MethodGet, v1("/poll/1"), "")); poll.Title != "Colors" {
+				t.Errorf("title = %q, want Colors", poll.Title)
+			}
+		}},
+	{name: "audit of missing poll", method: http.MethodGet, path: v1("/poll/999/audit"), status: http.StatusNotFound, code: apperror.CodePollNotFound},
+
+	// Шаблоны
+	{name: "list templates", method: http.MethodGet, path: v1("/templates"), setup: createTemplate, status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			templates := decode[api.TemplatesResponse](t, rec).Templates
+			if len(templates) != 1 || templates[0].Name != "colors" || templates[0].Questions != 2 {
+				t.Errorf("templates = %+v", templates)
+			}
+		}},
+	{name: "create template", method: http.MethodPost, path: v1("/templates"), body: `{"name":"sizes","poll_id":2}`, status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			if actions := templateAuditActions(t, s, 1); !reflect.DeepEqual(actions, []string{"create_template"}) {
+				t.Errorf("template audit actions = %v", actions)
+			}
+		}},
+	{name: "create template without name", method: http.MethodPost, path: v1("/templates"), body: `{"poll_id":2}`, status: http.StatusBadRequest, code: apperror.CodeValidationFailed},
+	{name: "create duplicate template", method: http.MethodPost, path: v1("/templates"), setup: createTemplate, body: `{"name":"colors","poll_id":2}`, status: http.StatusConflict, code: apperror.CodeTemplateExists,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			if templates := decode[api.TemplatesResponse](t, s.mustDo(http.MethodGet, v1("/templates"), "")).Templates; len(templates) != 1 {
+				t.Errorf("templates = %+v", templates)
+			}
+		}},
+	{name: "create template from missing poll", method: http.MethodPost, path: v1("/templates"), body: `{"name":"missing","poll_id":999}`, status: http.StatusNotFound, code: apperror.CodePollNotFound},
+	{name: "get template", method: http.MethodGet, path: v1("/templates/1"), setup: createTemplate, status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			if template := decode[api.Template](t, rec); template.Definition.Title != "Colors" {
+				t.Errorf("template = %+v", template)
+			}
+		}},
+	{name: "get missing template", method: http.MethodGet, path: v1("/templates/1"), status: http.StatusNotFound, code: apperror.CodeTemplateNotFound},
+	{name: "delete template", method: http.MethodDelete, path: v1("/templates/1"), setup: createTemplate, status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			if actions := templateAuditActions(t, s, 1); !reflect.DeepEqual(actions, []string{"delete_template", "create_template"}) {
+				t.Errorf("template audit actions = %v", actions)
+			}
+		}},
+	{name: "get template audit", method: http.MethodGet, path: v1("/templates/1/audit"), setup: createTemplate, status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			entries := decode[api.TemplateAuditResponse](t, rec).Entries
+			if len(entries) != 1 || entries[0].Actor == "" || !strings.Contains(string(entries[0].Diff), `"colors"`) {
+				t.Errorf("entries = %+v", entries)
+			}
+		}},
+	{name: "get missing template audit", method: http.MethodGet, path: v1("/templates/1/audit"), status: http.StatusNotFound, code: apperror.CodeTemplateNotFound},
+	{name: "get template audit with invalid id", method: http.MethodGet, path: v1("/templates/abc/audit"), status: http.StatusBadRequest, code: apperror.CodeValidationFailed},
+	{name: "delete missing template", method: http.MethodDelete, path: v1("/templates/1"), status: http.StatusNotFound, code: apperror.CodeTemplateNotFound},
+	{name: "instantiate template", method: http.MethodPost, path: v1("/templates/1/instantiate"), setup: createTemplate, body: `{"url":"from-template","publish":true}`, status: http.StatusOK,
+		check: func(t *testing.T, s *testServer, rec *httptest.ResponseRecorder) {
+			poll := decode[api.PollResponse](t, s.mustDo(http.MethodGet, v1("/poll/4"), ""))
+			if poll.URL != "from-template" || poll.Revision != 1 || len(poll.Questions) != 2 {
+				t.Errorf("poll = %+v", poll)
+			}
+			if actions := auditActions(t, s, 4); !reflect.DeepEqual(actions, []string{"instantiate_template"}) {
+				t.Errorf("audit actions = %v", actions)
+			}
+		}},
+	{name: "instantiate missing template", method: http.MethodPost, path: v1("/templates/1/instantiate"), body: `{"url":"from-template"}`, status: http.StatusNotFound, code: apperror.CodeTemplateNotFound},
+
+	// Неизвестные маршруты и методы
+	{name: "unknown route", method: http.MethodGet, path: v1("/nope"), status: http.StatusNotFound, code: apperror.CodeRouteNotFound},
+	{name: "unsupported method", method: http.MethodPut, path: v1("/poll/1"), body: `{}`, status: http.StatusMethodNotAllowed, code: apperror.CodeMethodNotAllowed},
+}
+
+// TestRoutes выполняет сценарии routeCases под /api/v1 и по прежним путям без версии
+// и проверяет, что сценарии покрывают все маршруты сервиса
+func TestRoutes(t *testing.T) {
+	covered := make(map[string]bool)
+	run := func(t *testing.T, tc routeCase, legacy bool) {
+		s := newTestServer(t, standardPolls()...)
+		if tc.setup != nil {
+			tc.setup(s)
+		}
+
+		path := tc.path
+		if legacy {
+			path = strings.TrimPrefix(path, routes.Prefix)
+		}
+		rec := s.do(tc.method, path, tc.contentType, tc.body)
+
+		if route, ok := matchRoute(s.router.Routes(), tc.method, path); ok {
+			covered[tc.method+" "+route] = true
+		}
+
+		if rec.Code != tc.status {
+			t.Fatalf("%s %s: status = %d, want %d; body: %s", tc.method, path, rec.Code, tc.status, rec.Body.String())
+		}
+		if tc.code != "" {
+			if got := problemCode(t, rec); got != tc.code {
+				t.Errorf("code = %q, want %q", got, tc.code)
+			}
+		}
+		// Ошибки общих обработчиков (например, проверки идентификаторов) возвращаются
+		// до обработчиков группы маршрутов без версии, поэтому проверяются только успешные ответы
+		if legacy && rec.Code < http.StatusBadRequest && rec.Header().Get("Deprecation") != "true" {
+			t.Errorf("legacy route is not marked deprecated")
+		}
+		if tc.check != nil {
+			tc.check(t, s, rec)
+		}
+	}
+
+	for _, tc := range routeCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc, false)
+		})
+	}
+
+	// Прежние пути без версии ведут себя так же, кроме описания API, которого у них нет
+	for _, tc := range routeCases {
+		path, _, _ := strings.Cut(tc.path, "?")
+		if !strings.HasPrefix(path, routes.Prefix+"/") || path == v1("/openapi.json") || strings.HasPrefix(path, v1("/docs/")) ||
+			tc.code == apperror.CodeRouteNotFound || tc.code == apperror.CodeMethodNotAllowed {
+			continue
+		}
+		t.Run("legacy "+tc.name, func(t *testing.T) {
+			run(t, tc, true)
+		})
+	}
+
+	var missing []string
+	s := newTestServer(t)
+	for _, route := range s.router.Routes() {
+		if !covered[route.Method+" "+route.Path] {
+			missing = append(missing, route.Method+" "+route.Path)
+		}
+	}
+	sort.Strings(missing)
+	if len(missing) > 0 {
+		t.Errorf("routes without cases:\n%s", strings.Join(missing, "\n"))
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"service-poll/pkg/config"
+	"service-poll/pkg/health"
+
+	"google.golang.org/grpc"
+)
+
+// backgroundTasks фоновые горутины сервиса, которые останавливаются при завершении работы
+type backgroundTasks struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newBackgroundTasks() *backgroundTasks {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &backgroundTasks{ctx: ctx, cancel: cancel}
+}
+
+// Go запускает фоновую задачу; задача должна завершиться после отмены контекста
+func (b *backgroundTasks) Go(task func(ctx context.Context)) {
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		task(b.ctx)
+	}()
+}
+
+// Stop отменяет контекст фоновых задач и ждет их завершения, но не дольше ctx
+func (b *backgroundTasks) Stop(ctx context.Context) error {
+	b.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// serve запускает HTTP-сервер и сервер gRPC (если grpcServer не nil) и при отмене ctx
+// (SIGTERM/SIGINT) останавливает их: переводит /readyz в fail, ждет drain_delay,
+// перестает принимать соединения, дожидается текущих запросов и фоновых задач
+// в пределах shutdown_timeout.
+func serve(ctx context.Context, cfg config.ServerConfig, grpcCfg config.GRPCConfig, handler http.Handler, grpcServer *grpc.Server, background *backgroundTasks) error {
+	server := &http.Server{
+		Addr:         cfg.Addr,
+		Handler:      handler,
+		ReadTimeout:  cfg.ReadTimeout.Duration,
+		WriteTimeout: cfg.WriteTimeout.Duration,
+		IdleTimeout:  cfg.IdleTimeout.Duration,
+	}
+
+	// Порт gRPC занимаем заранее, чтобы ошибка адреса остановила запуск
+	var grpcListener net.Listener
+	if grpcServer != nil {
+		var err error
+		if grpcListener, err = net.Listen("tcp", grpcCfg.Addr); err != nil {
+			background.Stop(context.Background())
+			return err
+		}
+	}
+
+	serverErr := make(chan error, 2)
+	if grpcServer != nil {
+		// Сервер gRPC учитывается в проверке готовности, пока принимает вызовы
+		worker := health.RegisterWorker("grpc", 0)
+		go func() {
+			worker.Start()
+			err := grpcServer.Serve(grpcListener)
+			worker.Stop()
+			serverErr <- err
+		}()
+		slog.Info("listening grpc", slog.String("addr", grpcCfg.Addr), slog.Bool("tls", cfg.TLS.Enabled()))
+	}
+
+	go func() {
+		var err error
+		if cfg.TLS.Enabled() {
+			err = server.ListenAndServeTLS(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		} else {
+			err = server.ListenAndServe()
+		}
+		serverErr <- err
+	}()
+
+	slog.Info("listening", slog.String("addr", cfg.Addr), slog.Bool("tls", cfg.TLS.Enabled()))
+
+	select {
+	case err := <-serverErr:
+		// Сервер не смог начать работу или остановился сам
+		if grpcServer != nil {
+			grpcServer.Stop()
+		}
+		server.Close()
+		background.Stop(context.Background())
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down", slog.Duration("drain_delay", cfg.DrainDelay.Duration))
+	health.SetDraining(true)
+	time.Sleep(cfg.DrainDelay.Duration)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout.Duration)
+	defer cancel()
+
+	var shutdownErr error
+	if grpcServer != nil {
+		if err := stopGRPC(shutdownCtx, grpcServer); err != nil {
+			shutdownErr = errors.Join(shutdownErr, err)
+		}
+		if err := <-serverErr; err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			shutdownErr = errors.Join(shutdownErr, err)
+		}
+	}
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		shutdownErr = errors.Join(shutdownErr, err)
+	}
+	if err := background.Stop(shutdownCtx); err != nil {
+		shutdownErr = errors.Join(shutdownErr, err)
+	}
+	if err := <-serverErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		shutdownErr = errors.Join(shutdownErr, err)
+	}
+
+	return shutdownErr
+}
+
+// stopGRPC останавливает сервер gRPC, дожидаясь текущих вызовов, но не дольше ctx;
+// по истечении ctx оставшиеся вызовы прерываются
+func stopGRPC(ctx context.Context, server *grpc.Server) error {
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		server.Stop()
+		<-done
+		return ctx.Err()
+	}
+}
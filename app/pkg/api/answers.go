@@ -0,0 +1,64 @@
+package api
+
+import "time"
+
+// AnswerRequest запрос на регистрацию ответов пользователя на вопросы опроса
+type AnswerRequest struct {
+	Attributes AnswerAttributes `json:"attributes"`
+}
+
+// AnswerAttributes пользователь и его ответы. Вопрос и варианты указываются текстом,
+// несколько вариантов ответа на вопрос с типом multiple перечисляются через запятую.
+type AnswerAttributes struct {
+	AccessToken string         `json:"access_token" binding:"required"`
+	UserID      uint           `json:"user_id" binding:"required"`
+	Username    string         `json:"username"`
+	Email       string         `json:"email"`
+	UserData    UserData       `json:"user_data"`
+	Results     []AnswerResult `json:"results" binding:"required"`
+}
+
+// UserData дополнительные сведения о пользователе
+type UserData struct {
+	Reason string `json:"reason"`
+	State  string `json:"state"`
+}
+
+// AnswerResult ответ на один вопрос
+type AnswerResult struct {
+	Question string `json:"question" binding:"required"`
+	Answer   string `json:"answer" binding:"required"`
+}
+
+// AnswerResponse зарегистрированные ответы
+type AnswerResponse struct {
+	AccessToken string             `json:"access_token"`
+	PollID      uint               `json:"poll_id"`
+	UserID      uint               `json:"user_id"`
+	Results     []RegisteredAnswer `json:"results"`
+}
+
+// RegisteredAnswer вопрос и идентификаторы выбранных вариантов ответа
+type RegisteredAnswer struct {
+	QuestionID uint   `json:"question_id"`
+	Answers    []uint `json:"answers"`
+}
+
+// Состояния квитанции асинхронно принятого ответа
+const (
+	ReceiptPending   = "pending"   // ответ ждет сохранения в очереди
+	ReceiptPersisted = "persisted" // ответ сохранен
+	ReceiptFailed    = "failed"    // ответ не удалось сохранить, причина в error
+)
+
+// ReceiptResponse квитанция ответа, принятого асинхронно (202 Accepted).
+// Состояния pending и failed известны только экземпляру сервиса, который принял ответ.
+type ReceiptResponse struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	PollID      uint       `json:"poll_id"`
+	UserID      uint       `json:"user_id"`
+	AcceptedAt  time.Time  `json:"accepted_at"`
+	PersistedAt *time.Time `json:"persisted_at,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
@@ -0,0 +1,115 @@
+// Package api описывает тела запросов и ответов HTTP API сервиса опросов.
+// Одни и те же типы используют обработчики, описание OpenAPI и клиент сервиса,
+// поэтому изменение типа сразу отражается во всех трех местах.
+package api
+
+// MessageResponse ответ с текстовым сообщением, например об удалении записи
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// CreatePollRequest запрос на создание опроса
+type CreatePollRequest struct {
+	Title string `json:"title" binding:"required"`
+	URL   string `json:"url" binding:"required"`
+}
+
+// UpdatePollRequest запрос на изменение опроса; пустые поля не изменяются
+type UpdatePollRequest struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// PollResponse опрос с вопросами и вариантами ответов
+type PollResponse struct {
+	ID        uint           `json:"id"`
+	Title     string         `json:"title"`
+	URL       string         `json:"url"`
+	Revision  uint           `json:"revision"`
+	Questions []PollQuestion `json:"questions"`
+}
+
+// PollQuestion вопрос опроса в ответе GetPoll
+type PollQuestion struct {
+	ID              uint         `json:"id"`
+	Text            string       `json:"text"`
+	Type            string       `json:"type"`
+	PossibleAnswers []PollOption `json:"possible_answers"`
+}
+
+// PollOption вариант ответа на вопрос в ответе GetPoll
+type PollOption struct {
+	ID   uint   `json:"id"`
+	Text string `json:"text"`
+}
+
+// PollSummary краткие сведения об опросе
+type PollSummary struct {
+	ID        uint   `json:"id"`
+	Title     string `json:"title"`
+	URL       string `json:"url"`
+	Revision  uint   `json:"revision"`
+	Questions int    `json:"questions"`
+}
+
+// ListPollsRequest параметры страницы списка опросов
+type ListPollsRequest struct {
+	Limit  int `json:"limit" binding:"min=1,max=100"`
+	Offset int `json:"offset" binding:"min=0"`
+}
+
+// PollListResponse страница списка опросов и общее количество опросов
+type PollListResponse struct {
+	Polls []PollSummary `json:"polls"`
+	Total int64         `json:"total"`
+}
+
+// ImportPollsResponse опросы, созданные по описаниям
+type ImportPollsResponse struct {
+	Polls []PollSummary `json:"polls"`
+}
+
+// ClonePollRequest запрос на копирование опроса; пустой заголовок сохраняет прежний
+type ClonePollRequest struct {
+	URL   string `json:"url" binding:"required"`
+	Title string `json:"title"`
+}
+
+// PollResultsResponse результаты опроса по текущим вопросам
+type PollResultsResponse struct {
+	ID      uint         `json:"id"`
+	Results []PollResult `json:"results"`
+}
+
+// PollResult количество и доля голосов за вариант ответа
+type PollResult struct {
+	Question         string `json:"question"`
+	Answer           string `json:"answer"`
+	AnswerCnt        int    `json:"answer_cnt"`
+	AnswerPercentage string `json:"answer_percentage"`
+}
+
+// AddQuestionRequest запрос на добавление вопроса к опросу
+type AddQuestionRequest struct {
+	Text    string   `json:"text" binding:"required"`
+	Type    string   `json:"type" binding:"required,oneof=single multiple"`
+	Answers []string `json:"answers" binding:"required,min=1"`
+}
+
+// UpdateQuestionRequest запрос на изменение вопроса.
+// Options — полный упорядоченный список вариантов: элементы с id переименовываются
+// или переставляются, без id — добавляются, отсутствующие в списке — удаляются.
+// Answers — прежний формат, в котором варианты сопоставляются по тексту.
+type UpdateQuestionRequest struct {
+	Text    string         `json:"text" binding:"required"`
+	Type    string         `json:"type" binding:"required,oneof=single multiple"`
+	Options []OptionUpdate `json:"options,omitempty" binding:"omitempty,min=1,dive"`
+	Answers []string       `json:"answers,omitempty" binding:"omitempty,min=1"`
+}
+
+// OptionUpdate вариант ответа в запросе на изменение вопроса.
+// Без ID вариант добавляется; с ID и пустым текстом — сохраняет прежний текст.
+type OptionUpdate struct {
+	ID   uint   `json:"id,omitempty"`
+	Text string `json:"text,omitempty"`
+}
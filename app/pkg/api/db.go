@@ -0,0 +1,22 @@
+package api
+
+// DBStatsResponse доступность базы данных и статистика пула соединений
+type DBStatsResponse struct {
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"` // постоянное сообщение, подробности — в журнале сервиса
+	LatencyMs float64   `json:"latency_ms"`
+	Pool      PoolStats `json:"pool"`
+}
+
+// PoolStats статистика пула соединений с базой данных
+type PoolStats struct {
+	MaxOpenConnections int     `json:"max_open_connections"`
+	OpenConnections    int     `json:"open_connections"`
+	InUse              int     `json:"in_use"`
+	Idle               int     `json:"idle"`
+	WaitCount          int64   `json:"wait_count"`
+	WaitDurationMs     float64 `json:"wait_duration_ms"`
+	MaxIdleClosed      int64   `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64   `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64   `json:"max_lifetime_closed"`
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Revision снимок ревизии опроса
+type Revision struct {
+	ID        uint               `json:"id"`
+	PollID    uint               `json:"poll_id"`
+	Number    uint               `json:"number"`
+	Title     string             `json:"title"`
+	URL       string             `json:"url"`
+	CreatedAt time.Time          `json:"created_at"`
+	Questions []RevisionQuestion `json:"questions"`
+}
+
+// RevisionQuestion снимок вопроса в ревизии
+type RevisionQuestion struct {
+	QuestionID uint             `json:"question_id"`
+	Text       string           `json:"text"`
+	Type       string           `json:"type"`
+	Options    []RevisionOption `json:"options"`
+}
+
+// RevisionOption снимок варианта ответа в ревизии
+type RevisionOption struct {
+	PossibleAnswerID uint   `json:"possible_answer_id"`
+	Text             string `json:"text"`
+}
+
+// RevisionsResponse список ревизий опроса и номер актуальной ревизии
+type RevisionsResponse struct {
+	PollID    uint              `json:"poll_id"`
+	Current   uint              `json:"current"`
+	Revisions []RevisionSummary `json:"revisions"`
+}
+
+// RevisionSummary краткие сведения о ревизии
+type RevisionSummary struct {
+	ID        uint      `json:"id"`
+	Number    uint      `json:"number"`
+	Title     string    `json:"title"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RevisionResultsResponse результаты опроса по одной ревизии или объединенные по ревизиям.
+// Revision — номер ревизии или "merged".
+type RevisionResultsResponse struct {
+	ID       uint             `json:"id"`
+	Revision string           `json:"revision"`
+	Results  []RevisionResult `json:"results"`
+}
+
+// RevisionResult результат по одному варианту ответа в ревизии или в объединенных результатах
+type RevisionResult struct {
+	QuestionID       uint   `json:"question_id"`
+	Question         string `json:"question"`
+	PossibleAnswerID uint   `json:"possible_answer_id"`
+	Answer           string `json:"answer"`
+	AnswerCnt        int    `json:"answer_cnt"`
+	AnswerPercentage string `json:"answer_percentage"`
+}
+
+// AuditResponse журнал изменений опроса, новые записи первыми
+type AuditResponse struct {
+	PollID  uint         `json:"poll_id"`
+	Entries []AuditEntry `json:"entries"`
+}
+
+// TemplateAuditResponse журнал изменений шаблона опроса, новые записи первыми
+type TemplateAuditResponse struct {
+	TemplateID uint         `json:"template_id"`
+	Entries    []AuditEntry `json:"entries"`
+}
+
+// AuditEntry запись журнала изменений. Diff — JSON вида {"поле": {"before": ..., "after": ...}}.
+type AuditEntry struct {
+	ID         uint            `json:"id"`
+	QuestionID *uint           `json:"question_id"`
+	Actor      string          `json:"actor"`
+	Method     string          `json:"method"`
+	Endpoint   string          `json:"endpoint"`
+	Action     string          `json:"action"`
+	Diff       json.RawMessage `json:"diff"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
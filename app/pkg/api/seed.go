@@ -0,0 +1,26 @@
+package api
+
+// SeedRequest параметры генерации опросов с синтетическими респондентами. Одни и те же
+// параметры с тем же seed всегда создают одинаковые опросы и ответы.
+type SeedRequest struct {
+	Seed        int64 `json:"seed"`
+	Polls       int   `json:"polls" binding:"min=1,max=1000"`
+	Questions   int   `json:"questions" binding:"min=1,max=100"`
+	Options     int   `json:"options" binding:"min=2,max=20"`
+	Respondents int   `json:"respondents" binding:"min=0,max=100000"`
+	// Идентификатор первого синтетического пользователя; следующие идут подряд
+	FirstUserID   uint    `json:"first_user_id" binding:"min=1"`
+	MultipleRatio float64 `json:"multiple_ratio" binding:"min=0,max=1"`
+	ResponseRate  float64 `json:"response_rate" binding:"min=0,max=1"`
+	// Распределение выбора вариантов; для custom веса вариантов по порядку задаются в weights
+	Distribution string    `json:"distribution" binding:"oneof=uniform skewed custom"`
+	Weights      []float64 `json:"weights,omitempty"`
+	Publish      bool      `json:"publish"`
+}
+
+// SeedResponse созданные опросы и количество ответов
+type SeedResponse struct {
+	Seed    int64         `json:"seed"`
+	Polls   []PollSummary `json:"polls"`
+	Answers int           `json:"answers"`
+}
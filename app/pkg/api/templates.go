@@ -0,0 +1,44 @@
+package api
+
+import (
+	"time"
+
+	"service-poll/pkg/definition"
+)
+
+// TemplateSummary краткие сведения о шаблоне опроса
+type TemplateSummary struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Parameters  []string  `json:"parameters"`
+	Questions   int       `json:"questions"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Template шаблон опроса вместе с описанием опроса
+type Template struct {
+	TemplateSummary
+	Definition definition.Definition `json:"definition"`
+}
+
+// TemplatesResponse список шаблонов опросов
+type TemplatesResponse struct {
+	Templates []TemplateSummary `json:"templates"`
+}
+
+// CreateTemplateRequest запрос на создание шаблона: указывается ровно одно из
+// poll_id (шаблон из существующего опроса) и definition (шаблон из описания)
+type CreateTemplateRequest struct {
+	Name        string                 `json:"name" binding:"required"`
+	Description string                 `json:"description,omitempty"`
+	PollID      uint                   `json:"poll_id,omitempty"`
+	Definition  *definition.Definition `json:"definition,omitempty"`
+}
+
+// InstantiateTemplateRequest запрос на создание опроса из шаблона
+type InstantiateTemplateRequest struct {
+	URL     string            `json:"url" binding:"required"`
+	Params  map[string]string `json:"params,omitempty"`
+	Publish bool              `json:"publish,omitempty"`
+}
@@ -0,0 +1,175 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Code стабильный машиночитаемый код ошибки. Коды не меняются между версиями сервиса,
+// поэтому клиенты могут опираться на них вместо текста сообщения.
+type Code string
+
+// Коды ошибок
+const (
+	CodeInvalidRequest    Code = "invalid_request"    // тело или параметры запроса не удалось разобрать
+	CodeValidationFailed  Code = "validation_failed"  // значения полей не прошли проверку, подробности в errors
+	CodeUnsupportedFormat Code = "unsupported_format" // формат описания опроса не поддерживается
+	CodePollNotFound      Code = "poll_not_found"     // опрос не найден
+	CodeQuestionNotFound  Code = "question_not_found" // вопрос не найден в опросе
+	CodeRevisionNotFound  Code = "revision_not_found" // ревизия опроса не найдена
+	CodeTemplateNotFound  Code = "template_not_found" // шаблон не найден
+	CodeTemplateExists    Code = "template_exists"    // шаблон с таким именем уже существует
+	CodeOptionsHaveVotes  Code = "options_have_votes" // удаляемые варианты ответа уже получили голоса
+	CodeReceiptNotFound   Code = "receipt_not_found"  // квитанция асинхронно принятого ответа не найдена
+	CodeIngestUnavailable Code = "ingest_unavailable" // очередь приема ответов заполнена или остановлена, повторите позже
+	CodeRouteNotFound     Code = "route_not_found"    // маршрут не существует
+	CodeMethodNotAllowed  Code = "method_not_allowed" // метод не поддерживается маршрутом
+	CodeInternal          Code = "internal_error"     // внутренняя ошибка сервиса, причина пишется в журнал
+)
+
+// Ошибки предметной области, общие для нескольких обработчиков
+var (
+	ErrPollNotFound     = NotFound(CodePollNotFound, "Poll not found")
+	ErrQuestionNotFound = NotFound(CodeQuestionNotFound, "Question not found")
+	ErrRevisionNotFound = NotFound(CodeRevisionNotFound, "Revision not found")
+	ErrTemplateNotFound = NotFound(CodeTemplateNotFound, "Template not found")
+	ErrReceiptNotFound  = NotFound(CodeReceiptNotFound, "Receipt not found")
+
+	// ErrIngestUnavailable очередь асинхронного приема ответов заполнена или остановлена
+	ErrIngestUnavailable = New(http.StatusServiceUnavailable, CodeIngestUnavailable, "Answer queue is full or stopped, retry later")
+)
+
+// FieldError ошибка проверки отдельного поля запроса.
+// Field — путь к полю в теле запроса, например "questions[0].options[1]".
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Field создает ошибку проверки поля
+func Field(field, code, message string) FieldError {
+	return FieldError{Field: field, Code: code, Message: message}
+}
+
+// Error ошибка с HTTP-статусом и стабильным кодом, которая отдается клиенту как problem+json.
+// Err — исходная причина, она пишется в журнал, но не передается клиенту.
+type Error struct {
+	Status     int
+	Code       Code
+	Detail     string
+	Fields     []FieldError
+	Extensions map[string]interface{}
+	Err        error
+}
+
+// New создает ошибку с указанным статусом, кодом и описанием
+func New(status int, code Code, detail string) *Error {
+	return &Error{Status: status, Code: code, Detail: detail}
+}
+
+// NotFound создает ошибку 404
+func NotFound(code Code, detail string) *Error {
+	return New(http.StatusNotFound, code, detail)
+}
+
+// Conflict создает ошибку 409
+func Conflict(code Code, detail string) *Error {
+	return New(http.StatusConflict, code, detail)
+}
+
+// InvalidRequest создает ошибку 400 для запроса, который не удалось разобрать
+func InvalidRequest(detail string) *Error {
+	return New(http.StatusBadRequest, CodeInvalidRequest, detail)
+}
+
+// Validation создает ошибку 400 с перечнем полей, не прошедших проверку
+func Validation(fields ...FieldError) *Error {
+	err := New(http.StatusBadRequest, CodeValidationFailed, "Request validation failed")
+	err.Fields = fields
+	return err
+}
+
+// Internal создает ошибку 500; detail отдается клиенту, cause пишется в журнал
+func Internal(detail string, cause error) *Error {
+	err := New(http.StatusInternalServerError, CodeInternal, detail)
+	err.Err = cause
+	return err
+}
+
+// Error возвращает описание ошибки вместе с причиной
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Detail + ": " + e.Err.Error()
+	}
+	return e.Detail
+}
+
+// Unwrap возвращает исходную причину ошибки
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is сравнивает ошибки по коду, чтобы errors.Is находил ошибку предметной области
+// независимо от описания и причины
+func (e *Error) Is(target error) bool {
+	var other *Error
+	return errors.As(target, &other) && other.Code == e.Code
+}
+
+// With возвращает копию ошибки с дополнительным полем в ответе
+func (e *Error) With(key string, value interface{}) *Error {
+	copied := *e
+	copied.Extensions = make(map[string]interface{}, len(e.Extensions)+1)
+	for k, v := range e.Extensions {
+		copied.Extensions[k] = v
+	}
+	copied.Extensions[key] = value
+	return &copied
+}
+
+// Wrap возвращает копию ошибки с указанной причиной
+func (e *Error) Wrap(cause error) *Error {
+	copied := *e
+	copied.Err = cause
+	return &copied
+}
+
+// WithFieldPrefix возвращает копию ошибки, в которой пути полей начинаются с prefix,
+// например при проверке одного элемента из списка
+func (e *Error) WithFieldPrefix(prefix string) *Error {
+	copied := *e
+	copied.Fields = make([]FieldError, len(e.Fields))
+	for i, field := range e.Fields {
+		field.Field = joinField(prefix, field.Field)
+		copied.Fields[i] = field
+	}
+	return &copied
+}
+
+// joinField соединяет путь к полю с вложенным путем
+func joinField(prefix, field string) string {
+	if field == "" {
+		return prefix
+	}
+	if strings.HasPrefix(field, "[") {
+		return prefix + field
+	}
+	return prefix + "." + field
+}
+
+// As возвращает ошибку *Error из цепочки err. Любая другая ошибка считается внутренней.
+func As(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal(http.StatusText(http.StatusInternalServerError), err)
+}
+
+// Invalid создает ошибку 400 с кодом validation_failed для одного поля
+func Invalid(field, code, format string, args ...interface{}) *Error {
+	return Validation(Field(field, code, fmt.Sprintf(format, args...)))
+}
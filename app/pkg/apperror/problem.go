@@ -0,0 +1,83 @@
+package apperror
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sort"
+)
+
+// ContentType тип содержимого ответа с ошибкой (RFC 7807)
+const ContentType = "application/problem+json"
+
+// typePrefix префикс URI типа ошибки; тип однозначно определяется кодом
+const typePrefix = "urn:service-poll:error:"
+
+// Problem тело ответа с ошибкой в формате RFC 7807
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      Code         `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+
+	// Дополнительные поля ответа, например список вариантов с голосами
+	Extensions map[string]interface{} `json:"-"`
+}
+
+// Problem формирует тело ответа; instance — путь запроса, requestID — идентификатор запроса
+func (e *Error) Problem(instance, requestID string) Problem {
+	return Problem{
+		Type:       typePrefix + string(e.Code),
+		Title:      http.StatusText(e.Status),
+		Status:     e.Status,
+		Detail:     e.Detail,
+		Instance:   instance,
+		Code:       e.Code,
+		RequestID:  requestID,
+		Errors:     e.Fields,
+		Extensions: e.Extensions,
+	}
+}
+
+// MarshalJSON записывает дополнительные поля на одном уровне с основными, как требует RFC 7807
+func (p Problem) MarshalJSON() ([]byte, error) {
+	type problem Problem
+	data, err := json.Marshal(problem(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return data, err
+	}
+
+	keys := make([]string, 0, len(p.Extensions))
+	for key := range p.Extensions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// Дописываем дополнительные поля после основных; основные поля не переопределяются
+	buf := bytes.NewBuffer(data[:len(data)-1])
+	for _, key := range keys {
+		switch key {
+		case "type", "title", "status", "detail", "instance", "code", "request_id", "errors":
+			continue
+		}
+		name, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(p.Extensions[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteByte(',')
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,128 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"service-poll/pkg/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Типы изменений, которые попадают в журнал
+const (
+	ActionCreatePoll          = "create_poll"
+	ActionImportPoll          = "import_poll"          // опрос создан по описанию
+	ActionInstantiateTemplate = "instantiate_template" // опрос создан из шаблона
+	ActionClonePoll           = "clone_poll"           // опрос создан копированием другого опроса
+	ActionPublishPoll         = "publish_poll"
+	ActionUpdatePoll          = "update_poll"
+	ActionDeletePoll          = "delete_poll"
+	ActionAddQuestion         = "add_question"
+	ActionUpdateQuestion      = "update_question"
+	ActionDeleteQuestion      = "delete_question"
+	ActionCreateTemplate      = "create_template"
+	ActionDeleteTemplate      = "delete_template"
+)
+
+// ActorHeader заголовок, в котором клиент передает автора изменения
+const ActorHeader = "X-Actor"
+
+// Change значение поля до и после изменения
+type Change struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Diff сравнивает значения полей до и после изменения и возвращает только отличающиеся.
+// Поле, отсутствующее в одной из карт, считается равным nil.
+func Diff(before, after map[string]interface{}) map[string]Change {
+	diff := make(map[string]Change)
+
+	for field, beforeValue := range before {
+		afterValue := after[field]
+		if !reflect.DeepEqual(beforeValue, afterValue) {
+			diff[field] = Change{Before: beforeValue, After: afterValue}
+		}
+	}
+
+	for field, afterValue := range after {
+		if _, ok := before[field]; !ok && afterValue != nil {
+			diff[field] = Change{Before: nil, After: afterValue}
+		}
+	}
+
+	return diff
+}
+
+// Source источник изменения: автор, метод и маршрут HTTP или метод gRPC
+type Source struct {
+	Actor    string
+	Method   string
+	Endpoint string
+}
+
+// sourceKey ключ источника изменения в контексте
+type sourceKey struct{}
+
+// NewContext возвращает контекст с источником изменения
+func NewContext(ctx context.Context, source Source) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+// FromContext возвращает источник изменения из контекста
+func FromContext(ctx context.Context) Source {
+	source, _ := ctx.Value(sourceKey{}).(Source)
+	return source
+}
+
+// Context возвращает контекст запроса с источником изменения: автором, методом и маршрутом
+func Context(c *gin.Context) context.Context {
+	return NewContext(c.Request.Context(), Source{
+		Actor:    Actor(c),
+		Method:   c.Request.Method,
+		Endpoint: c.FullPath(),
+	})
+}
+
+// Actor возвращает автора изменения из заголовка X-Actor,
+// а если он не передан — IP-адрес клиента.
+func Actor(c *gin.Context) string {
+	if actor := c.GetHeader(ActorHeader); actor != "" {
+		return actor
+	}
+	return c.ClientIP()
+}
+
+// Record сохраняет запись об изменении опроса или вопроса в журнал в транзакции tx,
+// в которой выполняется само изменение: если запись не сохранится, изменение откатится.
+// Автор, метод и маршрут берутся из контекста (см. NewContext). Пустой diff не записывается.
+func Record(ctx context.Context, tx *gorm.DB, pollID uint, questionID *uint, action string, diff map[string]Change) error {
+	return record(ctx, tx, models.AuditLog{PollID: pollID, QuestionID: questionID, Action: action}, diff)
+}
+
+// RecordTemplate сохраняет запись об изменении шаблона опроса так же, как Record
+func RecordTemplate(ctx context.Context, tx *gorm.DB, templateID uint, action string, diff map[string]Change) error {
+	return record(ctx, tx, models.AuditLog{TemplateID: &templateID, Action: action}, diff)
+}
+
+// record дополняет запись журнала источником изменения и diff и сохраняет ее в транзакции tx
+func record(ctx context.Context, tx *gorm.DB, entry models.AuditLog, diff map[string]Change) error {
+	if len(diff) == 0 {
+		return nil
+	}
+
+	diffJSON, err := json.Marshal(diff)
+	if err != nil {
+		return err
+	}
+
+	source := FromContext(ctx)
+	entry.Actor = source.Actor
+	entry.Method = source.Method
+	entry.Endpoint = source.Endpoint
+	entry.Diff = string(diffJSON)
+
+	return tx.Create(&entry).Error
+}
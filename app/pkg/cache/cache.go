@@ -0,0 +1,35 @@
+// Package cache хранилища кэша с общим интерфейсом: LRU в памяти процесса и Redis.
+// Значения — готовые байты (обычно JSON), поэтому хранилища не зависят от типов сервиса.
+//
+//	c := cache.NewLRU(10000)
+//	c.Set(ctx, "poll:1", body, time.Minute)
+//	body, ok, err := c.Get(ctx, "poll:1")
+package cache
+
+import (
+	"context"
+	"time"
+
+	"service-poll/pkg/config"
+)
+
+// Cache хранилище значений по ключу с ограниченным сроком хранения
+type Cache interface {
+	// Get возвращает значение; ok равно false, если ключа нет или срок хранения истек
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	// Set сохраняет значение на время ttl
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete удаляет ключи; отсутствие ключа не является ошибкой
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// New создает хранилище по настройкам cfg; для драйвера none возвращает nil
+func New(cfg config.CacheConfig) Cache {
+	switch cfg.Driver {
+	case config.CacheMemory:
+		return NewLRU(cfg.Size)
+	case config.CacheRedis:
+		return NewRedis(cfg.Redis)
+	}
+	return nil
+}
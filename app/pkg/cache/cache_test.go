@@ -0,0 +1,309 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"service-poll/pkg/config"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(2)
+
+	c.Set(ctx, "a", []byte("1"), time.Minute)
+	c.Set(ctx, "b", []byte("2"), time.Minute)
+	// Чтение a делает давно использованной запись b, и она вытесняется
+	if value, ok, _ := c.Get(ctx, "a"); !ok || string(value) != "1" {
+		t.Fatalf("Get(a) = %q, %v", value, ok)
+	}
+	c.Set(ctx, "c", []byte("3"), time.Minute)
+	if _, ok, _ := c.Get(ctx, "b"); ok {
+		t.Errorf("b is not evicted")
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+
+	c.Set(ctx, "a", []byte("updated"), time.Minute)
+	if value, _, _ := c.Get(ctx, "a"); string(value) != "updated" {
+		t.Errorf("Get(a) = %q, want updated", value)
+	}
+
+	c.Delete(ctx, "a", "missing")
+	if _, ok, _ := c.Get(ctx, "a"); ok {
+		t.Errorf("a is not deleted")
+	}
+
+	c.Set(ctx, "expired", []byte("x"), -time.Second)
+	if _, ok, _ := c.Get(ctx, "expired"); ok {
+		t.Errorf("expired entry is returned")
+	}
+}
+
+func TestRedis(t *testing.T) {
+	ctx := context.Background()
+	stub := newRedisStub(t, "secret")
+
+	cfg := config.Default().Cache.Redis
+	cfg.Addr = stub.addr
+	cfg.Password = "secret"
+	cfg.DB = 2
+	cfg.KeyPrefix = "test:"
+	c := NewRedis(cfg)
+	defer c.Close()
+
+	if _, ok, err := c.Get(ctx, "poll:1"); ok || err != nil {
+		t.Fatalf("Get(missing) = %v, %v", ok, err)
+	}
+	value := []byte("{\"id\":1}\r\n")
+	if err := c.Set(ctx, "poll:1", value, time.Minute); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if got, ok, err := c.Get(ctx, "poll:1"); !ok || err != nil || string(got) != string(value) {
+		t.Fatalf("Get = %q, %v, %v", got, ok, err)
+	}
+	if ttl := stub.ttl("test:poll:1"); ttl != time.Minute {
+		t.Errorf("ttl = %v, want 1m", ttl)
+	}
+
+	if err := c.Delete(ctx, "poll:1", "results:1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, ok, _ := c.Get(ctx, "poll:1"); ok {
+		t.Errorf("poll:1 is not deleted")
+	}
+
+	// Ошибка сервера возвращается как RedisError, соединение остается в пуле
+	stub.fail("ERR out of memory")
+	var redisErr RedisError
+	if err := c.Set(ctx, "poll:2", value, time.Minute); !errors.As(err, &redisErr) {
+		t.Errorf("Set error = %v, want RedisError", err)
+	}
+
+	// Разорванное соединение заменяется новым
+	stub.dropConnections()
+	if err := c.Set(ctx, "poll:2", value, time.Minute); err == nil {
+		t.Errorf("Set on a dropped connection succeeded")
+	}
+	if err := c.Set(ctx, "poll:2", value, time.Minute); err != nil {
+		t.Errorf("Set after reconnect: %v", err)
+	}
+	if got := stub.selected(); got != 2 {
+		t.Errorf("selected database = %d, want 2", got)
+	}
+}
+
+func TestRedisUnavailable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	cfg := config.Default().Cache.Redis
+	cfg.Addr = addr
+	c := NewRedis(cfg)
+	ctx := context.Background()
+	if _, _, err := c.Get(ctx, "poll:1"); err == nil || errors.Is(err, ErrUnavailable) {
+		t.Fatalf("Get without a server: err = %v, want a dial error", err)
+	}
+
+	// До истечения паузы команды не пытаются подключиться
+	if _, _, err := c.Get(ctx, "poll:1"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Get during backoff: err = %v, want ErrUnavailable", err)
+	}
+	if err := c.Delete(ctx, "poll:1"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Delete during backoff: err = %v, want ErrUnavailable", err)
+	}
+
+	// Повторная неудача удваивает паузу
+	c.retryAt = time.Now()
+	if _, _, err := c.Get(ctx, "poll:1"); err == nil || errors.Is(err, ErrUnavailable) {
+		t.Fatalf("Get after backoff: err = %v, want a dial error", err)
+	}
+	if c.backoff != 2*redisMinBackoff {
+		t.Errorf("backoff = %v, want %v", c.backoff, 2*redisMinBackoff)
+	}
+
+	// Сервер снова доступен: после паузы подключение удается, пауза сбрасывается
+	stub := newRedisStub(t, "")
+	c.cfg.Addr = stub.addr
+	c.retryAt = time.Now()
+	if err := c.Set(ctx, "poll:1", []byte("{}"), time.Minute); err != nil {
+		t.Fatalf("Set after the server is back: %v", err)
+	}
+	if !c.retryAt.IsZero() || c.backoff != 0 {
+		t.Errorf("backoff = %v, retry at %v, want reset", c.backoff, c.retryAt)
+	}
+}
+
+// redisStub сервер, который понимает команды AUTH, SELECT, GET, SET с PX и DEL
+type redisStub struct {
+	addr     string
+	password string
+
+	mu       sync.Mutex
+	values   map[string]string
+	ttls     map[string]time.Duration
+	database int
+	failNext string
+	conns    []net.Conn
+}
+
+func newRedisStub(t *testing.T, password string) *redisStub {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stub := &redisStub{
+		addr:     listener.Addr().String(),
+		password: password,
+		values:   make(map[string]string),
+		ttls:     make(map[string]time.Duration),
+	}
+	t.Cleanup(func() {
+		listener.Close()
+		stub.dropConnections()
+	})
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			stub.mu.Lock()
+			stub.conns = append(stub.conns, conn)
+			stub.mu.Unlock()
+			go stub.serve(conn)
+		}
+	}()
+	return stub
+}
+
+func (s *redisStub) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authenticated := s.password == ""
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		var reply string
+		switch command := strings.ToUpper(args[0]); {
+		case s.failNext != "":
+			reply = "-" + s.failNext + "\r\n"
+			s.failNext = ""
+		case command == "AUTH":
+			authenticated = args[1] == s.password
+			reply = "+OK\r\n"
+			if !authenticated {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		case !authenticated:
+			reply = "-NOAUTH Authentication required.\r\n"
+		case command == "SELECT":
+			s.database, _ = strconv.Atoi(args[1])
+			reply = "+OK\r\n"
+		case command == "GET":
+			value, ok := s.values[args[1]]
+			reply = "$-1\r\n"
+			if ok {
+				reply = fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+			}
+		case command == "SET":
+			s.values[args[1]] = args[2]
+			if len(args) == 5 && strings.ToUpper(args[3]) == "PX" {
+				ms, _ := strconv.Atoi(args[4])
+				s.ttls[args[1]] = time.Duration(ms) * time.Millisecond
+			}
+			reply = "+OK\r\n"
+		case command == "DEL":
+			deleted := 0
+			for _, key := range args[1:] {
+				if _, ok := s.values[key]; ok {
+					delete(s.values, key)
+					deleted++
+				}
+			}
+			reply = fmt.Sprintf(":%d\r\n", deleted)
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		s.mu.Unlock()
+
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// readCommand читает команду — массив строк RESP
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil || count < 1 {
+		return nil, fmt.Errorf("malformed command %q", line)
+	}
+	args := make([]string, count)
+	for i := range args {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "$")))
+		if err != nil {
+			return nil, fmt.Errorf("malformed argument %q", header)
+		}
+		value := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, value); err != nil {
+			return nil, err
+		}
+		args[i] = string(value[:size])
+	}
+	return args, nil
+}
+
+func (s *redisStub) ttl(key string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ttls[key]
+}
+
+func (s *redisStub) selected() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.database
+}
+
+func (s *redisStub) fail(message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failNext = message
+}
+
+func (s *redisStub) dropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+}
@@ -0,0 +1,280 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"service-poll/pkg/config"
+)
+
+// Redis кэш в Redis, общий для всех экземпляров сервиса. Клиент использует только
+// команды GET, SET с PX и DEL (а также AUTH и SELECT при подключении) протокола RESP,
+// поэтому вместо сервера Redis подходит любой совместимый сервер или локальный стаб.
+//
+// Если подключиться к серверу не удалось, новые подключения не открываются в течение
+// паузы, которая удваивается с каждой неудачей от redisMinBackoff до redisMaxBackoff:
+// команды сразу возвращают ErrUnavailable, и сервис работает без кэша, не ожидая
+// тайм-аута подключения на каждом запросе. По истечении паузы подключиться пробует
+// один запрос, остальные до его результата также получают ErrUnavailable.
+type Redis struct {
+	cfg  config.RedisConfig
+	pool chan *redisConn // свободные соединения
+
+	mu      sync.Mutex
+	backoff time.Duration // текущая пауза, 0 — последнее подключение удалось
+	retryAt time.Time     // до этого момента подключения не открываются
+}
+
+// Границы паузы между попытками подключиться к недоступному серверу Redis
+const (
+	redisMinBackoff = time.Second
+	redisMaxBackoff = 30 * time.Second
+)
+
+// ErrUnavailable сервер Redis недоступен, команда не отправлялась
+var ErrUnavailable = errors.New("redis: server unavailable, waiting before reconnecting")
+
+// redisConn соединение с сервером Redis
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+}
+
+// RedisError ошибка, которую вернул сервер Redis; соединение после нее остается рабочим
+type RedisError string
+
+func (e RedisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// NewRedis создает кэш в Redis. Соединения открываются при первых командах,
+// свободными остаются не больше cfg.PoolSize соединений.
+func NewRedis(cfg config.RedisConfig) *Redis {
+	return &Redis{cfg: cfg, pool: make(chan *redisConn, cfg.PoolSize)}
+}
+
+// Get возвращает значение ключа
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := r.do(ctx, "GET", r.cfg.KeyPrefix+key)
+	if err != nil {
+		return nil, false, err
+	}
+	if reply == nil {
+		return nil, false, nil
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("redis: unexpected GET reply %T", reply)
+	}
+	return value, true, nil
+}
+
+// Set сохраняет значение ключа на время ttl, округленное до миллисекунд
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	milliseconds := ttl.Milliseconds()
+	if milliseconds < 1 {
+		milliseconds = 1
+	}
+	_, err := r.do(ctx, "SET", r.cfg.KeyPrefix+key, string(value), "PX", strconv.FormatInt(milliseconds, 10))
+	return err
+}
+
+// Delete удаляет ключи одной командой
+func (r *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	args := make([]string, 0, len(keys)+1)
+	args = append(args, "DEL")
+	for _, key := range keys {
+		args = append(args, r.cfg.KeyPrefix+key)
+	}
+	_, err := r.do(ctx, args...)
+	return err
+}
+
+// Close закрывает свободные соединения
+func (r *Redis) Close() error {
+	for {
+		select {
+		case conn := <-r.pool:
+			conn.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// do выполняет команду на свободном соединении. Соединение, на котором произошла
+// сетевая ошибка или ошибка протокола, закрывается.
+func (r *Redis) do(ctx context.Context, args ...string) (interface{}, error) {
+	conn, err := r.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.command(r.deadline(ctx), args...)
+	var redisErr RedisError
+	if err != nil && !errors.As(err, &redisErr) {
+		conn.conn.Close()
+		return nil, err
+	}
+
+	select {
+	case r.pool <- conn:
+	default:
+		conn.conn.Close()
+	}
+	return reply, err
+}
+
+// conn возвращает свободное соединение или открывает новое
+func (r *Redis) conn(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-r.pool:
+		return conn, nil
+	default:
+	}
+
+	if !r.allowDial() {
+		return nil, ErrUnavailable
+	}
+	dialer := net.Dialer{Timeout: r.cfg.Timeout.Duration}
+	netConn, err := dialer.DialContext(ctx, "tcp", r.cfg.Addr)
+	r.dialed(err)
+	if err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	conn := &redisConn{conn: netConn, reader: bufio.NewReader(netConn), writer: bufio.NewWriter(netConn)}
+
+	deadline := r.deadline(ctx)
+	if r.cfg.Password != "" {
+		if _, err := conn.command(deadline, "AUTH", r.cfg.Password); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+	if r.cfg.DB != 0 {
+		if _, err := conn.command(deadline, "SELECT", strconv.Itoa(r.cfg.DB)); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// allowDial сообщает, можно ли открыть соединение. После паузы разрешает одну попытку
+// и откладывает следующие на ту же паузу, пока не станет известен ее результат.
+func (r *Redis) allowDial() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.retryAt.IsZero() {
+		return true
+	}
+	now := time.Now()
+	if now.Before(r.retryAt) {
+		return false
+	}
+	r.retryAt = now.Add(r.backoff)
+	return true
+}
+
+// dialed учитывает результат подключения: ошибка удваивает паузу, успех ее сбрасывает
+func (r *Redis) dialed(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err == nil {
+		if r.backoff > 0 {
+			slog.Info("redis is available again", slog.String("addr", r.cfg.Addr))
+		}
+		r.backoff = 0
+		r.retryAt = time.Time{}
+		return
+	}
+
+	r.backoff = min(max(2*r.backoff, redisMinBackoff), redisMaxBackoff)
+	r.retryAt = time.Now().Add(r.backoff)
+	slog.Warn("redis is unavailable, cache is bypassed",
+		slog.String("addr", r.cfg.Addr),
+		slog.Duration("retry_in", r.backoff),
+		slog.String("error", err.Error()))
+}
+
+// deadline срок выполнения команды: Timeout или срок ctx, если он раньше
+func (r *Redis) deadline(ctx context.Context) time.Time {
+	var deadline time.Time
+	if r.cfg.Timeout.Duration > 0 {
+		deadline = time.Now().Add(r.cfg.Timeout.Duration)
+	}
+	if ctxDeadline, ok := ctx.Deadline(); ok && (deadline.IsZero() || ctxDeadline.Before(deadline)) {
+		deadline = ctxDeadline
+	}
+	return deadline
+}
+
+// command отправляет команду массивом строк RESP и читает ответ
+func (c *redisConn) command(deadline time.Time, args ...string) (interface{}, error) {
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(c.writer, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(c.writer, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if err := c.writer.Flush(); err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	return c.reply()
+}
+
+// reply читает ответ: простую строку, ошибку, число или строку с длиной (nil, если ее нет)
+func (c *redisConn) reply() (interface{}, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	payload := line[1 : len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, RedisError(payload)
+	case ':':
+		n, err := strconv.ParseInt(payload, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed integer reply %q", payload)
+		}
+		return n, nil
+	case '$':
+		size, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed bulk reply %q", payload)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		value := make([]byte, size+2)
+		if _, err := io.ReadFull(c.reader, value); err != nil {
+			return nil, fmt.Errorf("redis: %w", err)
+		}
+		return value[:size], nil
+	default:
+		return nil, fmt.Errorf("redis: unsupported reply %q", line)
+	}
+}
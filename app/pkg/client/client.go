@@ -0,0 +1,314 @@
+// Package client клиент HTTP API сервиса опросов (версия 1) для других сервисов на Go.
+// Тела запросов и ответов — те же типы pkg/api, что использует сервис, ошибки сервиса
+// возвращаются как *Error с кодом из pkg/apperror.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"service-poll/pkg/apperror"
+	"service-poll/pkg/audit"
+	"service-poll/pkg/logger"
+)
+
+// APIPrefix префикс маршрутов API версии 1
+const APIPrefix = "/api/v1"
+
+// userAgent заголовок User-Agent запросов клиента
+const userAgent = "service-poll-client"
+
+// RetryPolicy правила повтора запросов. Повторяются ответы 429, 502, 503, 504 и сетевые ошибки
+// идемпотентных запросов (GET, PUT, DELETE). Запросы POST и PATCH повторяются, только если
+// соединение не удалось установить, чтобы ответы и изменения не записались дважды.
+type RetryPolicy struct {
+	MaxAttempts int           // количество попыток, включая первую; 1 — без повторов
+	MinBackoff  time.Duration // пауза перед первым повтором
+	MaxBackoff  time.Duration // предельная пауза между попытками
+}
+
+// DefaultRetryPolicy правила повтора по умолчанию
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	MinBackoff:  100 * time.Millisecond,
+	MaxBackoff:  2 * time.Second,
+}
+
+// Client клиент API сервиса опросов. Безопасен для использования из нескольких горутин.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	retry      RetryPolicy
+	actor      string
+}
+
+// Option настройка клиента
+type Option func(*Client)
+
+// WithHTTPClient задает HTTP-клиент, например с таймаутом или собственным транспортом
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetry задает правила повтора запросов
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// WithActor задает автора изменений, который записывается в журнал аудита (заголовок X-Actor)
+func WithActor(actor string) Option {
+	return func(c *Client) {
+		c.actor = actor
+	}
+}
+
+// New создает клиент сервиса по адресу вида "http://service-poll:5000"
+func New(baseURL string, opts ...Option) (*Client, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("client: invalid base URL: %w", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" || parsed.Host == "" {
+		return nil, fmt.Errorf("client: base URL must be an absolute http(s) URL, got %q", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/") + APIPrefix,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		retry:      DefaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.retry.MaxAttempts < 1 {
+		c.retry.MaxAttempts = 1
+	}
+
+	return c, nil
+}
+
+// Error ошибка, которую вернул сервис, в формате application/problem+json.
+// errors.Is сравнивает ее с ошибками pkg/apperror по коду, например
+// errors.Is(err, apperror.ErrPollNotFound).
+type Error struct {
+	StatusCode int
+	Problem    apperror.Problem
+}
+
+// Error возвращает описание ошибки
+func (e *Error) Error() string {
+	detail := e.Problem.Detail
+	if detail == "" {
+		detail = http.StatusText(e.StatusCode)
+	}
+	if e.Problem.Code == "" {
+		return fmt.Sprintf("service-poll: %d %s", e.StatusCode, detail)
+	}
+	return fmt.Sprintf("service-poll: %d %s: %s", e.StatusCode, e.Problem.Code, detail)
+}
+
+// Is сравнивает ошибку с ошибкой pkg/apperror по коду
+func (e *Error) Is(target error) bool {
+	var appErr *apperror.Error
+	return errors.As(target, &appErr) && appErr.Code == e.Problem.Code
+}
+
+// Code возвращает стабильный код ошибки сервиса
+func (e *Error) Code() apperror.Code {
+	return e.Problem.Code
+}
+
+// request параметры запроса к API
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	body        []byte
+	contentType string
+	accept      string
+}
+
+// newRequest формирует запрос; тело in кодируется в JSON
+func newRequest(method, path string, in interface{}) (request, error) {
+	req := request{method: method, path: path, accept: "application/json"}
+	if in != nil {
+		body, err := json.Marshal(in)
+		if err != nil {
+			return request{}, fmt.Errorf("client: encode request: %w", err)
+		}
+		req.body = body
+		req.contentType = "application/json"
+	}
+	return req, nil
+}
+
+// call выполняет запрос и разбирает ответ JSON в out (если out не nil)
+func (c *Client) call(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	req, err := newRequest(method, path, in)
+	if err != nil {
+		return err
+	}
+	req.query = query
+
+	body, err := c.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("client: decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}
+
+// do выполняет запрос с повторами и возвращает тело успешного ответа
+func (c *Client) do(ctx context.Context, req request) ([]byte, error) {
+	target := c.baseURL + req.path
+	if len(req.query) > 0 {
+		target += "?" + req.query.Encode()
+	}
+
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		body, retryAfter, err := c.attempt(ctx, req, target)
+		if err == nil {
+			return body, nil
+		}
+		lastErr = err
+
+		if attempt >= c.retry.MaxAttempts || !c.retryable(req.method, err) {
+			return nil, lastErr
+		}
+
+		// Ждем перед повтором; отмена контекста прерывает ожидание
+		timer := time.NewTimer(c.backoff(attempt, retryAfter))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt выполняет одну попытку запроса. retryAfter — пауза из заголовка Retry-After.
+func (c *Client) attempt(ctx context.Context, req request, target string) ([]byte, time.Duration, error) {
+	var body io.Reader
+	if req.body != nil {
+		body = bytes.NewReader(req.body)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, target, body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("client: %w", err)
+	}
+	if req.contentType != "" {
+		httpReq.Header.Set("Content-Type", req.contentType)
+	}
+	httpReq.Header.Set("Accept", req.accept+", "+apperror.ContentType)
+	httpReq.Header.Set("User-Agent", userAgent)
+	if c.actor != "" {
+		httpReq.Header.Set(audit.ActorHeader, c.actor)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+		return data, 0, nil
+	}
+
+	apiErr := &Error{StatusCode: resp.StatusCode}
+	if err := json.Unmarshal(data, &apiErr.Problem); err != nil || apiErr.Problem.Status == 0 {
+		// Ответ не в формате problem+json, например от балансировщика
+		apiErr.Problem = apperror.Problem{Status: resp.StatusCode, Title: http.StatusText(resp.StatusCode)}
+	}
+	if apiErr.Problem.RequestID == "" {
+		apiErr.Problem.RequestID = resp.Header.Get(logger.RequestIDHeader)
+	}
+
+	return nil, retryAfter(resp.Header.Get("Retry-After")), apiErr
+}
+
+// retryable определяет, можно ли повторить запрос после ошибки
+func (c *Client) retryable(method string, err error) bool {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			// 429 и 503 означают, что запрос не обрабатывался
+			return idempotent(method) || apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode == http.StatusServiceUnavailable
+		}
+		return false
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	// Если соединение не установлено, запрос точно не дошел до сервиса
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	return idempotent(method)
+}
+
+// backoff возвращает паузу перед повтором: экспоненциальную со случайным разбросом
+// или указанную сервисом в Retry-After, если она больше
+func (c *Client) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	delay := c.retry.MinBackoff << (attempt - 1)
+	if delay <= 0 || (c.retry.MaxBackoff > 0 && delay > c.retry.MaxBackoff) {
+		delay = c.retry.MaxBackoff
+	}
+	if delay > 0 {
+		delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+	}
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	return delay
+}
+
+// idempotent сообщает, что повтор запроса с этим методом не меняет результат
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// retryAfter разбирает заголовок Retry-After в секундах
+func retryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,405 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"service-poll/pkg/api"
+	"service-poll/pkg/apperror"
+	"service-poll/pkg/db"
+	"service-poll/pkg/definition"
+	"service-poll/pkg/handlers"
+	"service-poll/pkg/logger"
+	"service-poll/pkg/routes"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// testRetry правила повтора без долгих пауз
+var testRetry = RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+
+// newServiceClient запускает сервис поверх пустой базы SQLite во временном каталоге
+// и возвращает клиент, подключенный к нему
+func newServiceClient(t *testing.T) *Client {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "poll.db") + "?_pragma=busy_timeout(5000)&_txlock=immediate"
+	database, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: gormlogger.Discard, TranslateError: true})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	sqlDB, err := database.DB()
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	db.DB = database
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(logger.RequestID(), handlers.ValidateIDs())
+	if err := routes.Register(router, routes.Options{}); err != nil {
+		t.Fatalf("register routes: %v", err)
+	}
+	router.NoRoute(handlers.NoRoute)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	c, err := New(server.URL, WithRetry(testRetry), WithActor("client-test"))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	return c
+}
+
+// newStubClient запускает сервер с обработчиком handler и возвращает клиент, подключенный к нему
+func newStubClient(t *testing.T, handler http.HandlerFunc, opts ...Option) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	c, err := New(server.URL, append([]Option{WithRetry(testRetry)}, opts...)...)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	return c
+}
+
+func TestPrefixMatchesRoutes(t *testing.T) {
+	if APIPrefix != routes.Prefix {
+		t.Fatalf("APIPrefix = %q, want %q", APIPrefix, routes.Prefix)
+	}
+}
+
+func TestNewRejectsInvalidURL(t *testing.T) {
+	for _, baseURL := range []string{"", "service-poll:5000", "ftp://service-poll", "http://"} {
+		if _, err := New(baseURL); err == nil {
+			t.Errorf("New(%q) succeeded, want error", baseURL)
+		}
+	}
+}
+
+// TestClientAgainstService проходит по операциям API через клиент на настоящих обработчиках
+func TestClientAgainstService(t *testing.T) {
+	c := newServiceClient(t)
+	ctx := context.Background()
+
+	if err := c.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	stats, err := c.DBStats(ctx)
+	if err != nil || stats.Status != "ok" {
+		t.Fatalf("DBStats = %+v, %v", stats, err)
+	}
+
+	poll, err := c.CreatePoll(ctx, api.CreatePollRequest{Title: "Client", URL: "client"})
+	if err != nil {
+		t.Fatalf("CreatePoll: %v", err)
+	}
+	if _, err := c.UpdatePoll(ctx, poll.ID, api.UpdatePollRequest{Title: "Client poll"}); err != nil {
+		t.Fatalf("UpdatePoll: %v", err)
+	}
+
+	question, err := c.AddQuestion(ctx, poll.ID, api.AddQuestionRequest{Text: "Color", Type: "single", Answers: []string{"Red", "Green"}})
+	if err != nil {
+		t.Fatalf("AddQuestion: %v", err)
+	}
+	got, err := c.GetPoll(ctx, poll.ID)
+	if err != nil {
+		t.Fatalf("GetPoll: %v", err)
+	}
+	if got.Title != "Client poll" || len(got.Questions) != 1 || len(got.Questions[0].PossibleAnswers) != 2 {
+		t.Fatalf("GetPoll = %+v", got)
+	}
+	options := got.Questions[0].PossibleAnswers
+
+	answer, err := c.RegisterAnswer(ctx, poll.ID, api.AnswerAttributes{
+		AccessToken: "token",
+		UserID:      1,
+		Results:     []api.AnswerResult{{Question: "Color", Answer: "Red"}},
+	})
+	if err != nil {
+		t.Fatalf("RegisterAnswer: %v", err)
+	}
+	if len(answer.Results) != 1 || answer.Results[0].QuestionID != question.ID {
+		t.Fatalf("RegisterAnswer = %+v", answer)
+	}
+
+	results, err := c.GetPollResults(ctx, poll.ID)
+	if err != nil {
+		t.Fatalf("GetPollResults: %v", err)
+	}
+	if len(results.Results) != 1 || results.Results[0].Answer != "Red" || results.Results[0].AnswerCnt != 1 {
+		t.Fatalf("GetPollResults = %+v", results)
+	}
+
+	revision, err := c.PublishPoll(ctx, poll.ID)
+	if err != nil {
+		t.Fatalf("PublishPoll: %v", err)
+	}
+	if _, err := c.GetPollRevision(ctx, poll.ID, int(revision.Number)); err != nil {
+		t.Fatalf("GetPollRevision: %v", err)
+	}
+	revisions, err := c.GetPollRevisions(ctx, poll.ID)
+	if err != nil || len(revisions.Revisions) != 1 {
+		t.Fatalf("GetPollRevisions = %+v, %v", revisions, err)
+	}
+	if _, err := c.GetRevisionResults(ctx, poll.ID, int(revision.Number)); err != nil {
+		t.Fatalf("GetRevisionResults: %v", err)
+	}
+	merged, err := c.GetMergedResults(ctx, poll.ID)
+	if err != nil || merged.Revision != "merged" {
+		t.Fatalf("GetMergedResults = %+v, %v", merged, err)
+	}
+	if _, err := c.GetPollRevision(ctx, poll.ID, 9); !errors.Is(err, apperror.ErrRevisionNotFound) {
+		t.Fatalf("GetPollRevision(9) error = %v, want %s", err, apperror.CodeRevisionNotFound)
+	}
+
+	// Удаление варианта с голосом требует force
+	green := options[1].ID
+	update := api.UpdateQuestionRequest{Text: "Color", Type: "single", Options: []api.OptionUpdate{{ID: green}}}
+	var apiErr *Error
+	if _, err := c.UpdateQuestion(ctx, poll.ID, question.ID, update, false); !errors.As(err, &apiErr) || apiErr.Code() != apperror.CodeOptionsHaveVotes {
+		t.Fatalf("UpdateQuestion without force error = %v, want %s", err, apperror.CodeOptionsHaveVotes)
+	}
+	if apiErr.StatusCode != http.StatusConflict || apiErr.Problem.RequestID == "" {
+		t.Fatalf("UpdateQuestion problem = %+v", apiErr)
+	}
+	if _, err := c.UpdateQuestion(ctx, poll.ID, question.ID, update, true); err != nil {
+		t.Fatalf("UpdateQuestion with force: %v", err)
+	}
+
+	audit, err := c.GetPollAudit(ctx, poll.ID, question.ID)
+	if err != nil || len(audit.Entries) == 0 {
+		t.Fatalf("GetPollAudit = %+v, %v", audit, err)
+	}
+	for _, entry := range audit.Entries {
+		if entry.Actor != "client-test" {
+			t.Fatalf("audit actor = %q, want client-test", entry.Actor)
+		}
+	}
+
+	def, err := c.GetPollDefinition(ctx, poll.ID)
+	if err != nil || def.URL != "client" {
+		t.Fatalf("GetPollDefinition = %+v, %v", def, err)
+	}
+	def.URL = "client-imported"
+	imported, err := c.ImportPolls(ctx, []definition.Definition{*def})
+	if err != nil || len(imported.Polls) != 1 {
+		t.Fatalf("ImportPolls = %+v, %v", imported, err)
+	}
+	clone, err := c.ClonePoll(ctx, poll.ID, api.ClonePollRequest{URL: "client-copy"})
+	if err != nil || clone.Questions != 1 {
+		t.Fatalf("ClonePoll = %+v, %v", clone, err)
+	}
+
+	template, err := c.CreateTemplate(ctx, api.CreateTemplateRequest{Name: "client", PollID: poll.ID})
+	if err != nil {
+		t.Fatalf("CreateTemplate: %v", err)
+	}
+	templates, err := c.ListTemplates(ctx)
+	if err != nil || len(templates.Templates) != 1 {
+		t.Fatalf("ListTemplates = %+v, %v", templates, err)
+	}
+	if _, err := c.GetTemplate(ctx, template.ID); err != nil {
+		t.Fatalf("GetTemplate: %v", err)
+	}
+	if _, err := c.InstantiateTemplate(ctx, template.ID, api.InstantiateTemplateRequest{URL: "from-template"}); err != nil {
+		t.Fatalf("InstantiateTemplate: %v", err)
+	}
+	if err := c.DeleteTemplate(ctx, template.ID); err != nil {
+		t.Fatalf("DeleteTemplate: %v", err)
+	}
+	if _, err := c.GetTemplate(ctx, template.ID); !errors.Is(err, apperror.ErrTemplateNotFound) {
+		t.Fatalf("GetTemplate after delete error = %v, want %s", err, apperror.CodeTemplateNotFound)
+	}
+	if templateAudit, err := c.GetTemplateAudit(ctx, template.ID); err != nil || len(templateAudit.Entries) != 2 {
+		t.Fatalf("GetTemplateAudit = %+v, %v", templateAudit, err)
+	}
+
+	if err := c.DeleteQuestion(ctx, poll.ID, question.ID); err != nil {
+		t.Fatalf("DeleteQuestion: %v", err)
+	}
+	if err := c.DeleteQuestion(ctx, poll.ID, question.ID); !errors.Is(err, apperror.ErrQuestionNotFound) {
+		t.Fatalf("DeleteQuestion twice error = %v, want %s", err, apperror.CodeQuestionNotFound)
+	}
+	if err := c.DeletePoll(ctx, poll.ID); err != nil {
+		t.Fatalf("DeletePoll: %v", err)
+	}
+	if _, err := c.GetPoll(ctx, poll.ID); !errors.Is(err, apperror.ErrPollNotFound) {
+		t.Fatalf("GetPoll after delete error = %v, want %s", err, apperror.CodePollNotFound)
+	}
+}
+
+func TestValidationProblem(t *testing.T) {
+	c := newServiceClient(t)
+	ctx := context.Background()
+	if err := c.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	_, err := c.CreatePoll(ctx, api.CreatePollRequest{URL: "no-title"})
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("CreatePoll error = %v, want *Error", err)
+	}
+	if apiErr.StatusCode != http.StatusBadRequest || apiErr.Code() != apperror.CodeValidationFailed || len(apiErr.Problem.Errors) == 0 {
+		t.Fatalf("CreatePoll problem = %+v", apiErr.Problem)
+	}
+}
+
+func TestRetriesIdempotentRequests(t *testing.T) {
+	var attempts int32
+	c := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":1,"title":"Poll","url":"poll","revision":0,"questions":[]}`))
+	})
+
+	poll, err := c.GetPoll(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("GetPoll: %v", err)
+	}
+	if poll.ID != 1 || atomic.LoadInt32(&attempts) != 3 {
+		t.Fatalf("GetPoll = %+v after %d attempts", poll, attempts)
+	}
+}
+
+func TestDoesNotRetryAnswersAfterGatewayError(t *testing.T) {
+	var attempts int32
+	c := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	_, err := c.RegisterAnswer(context.Background(), 1, api.AnswerAttributes{AccessToken: "t", UserID: 1})
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("RegisterAnswer error = %v, want 502", err)
+	}
+	if attempts != 1 {
+		t.Fatalf("attempts = %d, want 1", attempts)
+	}
+}
+
+func TestRetriesAnswersWhenServiceUnavailable(t *testing.T) {
+	var attempts int32
+	c := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"t","poll_id":1,"user_id":1,"results":[]}`))
+	})
+
+	if _, err := c.RegisterAnswer(context.Background(), 1, api.AnswerAttributes{AccessToken: "t", UserID: 1}); err != nil {
+		t.Fatalf("RegisterAnswer: %v", err)
+	}
+	if attempts != 2 {
+		t.Fatalf("attempts = %d, want 2", attempts)
+	}
+}
+
+func TestRequestWrapsAnswerAttributes(t *testing.T) {
+	c := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/poll/7/answer" {
+			t.Errorf("request = %s %s", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("X-Actor") != "billing" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("headers = %v", r.Header)
+		}
+		var body map[string]map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		if body["attributes"]["user_id"] != float64(3) {
+			t.Errorf("body = %v, want user_id under attributes", body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"t","poll_id":7,"user_id":3,"results":[]}`))
+	}, WithActor("billing"))
+
+	answer, err := c.RegisterAnswer(context.Background(), 7, api.AnswerAttributes{AccessToken: "t", UserID: 3})
+	if err != nil || answer.PollID != 7 {
+		t.Fatalf("RegisterAnswer = %+v, %v", answer, err)
+	}
+}
+
+func TestNonProblemErrorResponse(t *testing.T) {
+	c := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-ID", "req-1")
+		http.Error(w, "upstream is down", http.StatusBadGateway)
+	}, WithRetry(RetryPolicy{MaxAttempts: 1}))
+
+	_, err := c.GetPoll(context.Background(), 1)
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("GetPoll error = %v, want *Error", err)
+	}
+	if apiErr.Problem.Status != http.StatusBadGateway || apiErr.Problem.RequestID != "req-1" || apiErr.Code() != "" {
+		t.Fatalf("problem = %+v", apiErr.Problem)
+	}
+	if errors.Is(err, apperror.ErrPollNotFound) {
+		t.Fatal("502 must not match ErrPollNotFound")
+	}
+}
+
+func TestContextCancelStopsRetries(t *testing.T) {
+	var attempts int32
+	c := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.GetPoll(ctx, 1)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetPoll error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("GetPoll waited %s after the context expired", elapsed)
+	}
+	if attempts != 1 {
+		t.Fatalf("attempts = %d, want 1", attempts)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	c := &Client{retry: RetryPolicy{MaxAttempts: 5, MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}}
+
+	for attempt, max := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 5: time.Second} {
+		if delay := c.backoff(attempt, 0); delay < max/2 || delay > max {
+			t.Errorf("backoff(%d) = %s, want between %s and %s", attempt, delay, max/2, max)
+		}
+	}
+	if delay := c.backoff(1, 3*time.Second); delay != 3*time.Second {
+		t.Errorf("backoff with Retry-After = %s, want 3s", delay)
+	}
+	if delay := retryAfter(" 2 "); delay != 2*time.Second {
+		t.Errorf("retryAfter = %s, want 2s", delay)
+	}
+	if delay := retryAfter("Wed, 21 Oct 2015 07:28:00 GMT"); delay != 0 {
+		t.Errorf("retryAfter(date) = %s, want 0", delay)
+	}
+}
@@ -0,0 +1,218 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"service-poll/pkg/api"
+	"service-poll/pkg/definition"
+	"service-poll/pkg/models"
+)
+
+// CreatePoll создает опрос
+func (c *Client) CreatePoll(ctx context.Context, req api.CreatePollRequest) (*models.Poll, error) {
+	var poll models.Poll
+	if err := c.call(ctx, http.MethodPost, "/poll/create", nil, req, &poll); err != nil {
+		return nil, err
+	}
+	return &poll, nil
+}
+
+// ListPolls получает страницу списка опросов
+func (c *Client) ListPolls(ctx context.Context, req api.ListPollsRequest) (*api.PollListResponse, error) {
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(req.Limit))
+	query.Set("offset", strconv.Itoa(req.Offset))
+
+	var polls api.PollListResponse
+	if err := c.call(ctx, http.MethodGet, "/polls", query, nil, &polls); err != nil {
+		return nil, err
+	}
+	return &polls, nil
+}
+
+// GetPoll получает опрос с вопросами и вариантами ответов
+func (c *Client) GetPoll(ctx context.Context, pollID uint) (*api.PollResponse, error) {
+	var poll api.PollResponse
+	if err := c.call(ctx, http.MethodGet, pollPath(pollID), nil, nil, &poll); err != nil {
+		return nil, err
+	}
+	return &poll, nil
+}
+
+// UpdatePoll изменяет название и (или) URL опроса
+func (c *Client) UpdatePoll(ctx context.Context, pollID uint, req api.UpdatePollRequest) (*models.Poll, error) {
+	var poll models.Poll
+	if err := c.call(ctx, http.MethodPatch, pollPath(pollID), nil, req, &poll); err != nil {
+		return nil, err
+	}
+	return &poll, nil
+}
+
+// DeletePoll удаляет опрос
+func (c *Client) DeletePoll(ctx context.Context, pollID uint) error {
+	return c.call(ctx, http.MethodDelete, pollPath(pollID), nil, nil, nil)
+}
+
+// GetPollResults получает результаты опроса по текущим вопросам
+func (c *Client) GetPollResults(ctx context.Context, pollID uint) (*api.PollResultsResponse, error) {
+	var results api.PollResultsResponse
+	if err := c.call(ctx, http.MethodGet, pollPath(pollID)+"/results", nil, nil, &results); err != nil {
+		return nil, err
+	}
+	return &results, nil
+}
+
+// GetRevisionResults получает результаты опроса по опубликованной ревизии
+func (c *Client) GetRevisionResults(ctx context.Context, pollID uint, revision int) (*api.RevisionResultsResponse, error) {
+	return c.revisionResults(ctx, pollID, strconv.Itoa(revision))
+}
+
+// GetMergedResults получает результаты опроса, объединенные по всем ревизиям
+func (c *Client) GetMergedResults(ctx context.Context, pollID uint) (*api.RevisionResultsResponse, error) {
+	return c.revisionResults(ctx, pollID, "merged")
+}
+
+// revisionResults получает результаты опроса с параметром ?revision
+func (c *Client) revisionResults(ctx context.Context, pollID uint, revision string) (*api.RevisionResultsResponse, error) {
+	var results api.RevisionResultsResponse
+	query := url.Values{"revision": {revision}}
+	if err := c.call(ctx, http.MethodGet, pollPath(pollID)+"/results", query, nil, &results); err != nil {
+		return nil, err
+	}
+	return &results, nil
+}
+
+// PublishPoll публикует текущее состояние опроса как новую ревизию
+func (c *Client) PublishPoll(ctx context.Context, pollID uint) (*api.Revision, error) {
+	var revision api.Revision
+	if err := c.call(ctx, http.MethodPost, pollPath(pollID)+"/publish", nil, nil, &revision); err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// GetPollRevisions получает список ревизий опроса
+func (c *Client) GetPollRevisions(ctx context.Context, pollID uint) (*api.RevisionsResponse, error) {
+	var revisions api.RevisionsResponse
+	if err := c.call(ctx, http.MethodGet, pollPath(pollID)+"/revisions", nil, nil, &revisions); err != nil {
+		return nil, err
+	}
+	return &revisions, nil
+}
+
+// GetPollRevision получает опубликованную ревизию опроса
+func (c *Client) GetPollRevision(ctx context.Context, pollID uint, revision int) (*api.Revision, error) {
+	var result api.Revision
+	path := fmt.Sprintf("%s/revisions/%d", pollPath(pollID), revision)
+	if err := c.call(ctx, http.MethodGet, path, nil, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ClonePoll создает копию опроса с новым URL
+func (c *Client) ClonePoll(ctx context.Context, pollID uint, req api.ClonePollRequest) (*api.PollSummary, error) {
+	var poll api.PollSummary
+	if err := c.call(ctx, http.MethodPost, pollPath(pollID)+"/clone", nil, req, &poll); err != nil {
+		return nil, err
+	}
+	return &poll, nil
+}
+
+// ImportPolls создает опросы по описаниям в одной транзакции
+func (c *Client) ImportPolls(ctx context.Context, definitions []definition.Definition) (*api.ImportPollsResponse, error) {
+	var polls api.ImportPollsResponse
+	query := url.Values{"format": {definition.FormatJSON}}
+	if err := c.call(ctx, http.MethodPost, "/polls/import", query, definitions, &polls); err != nil {
+		return nil, err
+	}
+	return &polls, nil
+}
+
+// GetPollDefinition получает описание опроса
+func (c *Client) GetPollDefinition(ctx context.Context, pollID uint) (*definition.Definition, error) {
+	var def definition.Definition
+	query := url.Values{"format": {definition.FormatJSON}}
+	if err := c.call(ctx, http.MethodGet, pollPath(pollID)+"/definition", query, nil, &def); err != nil {
+		return nil, err
+	}
+	return &def, nil
+}
+
+// GetPollAudit получает журнал изменений опроса; questionID больше нуля оставляет только записи вопроса
+func (c *Client) GetPollAudit(ctx context.Context, pollID, questionID uint) (*api.AuditResponse, error) {
+	var query url.Values
+	if questionID > 0 {
+		query = url.Values{"question_id": {strconv.FormatUint(uint64(questionID), 10)}}
+	}
+
+	var audit api.AuditResponse
+	if err := c.call(ctx, http.MethodGet, pollPath(pollID)+"/audit", query, nil, &audit); err != nil {
+		return nil, err
+	}
+	return &audit, nil
+}
+
+// AddQuestion добавляет вопрос с вариантами ответов в опрос
+func (c *Client) AddQuestion(ctx context.Context, pollID uint, req api.AddQuestionRequest) (*models.Question, error) {
+	var question models.Question
+	if err := c.call(ctx, http.MethodPost, pollPath(pollID)+"/question", nil, req, &question); err != nil {
+		return nil, err
+	}
+	return &question, nil
+}
+
+// UpdateQuestion изменяет вопрос и его варианты ответов.
+// force разрешает удалить варианты, за которые уже голосовали.
+func (c *Client) UpdateQuestion(ctx context.Context, pollID, questionID uint, req api.UpdateQuestionRequest, force bool) (*models.Question, error) {
+	var query url.Values
+	if force {
+		query = url.Values{"force": {"true"}}
+	}
+
+	var question models.Question
+	if err := c.call(ctx, http.MethodPatch, questionPath(pollID, questionID), query, req, &question); err != nil {
+		return nil, err
+	}
+	return &question, nil
+}
+
+// DeleteQuestion удаляет вопрос из опроса
+func (c *Client) DeleteQuestion(ctx context.Context, pollID, questionID uint) error {
+	return c.call(ctx, http.MethodDelete, questionPath(pollID, questionID), nil, nil, nil)
+}
+
+// RegisterAnswer регистрирует ответы пользователя на вопросы опроса.
+// Обертка attributes тела запроса добавляется клиентом.
+func (c *Client) RegisterAnswer(ctx context.Context, pollID uint, attributes api.AnswerAttributes) (*api.AnswerResponse, error) {
+	var answer api.AnswerResponse
+	req := api.AnswerRequest{Attributes: attributes}
+	if err := c.call(ctx, http.MethodPost, pollPath(pollID)+"/answer", nil, req, &answer); err != nil {
+		return nil, err
+	}
+	return &answer, nil
+}
+
+// GetReceipt возвращает состояние квитанции ответа, который сервис принял асинхронно
+// (202 Accepted) с квитанцией receiptID
+func (c *Client) GetReceipt(ctx context.Context, receiptID string) (*api.ReceiptResponse, error) {
+	var receipt api.ReceiptResponse
+	if err := c.call(ctx, http.MethodGet, "/receipts/"+url.PathEscape(receiptID), nil, nil, &receipt); err != nil {
+		return nil, err
+	}
+	return &receipt, nil
+}
+
+// pollPath возвращает путь опроса
+func pollPath(pollID uint) string {
+	return "/poll/" + strconv.FormatUint(uint64(pollID), 10)
+}
+
+// questionPath возвращает путь вопроса опроса
+func questionPath(pollID, questionID uint) string {
+	return fmt.Sprintf("%s/question/%d", pollPath(pollID), questionID)
+}
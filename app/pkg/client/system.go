@@ -0,0 +1,32 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"service-poll/pkg/api"
+)
+
+// Migrate создает таблицы базы данных сервиса и начальные данные
+func (c *Client) Migrate(ctx context.Context) error {
+	return c.call(ctx, http.MethodPost, "/migrate", nil, nil, nil)
+}
+
+// DBStats получает состояние подключения к базе данных и пула соединений
+func (c *Client) DBStats(ctx context.Context) (*api.DBStatsResponse, error) {
+	var stats api.DBStatsResponse
+	if err := c.call(ctx, http.MethodGet, "/db/stats", nil, nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// Seed создает опросы с синтетическими респондентами по параметрам генератора.
+// Сервис регистрирует POST /seed только с настройкой server.seed_endpoint.
+func (c *Client) Seed(ctx context.Context, req api.SeedRequest) (*api.SeedResponse, error) {
+	var seeded api.SeedResponse
+	if err := c.call(ctx, http.MethodPost, "/seed", nil, req, &seeded); err != nil {
+		return nil, err
+	}
+	return &seeded, nil
+}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"service-poll/pkg/api"
+)
+
+// ListTemplates получает список шаблонов опросов
+func (c *Client) ListTemplates(ctx context.Context) (*api.TemplatesResponse, error) {
+	var templates api.TemplatesResponse
+	if err := c.call(ctx, http.MethodGet, "/templates", nil, nil, &templates); err != nil {
+		return nil, err
+	}
+	return &templates, nil
+}
+
+// CreateTemplate создает шаблон по описанию или по существующему опросу
+func (c *Client) CreateTemplate(ctx context.Context, req api.CreateTemplateRequest) (*api.Template, error) {
+	var template api.Template
+	if err := c.call(ctx, http.MethodPost, "/templates", nil, req, &template); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// GetTemplate получает шаблон с описанием опроса
+func (c *Client) GetTemplate(ctx context.Context, templateID uint) (*api.Template, error) {
+	var template api.Template
+	if err := c.call(ctx, http.MethodGet, templatePath(templateID), nil, nil, &template); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// DeleteTemplate удаляет шаблон
+func (c *Client) DeleteTemplate(ctx context.Context, templateID uint) error {
+	return c.call(ctx, http.MethodDelete, templatePath(templateID), nil, nil, nil)
+}
+
+// InstantiateTemplate создает опрос по шаблону с подстановкой параметров
+func (c *Client) InstantiateTemplate(ctx context.Context, templateID uint, req api.InstantiateTemplateRequest) (*api.PollSummary, error) {
+	var poll api.PollSummary
+	if err := c.call(ctx, http.MethodPost, templatePath(templateID)+"/instantiate", nil, req, &poll); err != nil {
+		return nil, err
+	}
+	return &poll, nil
+}
+
+// GetTemplateAudit получает журнал изменений шаблона, в том числе удаленного
+func (c *Client) GetTemplateAudit(ctx context.Context, templateID uint) (*api.TemplateAuditResponse, error) {
+	var audit api.TemplateAuditResponse
+	if err := c.call(ctx, http.MethodGet, templatePath(templateID)+"/audit", nil, nil, &audit); err != nil {
+		return nil, err
+	}
+	return &audit, nil
+}
+
+// templatePath возвращает путь шаблона
+func templatePath(templateID uint) string {
+	return "/templates/" + strconv.FormatUint(uint64(templateID), 10)
+}
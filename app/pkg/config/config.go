@@ -0,0 +1,576 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Config настройки сервиса
+type Config struct {
+	Server   ServerConfig   `yaml:"server" toml:"server"`
+	GRPC     GRPCConfig     `yaml:"grpc" toml:"grpc"`
+	Database DatabaseConfig `yaml:"database" toml:"database"`
+	Log      LogConfig      `yaml:"log" toml:"log"`
+	Tracing  TracingConfig  `yaml:"tracing" toml:"tracing"`
+	Ingest   IngestConfig   `yaml:"ingest" toml:"ingest"`
+	Cache    CacheConfig    `yaml:"cache" toml:"cache"`
+	Counters CountersConfig `yaml:"counters" toml:"counters"`
+}
+
+// ServerConfig настройки HTTP-сервера
+type ServerConfig struct {
+	Addr         string    `yaml:"addr" toml:"addr"`
+	ReadTimeout  Duration  `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout Duration  `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout  Duration  `yaml:"idle_timeout" toml:"idle_timeout"`
+	TLS          TLSConfig `yaml:"tls" toml:"tls"`
+	// Остановка: сколько ждать после перевода /readyz в состояние fail, прежде чем
+	// перестать принимать соединения, и сколько ждать завершения текущих запросов
+	DrainDelay      Duration `yaml:"drain_delay" toml:"drain_delay"`
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	// Маршрут POST /seed создает опросы с синтетическими ответами и не требует авторизации,
+	// поэтому регистрируется только явно — для стендов и нагрузочного тестирования
+	SeedEndpoint bool `yaml:"seed_endpoint" toml:"seed_endpoint"`
+}
+
+// GRPCConfig настройки сервера gRPC. TLS и таймаут остановки общие с HTTP-сервером.
+type GRPCConfig struct {
+	Addr string `yaml:"addr" toml:"addr"` // пустой адрес — сервер gRPC не запускается
+	// Период, с которым поток результатов перечитывает их, чтобы заметить ответы,
+	// принятые другими экземплярами сервиса
+	StreamInterval Duration `yaml:"stream_interval" toml:"stream_interval"`
+}
+
+// TLSConfig настройки TLS. TLS включен, если указаны сертификат и ключ.
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file" toml:"cert_file"`
+	KeyFile  string `yaml:"key_file" toml:"key_file"`
+}
+
+// Enabled сообщает, нужно ли запускать сервер с TLS
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != "" && t.KeyFile != ""
+}
+
+// Драйверы базы данных
+const (
+	DriverPostgres = "postgres" // сервер PostgreSQL
+	DriverSQLite   = "sqlite"   // файл SQLite для локальной разработки и установки на одном узле
+	DriverMemory   = "memory"   // база в памяти процесса для тестов, пустая при каждом запуске
+)
+
+// DatabaseConfig настройки подключения к базе данных. Host, Port, User, Password, Name,
+// Timezone и SSLMode относятся только к PostgreSQL, Path — только к SQLite.
+type DatabaseConfig struct {
+	Driver          string   `yaml:"driver" toml:"driver"`
+	Path            string   `yaml:"path" toml:"path"`
+	Host            string   `yaml:"host" toml:"host"`
+	Port            int      `yaml:"port" toml:"port"`
+	User            string   `yaml:"user" toml:"user"`
+	Password        string   `yaml:"password" toml:"password"`
+	Name            string   `yaml:"name" toml:"name"`
+	Timezone        string   `yaml:"timezone" toml:"timezone"`
+	SSLMode         string   `yaml:"sslmode" toml:"sslmode"`
+	ConnectTimeout  Duration `yaml:"connect_timeout" toml:"connect_timeout"`
+	MaxOpenConns    int      `yaml:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns    int      `yaml:"max_idle_conns" toml:"max_idle_conns"`
+	ConnMaxLifetime Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
+	ConnMaxIdleTime Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time"`
+	// Повторные попытки подключения при запуске, пока база данных недоступна
+	ConnectRetryTimeout Duration `yaml:"connect_retry_timeout" toml:"connect_retry_timeout"`
+	RetryInitialBackoff Duration `yaml:"retry_initial_backoff" toml:"retry_initial_backoff"`
+	RetryMaxBackoff     Duration `yaml:"retry_max_backoff" toml:"retry_max_backoff"`
+}
+
+// LogConfig настройки журналирования
+type LogConfig struct {
+	Level string `yaml:"level" toml:"level"` // debug, info, warn или error
+	// Запросы к базе данных дольше порога пишутся в журнал как медленные (0 — не отслеживать)
+	SlowQueryThreshold Duration `yaml:"slow_query_threshold" toml:"slow_query_threshold"`
+}
+
+// TracingConfig настройки трассировки OpenTelemetry
+type TracingConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+	// Адрес коллектора OTLP/HTTP в виде host:port
+	Endpoint    string  `yaml:"endpoint" toml:"endpoint"`
+	Insecure    bool    `yaml:"insecure" toml:"insecure"` // без TLS, например для локального коллектора
+	ServiceName string  `yaml:"service_name" toml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio"` // доля трассируемых запросов от 0 до 1
+}
+
+// IngestConfig настройки асинхронного приема ответов. Если прием включен,
+// POST /poll/:id/answer проверяет ответ по структуре опроса из кэша, отвечает 202
+// с квитанцией и ставит ответ в очередь, из которой фоновый обработчик сохраняет
+// ответы пачками.
+type IngestConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+	// Размер очереди: когда она заполнена, новые ответы отклоняются с кодом 503
+	QueueSize int `yaml:"queue_size" toml:"queue_size"`
+	// Наибольшее количество ответов пользователей в одной транзакции и наибольшая пауза
+	// перед сохранением неполной пачки
+	BatchSize     int      `yaml:"batch_size" toml:"batch_size"`
+	FlushInterval Duration `yaml:"flush_interval" toml:"flush_interval"`
+	// Сколько хранится в кэше структура опроса; изменения в этом экземпляре сервиса
+	// сбрасывают кэш сразу, в других — видны по истечении срока
+	CacheTTL Duration `yaml:"cache_ttl" toml:"cache_ttl"`
+	// Сколько хранятся в памяти квитанции ответов, которые не удалось сохранить
+	FailedReceiptTTL Duration `yaml:"failed_receipt_ttl" toml:"failed_receipt_ttl"`
+}
+
+// Хранилища кэша опросов и результатов
+const (
+	CacheNone   = "none"   // кэш выключен
+	CacheMemory = "memory" // LRU в памяти процесса
+	CacheRedis  = "redis"  // Redis, общий для всех экземпляров сервиса
+)
+
+// CacheConfig настройки кэша описаний опросов и результатов. Изменения через сервис
+// сбрасывают кэш сразу; в памяти процесса — только у того экземпляра, который их выполнил,
+// поэтому при нескольких экземплярах другие увидят изменения по истечении TTL.
+// По умолчанию кэш выключен, memory и redis включаются явно.
+type CacheConfig struct {
+	Driver string   `yaml:"driver" toml:"driver"` // none, memory или redis
+	TTL    Duration `yaml:"ttl" toml:"ttl"`
+	// Наибольшее количество записей в памяти процесса
+	Size  int         `yaml:"size" toml:"size"`
+	Redis RedisConfig `yaml:"redis" toml:"redis"`
+}
+
+// RedisConfig настройки подключения к Redis
+type RedisConfig struct {
+	Addr      string   `yaml:"addr" toml:"addr"` // host:port
+	Password  string   `yaml:"password" toml:"password"`
+	DB        int      `yaml:"db" toml:"db"`
+	KeyPrefix string   `yaml:"key_prefix" toml:"key_prefix"`
+	Timeout   Duration `yaml:"timeout" toml:"timeout"` // подключение и выполнение одной команды
+	PoolSize  int      `yaml:"pool_size" toml:"pool_size"`
+}
+
+// CountersConfig настройки счетчиков результатов. Счетчики обновляются вместе с ответами,
+// а фоновая сверка пересчитывает их по ответам, исправляет расхождения и учитывает
+// их в метрике result_counter_drift_total.
+type CountersConfig struct {
+	// Интервал сверки; 0 — сверка выключена
+	ReconcileInterval Duration `yaml:"reconcile_interval" toml:"reconcile_interval"`
+}
+
+// Duration длительность, которая в файле настроек и переменных окружения
+// записывается строкой вида "5s" или "1m30s"
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalText разбирает длительность из строки
+func (d *Duration) UnmarshalText(text []byte) error {
+	duration, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	d.Duration = duration
+	return nil
+}
+
+// MarshalText записывает длительность строкой
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.Duration.String()), nil
+}
+
+// Default возвращает настройки по умолчанию
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Addr:         ":5000",
+			ReadTimeout:  Duration{15 * time.Second},
+			WriteTimeout: Duration{30 * time.Second},
+			IdleTimeout:  Duration{60 * time.Second},
+
+			DrainDelay:      Duration{5 * time.Second},
+			ShutdownTimeout: Duration{30 * time.Second},
+		},
+		GRPC: GRPCConfig{
+			Addr:           ":5001",
+			StreamInterval: Duration{5 * time.Second},
+		},
+		Database: DatabaseConfig{
+			Driver:          DriverPostgres,
+			Path:            "service-poll.db",
+			Host:            "localhost",
+			Port:            5432,
+			Timezone:        "UTC",
+			SSLMode:         "disable",
+			ConnectTimeout:  Duration{5 * time.Second},
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: Duration{30 * time.Minute},
+			ConnMaxIdleTime: Duration{5 * time.Minute},
+
+			ConnectRetryTimeout: Duration{60 * time.Second},
+			RetryInitialBackoff: Duration{500 * time.Millisecond},
+			RetryMaxBackoff:     Duration{10 * time.Second},
+		},
+		Log: LogConfig{
+			Level:              "info",
+			SlowQueryThreshold: Duration{200 * time.Millisecond},
+		},
+		Tracing: TracingConfig{
+			Endpoint:    "localhost:4318",
+			Insecure:    true,
+			ServiceName: "service-poll",
+			SampleRatio: 1,
+		},
+		Ingest: IngestConfig{
+			QueueSize:        10000,
+			BatchSize:        500,
+			FlushInterval:    Duration{100 * time.Millisecond},
+			CacheTTL:         Duration{30 * time.Second},
+			FailedReceiptTTL: Duration{time.Hour},
+		},
+		Cache: CacheConfig{
+			Driver: CacheNone,
+			TTL:    Duration{time.Minute},
+			Size:   10000,
+			Redis: RedisConfig{
+				Addr:      "localhost:6379",
+				KeyPrefix: "service-poll:",
+				Timeout:   Duration{500 * time.Millisecond},
+				PoolSize:  10,
+			},
+		},
+		Counters: CountersConfig{
+			ReconcileInterval: Duration{15 * time.Minute},
+		},
+	}
+}
+
+// setting описание одной настройки: флаг командной строки, переменная окружения
+// и функция, записывающая строковое значение в настройки
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	set   func(c *Config, value string) error
+}
+
+// settings список настроек, которые можно задать переменными окружения и флагами
+var settings = []setting{
+	{"addr", "HTTP_ADDR", "address to listen on", stringSetting(func(c *Config) *string { return &c.Server.Addr })},
+	{"read-timeout", "HTTP_READ_TIMEOUT", "HTTP read timeout", durationSetting(func(c *Config) *Duration { return &c.Server.ReadTimeout })},
+	{"write-timeout", "HTTP_WRITE_TIMEOUT", "HTTP write timeout", durationSetting(func(c *Config) *Duration { return &c.Server.WriteTimeout })},
+	{"idle-timeout", "HTTP_IDLE_TIMEOUT", "HTTP keep-alive idle timeout", durationSetting(func(c *Config) *Duration { return &c.Server.IdleTimeout })},
+	{"drain-delay", "HTTP_DRAIN_DELAY", "delay between failing readiness and closing listeners on shutdown", durationSetting(func(c *Config) *Duration { return &c.Server.DrainDelay })},
+	{"shutdown-timeout", "HTTP_SHUTDOWN_TIMEOUT", "time to wait for in-flight requests on shutdown", durationSetting(func(c *Config) *Duration { return &c.Server.ShutdownTimeout })},
+	{"seed-endpoint", "HTTP_SEED_ENDPOINT", "serve POST /seed, which generates synthetic polls and answers without authorization", boolSetting(func(c *Config) *bool { return &c.Server.SeedEndpoint })},
+	{"tls-cert", "TLS_CERT_FILE", "TLS certificate file", stringSetting(func(c *Config) *string { return &c.Server.TLS.CertFile })},
+	{"tls-key", "TLS_KEY_FILE", "TLS private key file", stringSetting(func(c *Config) *string { return &c.Server.TLS.KeyFile })},
+	{"grpc-addr", "GRPC_ADDR", "gRPC address to listen on (empty — gRPC disabled)", stringSetting(func(c *Config) *string { return &c.GRPC.Addr })},
+	{"grpc-stream-interval", "GRPC_STREAM_INTERVAL", "how often result streams re-read results", durationSetting(func(c *Config) *Duration { return &c.GRPC.StreamInterval })},
+	{"db-driver", "DB_DRIVER", "database driver: postgres, sqlite or memory", stringSetting(func(c *Config) *string { return &c.Database.Driver })},
+	{"db-path", "DB_PATH", "SQLite database file", stringSetting(func(c *Config) *string { return &c.Database.Path })},
+	{"db-host", "DB_HOST", "database host", stringSetting(func(c *Config) *string { return &c.Database.Host })},
+	{"db-port", "DB_PORT", "database port", intSetting(func(c *Config) *int { return &c.Database.Port })},
+	{"db-user", "DB_USER", "database user", stringSetting(func(c *Config) *string { return &c.Database.User })},
+	{"db-password", "DB_PASSWORD", "database password", stringSetting(func(c *Config) *string { return &c.Database.Password })},
+	{"db-name", "DB_NAME", "database name", stringSetting(func(c *Config) *string { return &c.Database.Name })},
+	{"db-timezone", "DB_TIMEZONE", "database session time zone", stringSetting(func(c *Config) *string { return &c.Database.Timezone })},
+	{"db-sslmode", "DB_SSLMODE", "database SSL mode", stringSetting(func(c *Config) *string { return &c.Database.SSLMode })},
+	{"db-connect-timeout", "DB_CONNECT_TIMEOUT", "database connect timeout", durationSetting(func(c *Config) *Duration { return &c.Database.ConnectTimeout })},
+	{"db-max-open-conns", "DB_MAX_OPEN_CONNS", "maximum open database connections (0 — unlimited)", intSetting(func(c *Config) *int { return &c.Database.MaxOpenConns })},
+	{"db-max-idle-conns", "DB_MAX_IDLE_CONNS", "maximum idle database connections", intSetting(func(c *Config) *int { return &c.Database.MaxIdleConns })},
+	{"db-conn-max-lifetime", "DB_CONN_MAX_LIFETIME", "maximum database connection lifetime", durationSetting(func(c *Config) *Duration { return &c.Database.ConnMaxLifetime })},
+	{"db-conn-max-idle-time", "DB_CONN_MAX_IDLE_TIME", "maximum database connection idle time", durationSetting(func(c *Config) *Duration { return &c.Database.ConnMaxIdleTime })},
+	{"db-connect-retry-timeout", "DB_CONNECT_RETRY_TIMEOUT", "how long to retry connecting to the database at startup (0 — single attempt)", durationSetting(func(c *Config) *Duration { return &c.Database.ConnectRetryTimeout })},
+	{"db-retry-initial-backoff", "DB_RETRY_INITIAL_BACKOFF", "initial delay between database connection attempts", durationSetting(func(c *Config) *Duration { return &c.Database.RetryInitialBackoff })},
+	{"db-retry-max-backoff", "DB_RETRY_MAX_BACKOFF", "maximum delay between database connection attempts", durationSetting(func(c *Config) *Duration { return &c.Database.RetryMaxBackoff })},
+	{"log-level", "LOG_LEVEL", "log level: debug, info, warn or error", stringSetting(func(c *Config) *string { return &c.Log.Level })},
+	{"log-slow-query-threshold", "LOG_SLOW_QUERY_THRESHOLD", "log database queries slower than this as slow (0 — disabled)", durationSetting(func(c *Config) *Duration { return &c.Log.SlowQueryThreshold })},
+	{"tracing-enabled", "TRACING_ENABLED", "export OpenTelemetry traces", boolSetting(func(c *Config) *bool { return &c.Tracing.Enabled })},
+	{"tracing-endpoint", "TRACING_ENDPOINT", "OTLP/HTTP collector address (host:port)", stringSetting(func(c *Config) *string { return &c.Tracing.Endpoint })},
+	{"tracing-insecure", "TRACING_INSECURE", "connect to the collector without TLS", boolSetting(func(c *Config) *bool { return &c.Tracing.Insecure })},
+	{"tracing-service-name", "TRACING_SERVICE_NAME", "service name reported in traces", stringSetting(func(c *Config) *string { return &c.Tracing.ServiceName })},
+	{"tracing-sample-ratio", "TRACING_SAMPLE_RATIO", "fraction of requests to trace, from 0 to 1", floatSetting(func(c *Config) *float64 { return &c.Tracing.SampleRatio })},
+	{"ingest-enabled", "INGEST_ENABLED", "accept answers asynchronously with 202 and a receipt", boolSetting(func(c *Config) *bool { return &c.Ingest.Enabled })},
+	{"ingest-queue-size", "INGEST_QUEUE_SIZE", "answers waiting to be saved before new ones are rejected", intSetting(func(c *Config) *int { return &c.Ingest.QueueSize })},
+	{"ingest-batch-size", "INGEST_BATCH_SIZE", "maximum answers saved in one transaction", intSetting(func(c *Config) *int { return &c.Ingest.BatchSize })},
+	{"ingest-flush-interval", "INGEST_FLUSH_INTERVAL", "maximum delay before saving an incomplete batch", durationSetting(func(c *Config) *Duration { return &c.Ingest.FlushInterval })},
+	{"ingest-cache-ttl", "INGEST_CACHE_TTL", "how long poll structure is cached for validation", durationSetting(func(c *Config) *Duration { return &c.Ingest.CacheTTL })},
+	{"ingest-failed-receipt-ttl", "INGEST_FAILED_RECEIPT_TTL", "how long receipts of answers that failed to save are kept", durationSetting(func(c *Config) *Duration { return &c.Ingest.FailedReceiptTTL })},
+	{"cache-driver", "CACHE_DRIVER", "poll and results cache: none, memory or redis", stringSetting(func(c *Config) *string { return &c.Cache.Driver })},
+	{"cache-ttl", "CACHE_TTL", "how long cached polls and results are kept", durationSetting(func(c *Config) *Duration { return &c.Cache.TTL })},
+	{"cache-size", "CACHE_SIZE", "maximum entries of the in-memory cache", intSetting(func(c *Config) *int { return &c.Cache.Size })},
+	{"redis-addr", "REDIS_ADDR", "Redis address (host:port)", stringSetting(func(c *Config) *string { return &c.Cache.Redis.Addr })},
+	{"redis-password", "REDIS_PASSWORD", "Redis password", stringSetting(func(c *Config) *string { return &c.Cache.Redis.Password })},
+	{"redis-db", "REDIS_DB", "Redis database number", intSetting(func(c *Config) *int { return &c.Cache.Redis.DB })},
+	{"redis-key-prefix", "REDIS_KEY_PREFIX", "prefix of cache keys in Redis", stringSetting(func(c *Config) *string { return &c.Cache.Redis.KeyPrefix })},
+	{"redis-timeout", "REDIS_TIMEOUT", "Redis connect and command timeout", durationSetting(func(c *Config) *Duration { return &c.Cache.Redis.Timeout })},
+	{"redis-pool-size", "REDIS_POOL_SIZE", "maximum idle Redis connections", intSetting(func(c *Config) *int { return &c.Cache.Redis.PoolSize })},
+	{"counters-reconcile-interval", "COUNTERS_RECONCILE_INTERVAL", "how often result counters are recounted from answers (0 disables)", durationSetting(func(c *Config) *Duration { return &c.Counters.ReconcileInterval })},
+}
+
+func stringSetting(field func(c *Config) *string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+func intSetting(field func(c *Config) *int) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		*field(c) = number
+		return nil
+	}
+}
+
+func boolSetting(field func(c *Config) *bool) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		flag, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		*field(c) = flag
+		return nil
+	}
+}
+
+func floatSetting(field func(c *Config) *float64) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		*field(c) = number
+		return nil
+	}
+}
+
+func durationSetting(field func(c *Config) *Duration) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		return field(c).UnmarshalText([]byte(value))
+	}
+}
+
+// ConfigFileEnv переменная окружения с путем к файлу настроек
+const ConfigFileEnv = "CONFIG_FILE"
+
+// Load загружает настройки с приоритетом (от меньшего к большему): значения по умолчанию,
+// файл настроек (YAML или TOML), переменные окружения, флаги командной строки.
+// Путь к файлу задается флагом -config или переменной окружения CONFIG_FILE.
+func Load(args []string) (Config, error) {
+	flags := flag.NewFlagSet("service-poll", flag.ContinueOnError)
+	configFile := flags.String("config", os.Getenv(ConfigFileEnv), "path to a YAML or TOML config file")
+
+	// Значения флагов запоминаются и применяются последними
+	flagValues := make(map[string]string)
+	for _, s := range settings {
+		name := s.flag
+		flags.Func(name, fmt.Sprintf("%s (env %s)", s.usage, s.env), func(value string) error {
+			flagValues[name] = value
+			return nil
+		})
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	cfg := Default()
+
+	if *configFile != "" {
+		if err := loadFile(&cfg, *configFile); err != nil {
+			return Config{}, err
+		}
+	}
+
+	for _, s := range settings {
+		if value, ok := os.LookupEnv(s.env); ok {
+			if err := s.set(&cfg, value); err != nil {
+				return Config{}, fmt.Errorf("env %s: %w", s.env, err)
+			}
+		}
+	}
+
+	for _, s := range settings {
+		if value, ok := flagValues[s.flag]; ok {
+			if err := s.set(&cfg, value); err != nil {
+				return Config{}, fmt.Errorf("flag -%s: %w", s.flag, err)
+			}
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+// loadFile читает настройки из файла, формат определяется по расширению
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, cfg)
+	case ".toml":
+		err = toml.Unmarshal(data, cfg)
+	default:
+		return fmt.Errorf("config file %s: unsupported format, use .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Допустимые значения sslmode для PostgreSQL
+var sslModes = map[string]bool{
+	"disable": true, "allow": true, "prefer": true, "require": true, "verify-ca": true, "verify-full": true,
+}
+
+// Допустимые уровни журналирования
+var logLevels = map[string]bool{
+	"debug": true, "info": true, "warn": true, "error": true,
+}
+
+// Validate проверяет настройки при запуске сервиса
+func (c Config) Validate() error {
+	var problems []string
+
+	if c.Server.Addr == "" {
+		problems = append(problems, "server.addr is required")
+	}
+	if (c.Server.TLS.CertFile == "") != (c.Server.TLS.KeyFile == "") {
+		problems = append(problems, "server.tls.cert_file and server.tls.key_file must be set together")
+	}
+	for _, timeout := range []struct {
+		name  string
+		value Duration
+	}{
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.drain_delay", c.Server.DrainDelay},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout},
+		{"grpc.stream_interval", c.GRPC.StreamInterval},
+		{"database.connect_timeout", c.Database.ConnectTimeout},
+		{"database.conn_max_lifetime", c.Database.ConnMaxLifetime},
+		{"database.conn_max_idle_time", c.Database.ConnMaxIdleTime},
+		{"database.connect_retry_timeout", c.Database.ConnectRetryTimeout},
+		{"database.retry_initial_backoff", c.Database.RetryInitialBackoff},
+		{"database.retry_max_backoff", c.Database.RetryMaxBackoff},
+		{"ingest.cache_ttl", c.Ingest.CacheTTL},
+		{"ingest.failed_receipt_ttl", c.Ingest.FailedReceiptTTL},
+		{"cache.ttl", c.Cache.TTL},
+		{"cache.redis.timeout", c.Cache.Redis.Timeout},
+		{"counters.reconcile_interval", c.Counters.ReconcileInterval},
+	} {
+		if timeout.value.Duration < 0 {
+			problems = append(problems, timeout.name+" must not be negative")
+		}
+	}
+
+	if c.GRPC.Addr != "" && c.GRPC.Addr == c.Server.Addr {
+		problems = append(problems, "grpc.addr must differ from server.addr")
+	}
+
+	// Настройки подключения проверяются только для выбранного драйвера
+	switch c.Database.Driver {
+	case DriverPostgres:
+		if c.Database.Host == "" {
+			problems = append(problems, "database.host is required")
+		}
+		if c.Database.Port <= 0 || c.Database.Port > 65535 {
+			problems = append(problems, "database.port must be between 1 and 65535")
+		}
+		if c.Database.User == "" {
+			problems = append(problems, "database.user is required")
+		}
+		if c.Database.Name == "" {
+			problems = append(problems, "database.name is required")
+		}
+		if !sslModes[c.Database.SSLMode] {
+			problems = append(problems, fmt.Sprintf("database.sslmode %q is not supported", c.Database.SSLMode))
+		}
+	case DriverSQLite:
+		if c.Database.Path == "" {
+			problems = append(problems, "database.path is required")
+		}
+	case DriverMemory:
+	default:
+		problems = append(problems, fmt.Sprintf("database.driver %q is not supported, use %s, %s or %s",
+			c.Database.Driver, DriverPostgres, DriverSQLite, DriverMemory))
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		problems = append(problems, "database pool sizes must not be negative")
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		problems = append(problems, "database.max_idle_conns must not exceed database.max_open_conns")
+	}
+
+	if c.Database.RetryMaxBackoff.Duration < c.Database.RetryInitialBackoff.Duration {
+		problems = append(problems, "database.retry_max_backoff must not be less than database.retry_initial_backoff")
+	}
+
+	if !logLevels[c.Log.Level] {
+		problems = append(problems, fmt.Sprintf("log.level %q is not supported", c.Log.Level))
+	}
+	if c.Log.SlowQueryThreshold.Duration < 0 {
+		problems = append(problems, "log.slow_query_threshold must not be negative")
+	}
+
+	if c.Tracing.Enabled && c.Tracing.Endpoint == "" {
+		problems = append(problems, "tracing.endpoint is required when tracing is enabled")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		problems = append(problems, "tracing.sample_ratio must be between 0 and 1")
+	}
+
+	if c.Ingest.Enabled {
+		if c.Ingest.QueueSize < 1 || c.Ingest.BatchSize < 1 {
+			problems = append(problems, "ingest.queue_size and ingest.batch_size must be at least 1")
+		}
+		if c.Ingest.FlushInterval.Duration <= 0 {
+			problems = append(problems, "ingest.flush_interval must be positive")
+		}
+	}
+
+	switch c.Cache.Driver {
+	case CacheNone:
+	case CacheMemory:
+		if c.Cache.Size < 1 {
+			problems = append(problems, "cache.size must be at least 1")
+		}
+	case CacheRedis:
+		if c.Cache.Redis.Addr == "" {
+			problems = append(problems, "cache.redis.addr is required")
+		}
+		if c.Cache.Redis.DB < 0 || c.Cache.Redis.PoolSize < 1 {
+			problems = append(problems, "cache.redis.db must not be negative and cache.redis.pool_size must be at least 1")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("cache.driver %q is not supported, use %s, %s or %s",
+			c.Cache.Driver, CacheNone, CacheMemory, CacheRedis))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
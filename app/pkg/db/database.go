@@ -1,43 +1,122 @@
 package db
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
-	"log"
-	"os"
+	"log/slog"
+	"time"
+
+	"service-poll/pkg/config"
 
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
-var err error
-
-func InitDB() {
-	dbHost := os.Getenv("DB_HOST")
-	dbPort := os.Getenv("DB_PORT")
-	dbUser := os.Getenv("DB_USER")
-	dbPassword := os.Getenv("DB_PASSWORD")
-	dbTimezone := os.Getenv("DB_TIMEZONE")
-	dbName := os.Getenv("DB_NAME")
-
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s sslmode=disable TimeZone=%s", dbHost, dbPort, dbUser, dbPassword, dbTimezone)
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+
+// InitDB подключается к базе данных драйвером database.driver, создавая ее при необходимости,
+// и настраивает пул соединений. Пока база данных недоступна, попытки повторяются
+// с экспоненциальной задержкой в течение database.connect_retry_timeout.
+// SQL-запросы пишутся в журнал gormLogger. Нарушения уникальности возвращаются
+// как gorm.ErrDuplicatedKey независимо от драйвера.
+func InitDB(ctx context.Context, cfg config.DatabaseConfig, gormLogger gormlogger.Interface) error {
+	gormConfig := &gorm.Config{Logger: gormLogger, TranslateError: true}
+
+	deadline := time.Now().Add(cfg.ConnectRetryTimeout.Duration)
+	backoff := cfg.RetryInitialBackoff.Duration
+
+	for attempt := 1; ; attempt++ {
+		conn, err := open(cfg, gormConfig)
+		if err == nil {
+			DB = conn
+			return nil
+		}
+
+		if time.Now().Add(backoff).After(deadline) {
+			return fmt.Errorf("connect to database after %d attempt(s): %w", attempt, err)
+		}
+
+		slog.Warn("database is not available, retrying",
+			slog.Int("attempt", attempt), slog.Duration("backoff", backoff), slog.String("error", err.Error()))
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("connect to database: %w", ctx.Err())
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > cfg.RetryMaxBackoff.Duration {
+			backoff = cfg.RetryMaxBackoff.Duration
+		}
+	}
+}
+
+// open выполняет одну попытку подключения к базе данных
+func open(cfg config.DatabaseConfig, gormConfig *gorm.Config) (*gorm.DB, error) {
+	d, ok := drivers[cfg.Driver]
+	if !ok {
+		return nil, fmt.Errorf("unsupported database driver %q", cfg.Driver)
+	}
+
+	if d.prepare != nil {
+		if err := d.prepare(cfg, gormConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	conn, err := gorm.Open(d.dialector(cfg), gormConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	// Настраиваем пул соединений
+	sqlDB, err := conn.DB()
+	if err != nil {
+		return nil, err
+	}
+	d.configurePool(sqlDB, cfg)
+
+	return conn, nil
+}
+
+// Ping проверяет, что база данных доступна
+func Ping(ctx context.Context) error {
+	if DB == nil {
+		return errors.New("database is not initialized")
+	}
+
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// Stats возвращает статистику пула соединений
+func Stats() sql.DBStats {
+	if DB == nil {
+		return sql.DBStats{}
+	}
+
+	sqlDB, err := DB.DB()
 	if err != nil {
-		log.Fatal("Failed to connect to the database")
+		return sql.DBStats{}
 	}
+	return sqlDB.Stats()
+}
 
-	var dbNameOnServer string
-	queryResult := DB.Raw("SELECT datname FROM pg_database WHERE datname = ?", dbName).Scan(&dbNameOnServer)
-	if queryResult.Error != nil {
-		log.Panicf("Error executing query: %v", queryResult.Error)
-	} else if queryResult.RowsAffected == 0 {
-		createDatabaseCommand := fmt.Sprintf("CREATE DATABASE \"%s\"", dbName)
-		DB.Exec(createDatabaseCommand)
+// Close закрывает пул соединений с базой данных
+func Close() error {
+	if DB == nil {
+		return nil
 	}
 
-	dsn = fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable TimeZone=%s", dbHost, dbPort, dbUser, dbPassword, dbName, dbTimezone)
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	sqlDB, err := DB.DB()
 	if err != nil {
-		log.Fatal("Failed to connect to the database")
+		return err
 	}
+	return sqlDB.Close()
 }
@@ -0,0 +1,34 @@
+package db
+
+import (
+	"database/sql"
+
+	"service-poll/pkg/config"
+
+	"gorm.io/gorm"
+)
+
+// driver подключение к хранилищу одного вида
+type driver struct {
+	// prepare готовит хранилище перед подключением, например создает базу данных; может быть nil
+	prepare func(cfg config.DatabaseConfig, gormConfig *gorm.Config) error
+	// dialector возвращает диалект GORM для подключения к хранилищу
+	dialector func(cfg config.DatabaseConfig) gorm.Dialector
+	// configurePool настраивает пул соединений
+	configurePool func(sqlDB *sql.DB, cfg config.DatabaseConfig)
+}
+
+// drivers драйверы по значению database.driver
+var drivers = map[string]driver{
+	config.DriverPostgres: {prepare: ensureDatabase, dialector: postgresDialector, configurePool: configurePool},
+	config.DriverSQLite:   {prepare: ensureDirectory, dialector: sqliteDialector, configurePool: configurePool},
+	config.DriverMemory:   {dialector: memoryDialector, configurePool: configureMemoryPool},
+}
+
+// configurePool настраивает пул соединений по настройкам database.*
+func configurePool(sqlDB *sql.DB, cfg config.DatabaseConfig) {
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime.Duration)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime.Duration)
+}
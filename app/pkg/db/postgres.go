@@ -0,0 +1,83 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"service-poll/pkg/config"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// Код ошибки PostgreSQL "duplicate_database"
+const pgDuplicateDatabase = "42P04"
+
+// dsn формирует строку подключения к PostgreSQL; пустое имя базы — подключение без выбора базы
+func dsn(cfg config.DatabaseConfig, dbName string) string {
+	parts := []string{
+		"host=" + quoteDSNValue(cfg.Host),
+		fmt.Sprintf("port=%d", cfg.Port),
+		"user=" + quoteDSNValue(cfg.User),
+		"password=" + quoteDSNValue(cfg.Password),
+		"sslmode=" + quoteDSNValue(cfg.SSLMode),
+		"TimeZone=" + quoteDSNValue(cfg.Timezone),
+	}
+	if dbName != "" {
+		parts = append(parts, "dbname="+quoteDSNValue(dbName))
+	}
+	if cfg.ConnectTimeout.Duration > 0 {
+		parts = append(parts, fmt.Sprintf("connect_timeout=%d", int(cfg.ConnectTimeout.Seconds())))
+	}
+	return strings.Join(parts, " ")
+}
+
+// quoteDSNValue экранирует значение для строки подключения в формате key=value
+func quoteDSNValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
+// quoteIdentifier экранирует имя объекта базы данных для SQL
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// postgresDialector возвращает диалект PostgreSQL для базы database.name
+func postgresDialector(cfg config.DatabaseConfig) gorm.Dialector {
+	return postgres.Open(dsn(cfg, cfg.Name))
+}
+
+// ensureDatabase создает базу данных PostgreSQL, если ее еще нет на сервере
+func ensureDatabase(cfg config.DatabaseConfig, gormConfig *gorm.Config) error {
+	conn, err := gorm.Open(postgres.Open(dsn(cfg, "")), gormConfig)
+	if err != nil {
+		return err
+	}
+
+	// Соединение без выбора базы нужно только для ее создания
+	if sqlDB, err := conn.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	var dbNameOnServer string
+	queryResult := conn.Raw("SELECT datname FROM pg_database WHERE datname = ?", cfg.Name).Scan(&dbNameOnServer)
+	if queryResult.Error != nil {
+		return fmt.Errorf("check database %q: %w", cfg.Name, queryResult.Error)
+	}
+	if queryResult.RowsAffected > 0 {
+		return nil
+	}
+
+	// База могла быть создана параллельно другим экземпляром сервиса
+	var pgErr *pgconn.PgError
+	if err := conn.Exec("CREATE DATABASE " + quoteIdentifier(cfg.Name)).Error; err != nil &&
+		!(errors.As(err, &pgErr) && pgErr.Code == pgDuplicateDatabase) {
+		return fmt.Errorf("create database %q: %w", cfg.Name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"service-poll/pkg/config"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// sqliteOptions параметры подключения к SQLite: при занятой базе запрос ждет снятия
+// блокировки вместо немедленной ошибки SQLITE_BUSY. Транзакции сразу берут блокировку
+// записи (BEGIN IMMEDIATE): транзакция, которая сначала читает, а затем пишет, иначе
+// получает SQLITE_BUSY без ожидания, если другая транзакция уже пишет.
+const sqliteOptions = "_pragma=busy_timeout(5000)&_txlock=immediate"
+
+// sqliteDialector возвращает диалект SQLite для файла database.path. Журнал WAL позволяет
+// читать базу во время записи.
+func sqliteDialector(cfg config.DatabaseConfig) gorm.Dialector {
+	return sqlite.Open("file:" + cfg.Path + "?" + sqliteOptions + "&_pragma=journal_mode(WAL)")
+}
+
+// ensureDirectory создает каталог файла базы SQLite; сам файл SQLite создает при подключении
+func ensureDirectory(cfg config.DatabaseConfig, _ *gorm.Config) error {
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o755); err != nil {
+		return fmt.Errorf("create database directory: %w", err)
+	}
+	return nil
+}
+
+// memoryDatabases счетчик баз в памяти: каждое подключение получает новую пустую базу
+var memoryDatabases atomic.Int64
+
+// memoryDialector возвращает диалект базы SQLite в памяти процесса. Соединения пула
+// открывают одну и ту же базу через VFS memdb с обычными блокировками SQLite.
+func memoryDialector(config.DatabaseConfig) gorm.Dialector {
+	name := fmt.Sprintf("/service-poll-%d", memoryDatabases.Add(1))
+	return sqlite.Open("file:" + name + "?vfs=memdb&" + sqliteOptions)
+}
+
+// configureMemoryPool настраивает пул соединений базы в памяти. База существует,
+// пока открыто хотя бы одно соединение, поэтому простаивающие соединения не закрываются.
+func configureMemoryPool(sqlDB *sql.DB, cfg config.DatabaseConfig) {
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(max(cfg.MaxIdleConns, 1))
+	sqlDB.SetConnMaxLifetime(0)
+	sqlDB.SetConnMaxIdleTime(0)
+}
@@ -0,0 +1,219 @@
+package definition
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"service-poll/pkg/apperror"
+	"service-poll/pkg/models"
+
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// Форматы документа с описанием опроса
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// Definition переносимое описание опроса: заголовок, адрес, настройки, вопросы и варианты ответов
+type Definition struct {
+	Title     string     `json:"title" yaml:"title"`
+	URL       string     `json:"url" yaml:"url"`
+	Settings  Settings   `json:"settings" yaml:"settings"`
+	Questions []Question `json:"questions" yaml:"questions"`
+}
+
+// Settings настройки опроса
+type Settings struct {
+	Publish bool `json:"publish" yaml:"publish"` // опубликовать ревизию опроса сразу после импорта
+}
+
+// Question описание вопроса опроса
+type Question struct {
+	Text    string   `json:"text" yaml:"text"`
+	Type    string   `json:"type" yaml:"type"` // тип вопроса: "single" или "multiple"
+	Options []string `json:"options" yaml:"options"`
+}
+
+// Validate проверяет, что описание опроса можно импортировать.
+// Возвращает *apperror.Error со всеми полями, не прошедшими проверку.
+func (d Definition) Validate() error {
+	var fields []apperror.FieldError
+	if strings.TrimSpace(d.Title) == "" {
+		fields = append(fields, apperror.Field("title", "required", "is required"))
+	}
+	if strings.TrimSpace(d.URL) == "" {
+		fields = append(fields, apperror.Field("url", "required", "is required"))
+	}
+	fields = append(fields, d.questionErrors()...)
+
+	if len(fields) > 0 {
+		return apperror.Validation(fields...)
+	}
+	return nil
+}
+
+// ValidateQuestions проверяет вопросы и варианты ответов описания
+func (d Definition) ValidateQuestions() error {
+	if fields := d.questionErrors(); len(fields) > 0 {
+		return apperror.Validation(fields...)
+	}
+	return nil
+}
+
+// questionErrors возвращает ошибки проверки вопросов и вариантов ответов
+func (d Definition) questionErrors() []apperror.FieldError {
+	var fields []apperror.FieldError
+
+	questionTexts := make(map[string]bool, len(d.Questions))
+	for i, question := range d.Questions {
+		field := fmt.Sprintf("questions[%d]", i)
+
+		if strings.TrimSpace(question.Text) == "" {
+			fields = append(fields, apperror.Field(field+".text", "required", "is required"))
+		} else if questionTexts[question.Text] {
+			fields = append(fields, apperror.Field(field+".text", "duplicate", fmt.Sprintf("duplicate question (%s)", question.Text)))
+		}
+		questionTexts[question.Text] = true
+
+		if question.Type != "single" && question.Type != "multiple" {
+			fields = append(fields, apperror.Field(field+".type", "oneof", "must be one of: single, multiple"))
+		}
+		if len(question.Options) == 0 {
+			fields = append(fields, apperror.Field(field+".options", "min", "must contain at least 1 item(s)"))
+		}
+
+		optionTexts := make(map[string]bool, len(question.Options))
+		for j, option := range question.Options {
+			optionField := fmt.Sprintf("%s.options[%d]", field, j)
+			if strings.TrimSpace(option) == "" {
+				fields = append(fields, apperror.Field(optionField, "required", "is required"))
+			} else if optionTexts[option] {
+				fields = append(fields, apperror.Field(optionField, "duplicate", fmt.Sprintf("duplicate option (%s)", option)))
+			}
+			optionTexts[option] = true
+		}
+	}
+
+	return fields
+}
+
+// Parse разбирает документ с одним описанием опроса или списком описаний
+func Parse(data []byte, format string) ([]Definition, error) {
+	switch format {
+	case FormatJSON:
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+			var definitions []Definition
+			if err := json.Unmarshal(trimmed, &definitions); err != nil {
+				return nil, err
+			}
+			return definitions, nil
+		}
+
+		var single Definition
+		if err := json.Unmarshal(data, &single); err != nil {
+			return nil, err
+		}
+		return []Definition{single}, nil
+
+	case FormatYAML:
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil {
+			return nil, err
+		}
+		if len(node.Content) > 0 && node.Content[0].Kind == yaml.SequenceNode {
+			var definitions []Definition
+			if err := node.Decode(&definitions); err != nil {
+				return nil, err
+			}
+			return definitions, nil
+		}
+
+		var single Definition
+		if err := node.Decode(&single); err != nil {
+			return nil, err
+		}
+		return []Definition{single}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported format (%s)", format)
+}
+
+// Marshal сериализует описание опроса в указанном формате
+func Marshal(d Definition, format string) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		return json.MarshalIndent(d, "", "  ")
+	case FormatYAML:
+		return yaml.Marshal(d)
+	}
+
+	return nil, fmt.Errorf("unsupported format (%s)", format)
+}
+
+// FromPoll формирует описание из опроса с загруженными вопросами и вариантами ответов
+func FromPoll(poll models.Poll) Definition {
+	d := Definition{
+		Title:     poll.Title,
+		URL:       poll.URL,
+		Settings:  Settings{Publish: poll.Revision > 0},
+		Questions: make([]Question, 0, len(poll.Questions)),
+	}
+
+	for _, question := range poll.Questions {
+		questionDefinition := Question{
+			Text:    question.Text,
+			Type:    question.Type,
+			Options: make([]string, 0, len(question.PossibleAnswer)),
+		}
+		for _, possibleAnswer := range question.PossibleAnswer {
+			questionDefinition.Options = append(questionDefinition.Options, possibleAnswer.Text)
+		}
+		d.Questions = append(d.Questions, questionDefinition)
+	}
+
+	return d
+}
+
+// Create создает опрос со всеми вопросами и вариантами ответов по описанию.
+// Должна вызываться внутри транзакции.
+func Create(tx *gorm.DB, d Definition) (models.Poll, error) {
+	poll := models.Poll{
+		Title: d.Title,
+		URL:   d.URL,
+	}
+	if err := tx.Create(&poll).Error; err != nil {
+		return models.Poll{}, err
+	}
+
+	for _, questionDefinition := range d.Questions {
+		question := models.Question{
+			Text:   questionDefinition.Text,
+			Type:   questionDefinition.Type,
+			PollID: poll.ID,
+		}
+		if err := tx.Create(&question).Error; err != nil {
+			return models.Poll{}, err
+		}
+
+		for position, option := range questionDefinition.Options {
+			possibleAnswer := models.PossibleAnswer{
+				Text:       option,
+				QuestionID: question.ID,
+				Position:   position,
+			}
+			if err := tx.Create(&possibleAnswer).Error; err != nil {
+				return models.Poll{}, err
+			}
+			question.PossibleAnswer = append(question.PossibleAnswer, possibleAnswer)
+		}
+
+		poll.Questions = append(poll.Questions, question)
+	}
+
+	return poll, nil
+}
@@ -0,0 +1,66 @@
+package definition
+
+import (
+	"regexp"
+	"sort"
+
+	"service-poll/pkg/apperror"
+)
+
+// placeholderPattern параметр шаблона вида {{name}}
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// Placeholders возвращает отсортированный список параметров, используемых
+// в заголовке и текстах вопросов описания
+func (d Definition) Placeholders() []string {
+	seen := make(map[string]bool)
+	collect := func(text string) {
+		for _, match := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+			seen[match[1]] = true
+		}
+	}
+
+	collect(d.Title)
+	for _, question := range d.Questions {
+		collect(question.Text)
+	}
+
+	placeholders := make([]string, 0, len(seen))
+	for name := range seen {
+		placeholders = append(placeholders, name)
+	}
+	sort.Strings(placeholders)
+
+	return placeholders
+}
+
+// Substitute возвращает копию описания, в заголовке и текстах вопросов которой
+// параметры {{name}} заменены значениями. Все используемые параметры должны быть переданы.
+func (d Definition) Substitute(params map[string]string) (Definition, error) {
+	var missing []apperror.FieldError
+	for _, name := range d.Placeholders() {
+		if _, ok := params[name]; !ok {
+			missing = append(missing, apperror.Field("params."+name, "required", "template parameter is required"))
+		}
+	}
+	if len(missing) > 0 {
+		return Definition{}, apperror.Validation(missing...)
+	}
+
+	replace := func(text string) string {
+		return placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+			return params[placeholderPattern.FindStringSubmatch(placeholder)[1]]
+		})
+	}
+
+	result := d
+	result.Title = replace(d.Title)
+	result.Questions = make([]Question, 0, len(d.Questions))
+	for _, question := range d.Questions {
+		question.Text = replace(question.Text)
+		question.Options = append([]string(nil), question.Options...)
+		result.Questions = append(result.Questions, question)
+	}
+
+	return result, nil
+}
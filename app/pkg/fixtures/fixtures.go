@@ -0,0 +1,201 @@
+// Package fixtures создает опросы с вопросами, вариантами ответа и ответами респондентов.
+// Используется для фикстур миграций и в интеграционных тестах:
+//
+//	poll, err := fixtures.Poll("Colors").
+//		Single("Favorite color", "Red", "Green", "Blue").
+//		Multiple("Colors you wear", "Red", "Green", "Blue").
+//		Answer(1, 0, 2).
+//		Answer(1, 1, 0, 1).
+//		Create(db.DB)
+package fixtures
+
+import (
+	"fmt"
+	"strings"
+
+	"service-poll/pkg/definition"
+	"service-poll/pkg/models"
+	"service-poll/pkg/service"
+
+	"gorm.io/gorm"
+)
+
+// Типы вопросов
+const (
+	Single   = "single"
+	Multiple = "multiple"
+)
+
+// batchSize количество строк в одном запросе INSERT при создании ответов
+const batchSize = 500
+
+// PollBuilder описание опроса, который создается методом Create
+type PollBuilder struct {
+	definition definition.Definition
+	answers    []answerFixture
+}
+
+// answerFixture ответ респондента на вопрос; вопрос и варианты заданы номерами от нуля
+type answerFixture struct {
+	userID   uint
+	question int
+	options  []int
+}
+
+// Poll начинает описание опроса с заголовком title. Адрес по умолчанию получается
+// из заголовка: "Poll 1" — "poll-1".
+func Poll(title string) *PollBuilder {
+	return &PollBuilder{definition: definition.Definition{
+		Title: title,
+		URL:   strings.ToLower(strings.Join(strings.Fields(title), "-")),
+	}}
+}
+
+// URL задает адрес опроса
+func (b *PollBuilder) URL(url string) *PollBuilder {
+	b.definition.URL = url
+	return b
+}
+
+// Question добавляет вопрос с вариантами ответа в порядке их следования
+func (b *PollBuilder) Question(text, questionType string, options ...string) *PollBuilder {
+	b.definition.Questions = append(b.definition.Questions, definition.Question{
+		Text:    text,
+		Type:    questionType,
+		Options: options,
+	})
+	return b
+}
+
+// Single добавляет вопрос с одним вариантом ответа
+func (b *PollBuilder) Single(text string, options ...string) *PollBuilder {
+	return b.Question(text, Single, options...)
+}
+
+// Multiple добавляет вопрос с несколькими вариантами ответа
+func (b *PollBuilder) Multiple(text string, options ...string) *PollBuilder {
+	return b.Question(text, Multiple, options...)
+}
+
+// Published публикует первую ревизию опроса; ответы, заданные методом Answer,
+// относятся к этой ревизии
+func (b *PollBuilder) Published() *PollBuilder {
+	b.definition.Settings.Publish = true
+	return b
+}
+
+// Answer добавляет ответ пользователя userID на вопрос с номером question (от нуля)
+// вариантами с номерами options (от нуля). Токен доступа — "user-<userID>-token".
+func (b *PollBuilder) Answer(userID uint, question int, options ...int) *PollBuilder {
+	b.answers = append(b.answers, answerFixture{userID: userID, question: question, options: options})
+	return b
+}
+
+// Create создает опрос в базе данных tx и возвращает его с вопросами, вариантами ответа
+// и ответами. Номера вопросов и вариантов в ответах проверяются до записи.
+func (b *PollBuilder) Create(tx *gorm.DB) (models.Poll, error) {
+	if err := b.validate(); err != nil {
+		return models.Poll{}, err
+	}
+
+	var poll models.Poll
+	err := tx.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if poll, err = definition.Create(tx, b.definition); err != nil {
+			return err
+		}
+
+		// Ответы на опубликованный опрос относятся к его ревизии, как при регистрации через API
+		var revisionID *uint
+		if b.definition.Settings.Publish {
+			revision, err := service.PublishRevision(tx, &poll)
+			if err != nil {
+				return err
+			}
+			poll.Revision = revision.Number
+			revisionID = &revision.ID
+		}
+
+		// Ответы и их связи с вариантами создаются пачками: генератор может описать
+		// десятки тысяч ответов
+		answers := make([]models.Answer, len(b.answers))
+		for i, fixture := range b.answers {
+			answers[i] = models.Answer{
+				UserID:      fixture.userID,
+				AccessToken: fmt.Sprintf("user-%d-token", fixture.userID),
+				QuestionID:  poll.Questions[fixture.question].ID,
+				PollID:      poll.ID,
+				RevisionID:  revisionID,
+			}
+		}
+		if len(answers) == 0 {
+			return nil
+		}
+		if err := tx.CreateInBatches(&answers, batchSize).Error; err != nil {
+			return err
+		}
+
+		// Связи с вариантами ответа создаются через таблицу answer_possible_answers
+		var links []models.AnswerPossibleAnswer
+		for i, fixture := range b.answers {
+			question := poll.Questions[fixture.question]
+			for _, option := range fixture.options {
+				links = append(links, models.AnswerPossibleAnswer{
+					AnswerID:         answers[i].ID,
+					PossibleAnswerID: question.PossibleAnswer[option].ID,
+				})
+				answers[i].PossibleAnswers = append(answers[i].PossibleAnswers, question.PossibleAnswer[option])
+			}
+		}
+		if len(links) > 0 {
+			if err := tx.CreateInBatches(&links, batchSize).Error; err != nil {
+				return err
+			}
+		}
+
+		// Счетчики результатов обновляются так же, как при регистрации ответов через API
+		if err := service.CountAnswers(tx, answers); err != nil {
+			return err
+		}
+
+		poll.Answers = answers
+		return nil
+	})
+	if err != nil {
+		return models.Poll{}, fmt.Errorf("create poll %q: %w", b.definition.Title, err)
+	}
+
+	return poll, nil
+}
+
+// validate проверяет номера вопросов и вариантов в ответах
+func (b *PollBuilder) validate() error {
+	for _, fixture := range b.answers {
+		if fixture.question < 0 || fixture.question >= len(b.definition.Questions) {
+			return fmt.Errorf("poll %q: answer of user %d refers to question %d, poll has %d question(s)",
+				b.definition.Title, fixture.userID, fixture.question, len(b.definition.Questions))
+		}
+
+		options := b.definition.Questions[fixture.question].Options
+		for _, option := range fixture.options {
+			if option < 0 || option >= len(options) {
+				return fmt.Errorf("poll %q: answer of user %d refers to option %d of question %d, question has %d option(s)",
+					b.definition.Title, fixture.userID, option, fixture.question, len(options))
+			}
+		}
+	}
+	return nil
+}
+
+// CreateAll создает опросы по порядку и возвращает их
+func CreateAll(tx *gorm.DB, builders ...*PollBuilder) ([]models.Poll, error) {
+	polls := make([]models.Poll, 0, len(builders))
+	for _, builder := range builders {
+		poll, err := builder.Create(tx)
+		if err != nil {
+			return nil, err
+		}
+		polls = append(polls, poll)
+	}
+	return polls, nil
+}
@@ -0,0 +1,162 @@
+package fixtures
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Распределения, по которым синтетические респонденты выбирают варианты ответа
+const (
+	DistributionUniform = "uniform" // все варианты равновероятны
+	DistributionSkewed  = "skewed"  // вероятность варианта номер i пропорциональна 1/(i+1)
+	DistributionCustom  = "custom"  // вероятности пропорциональны весам Weights
+)
+
+// Generator параметры генерации опросов с синтетическими респондентами.
+// Одни и те же параметры, включая Seed, всегда дают одни и те же опросы и ответы
+// независимо от идентификаторов, которые им присвоит база данных.
+type Generator struct {
+	Seed        int64
+	Polls       int // количество опросов
+	Questions   int // вопросов в опросе
+	Options     int // вариантов ответа в вопросе, не меньше двух
+	Respondents int // синтетических пользователей с идентификаторами FirstUserID, FirstUserID+1, ...
+	FirstUserID uint
+	// Доля вопросов с несколькими вариантами ответа (0-1)
+	MultipleRatio float64
+	// Вероятность, что респондент отвечает на опрос (0-1)
+	ResponseRate float64
+	Distribution string
+	Weights      []float64 // веса вариантов по порядку для DistributionCustom, len(Weights) == Options
+	Publish      bool      // публиковать опросы; ответы относятся к первой ревизии
+}
+
+// DefaultGenerator возвращает параметры фикстур миграции: три опроса по три вопроса
+// с тремя вариантами ответа и шесть респондентов
+func DefaultGenerator() Generator {
+	return Generator{
+		Seed:          1,
+		Polls:         3,
+		Questions:     3,
+		Options:       3,
+		Respondents:   6,
+		FirstUserID:   1,
+		MultipleRatio: 0.4,
+		ResponseRate:  1,
+		Distribution:  DistributionUniform,
+	}
+}
+
+// Generate описывает опросы "Poll 1", "Poll 2", ... с вопросами "Question 1 Poll 1", ...
+// и вариантами "Answer 1 Question 1 Poll 1", ... Респондент, отвечающий на опрос, отвечает
+// на все его вопросы: на вопрос с одним вариантом — одним, на вопрос с несколькими —
+// от двух до всех вариантов. Параметры должны быть проверены заранее.
+func (g Generator) Generate() []*PollBuilder {
+	rng := rand.New(rand.NewSource(g.Seed))
+	weights := g.weights()
+
+	builders := make([]*PollBuilder, 0, g.Polls)
+	for pollNumber := 1; pollNumber <= g.Polls; pollNumber++ {
+		builder := Poll(fmt.Sprintf("Poll %d", pollNumber)).URL(fmt.Sprintf("poll-%d-url", pollNumber))
+		if g.Publish {
+			builder.Published()
+		}
+
+		questionTypes := make([]string, g.Questions)
+		for questionIndex := range questionTypes {
+			questionTypes[questionIndex] = Single
+			if rng.Float64() < g.MultipleRatio {
+				questionTypes[questionIndex] = Multiple
+			}
+
+			options := make([]string, g.Options)
+			for optionIndex := range options {
+				options[optionIndex] = fmt.Sprintf("Answer %d Question %d Poll %d", optionIndex+1, questionIndex+1, pollNumber)
+			}
+			builder.Question(fmt.Sprintf("Question %d Poll %d", questionIndex+1, pollNumber), questionTypes[questionIndex], options...)
+		}
+
+		for respondent := 0; respondent < g.Respondents; respondent++ {
+			if rng.Float64() >= g.ResponseRate {
+				continue
+			}
+
+			userID := g.FirstUserID + uint(respondent)
+			for questionIndex, questionType := range questionTypes {
+				count := 1
+				if questionType == Multiple {
+					count = 2 + rng.Intn(g.Options-1)
+				}
+				builder.Answer(userID, questionIndex, choose(rng, weights, count)...)
+			}
+		}
+
+		builders = append(builders, builder)
+	}
+	return builders
+}
+
+// weights возвращает веса вариантов ответа по распределению
+func (g Generator) weights() []float64 {
+	weights := make([]float64, g.Options)
+	for i := range weights {
+		switch g.Distribution {
+		case DistributionSkewed:
+			weights[i] = 1 / float64(i+1)
+		case DistributionCustom:
+			weights[i] = math.Max(g.Weights[i], 0)
+		default:
+			weights[i] = 1
+		}
+	}
+	return weights
+}
+
+// choose выбирает count разных номеров вариантов с вероятностями, пропорциональными весам,
+// и возвращает их по возрастанию. Варианты с нулевым весом выбираются, только если
+// вариантов с ненулевым весом не хватает.
+func choose(rng *rand.Rand, weights []float64, count int) []int {
+	chosen := make([]bool, len(weights))
+
+	for n := 0; n < count; n++ {
+		var total float64
+		for i, weight := range weights {
+			if !chosen[i] {
+				total += weight
+			}
+		}
+
+		pick := -1
+		if total > 0 {
+			target := rng.Float64() * total
+			for i, weight := range weights {
+				if chosen[i] || weight == 0 {
+					continue
+				}
+				pick = i
+				if target -= weight; target < 0 {
+					break
+				}
+			}
+		} else {
+			// Остались только варианты с нулевым весом
+			free := make([]int, 0, len(weights))
+			for i := range weights {
+				if !chosen[i] {
+					free = append(free, i)
+				}
+			}
+			pick = free[rng.Intn(len(free))]
+		}
+		chosen[pick] = true
+	}
+
+	options := make([]int, 0, count)
+	for i, ok := range chosen {
+		if ok {
+			options = append(options, i)
+		}
+	}
+	return options
+}
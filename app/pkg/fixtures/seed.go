@@ -0,0 +1,104 @@
+package fixtures
+
+import (
+	"context"
+
+	"service-poll/pkg/api"
+	"service-poll/pkg/apperror"
+	"service-poll/pkg/models"
+	"service-poll/pkg/service"
+
+	"gorm.io/gorm"
+)
+
+// MaxSeedAnswers наибольшее количество ответов (опросы × вопросы × респонденты),
+// которое можно сгенерировать одним запросом
+const MaxSeedAnswers = 1_000_000
+
+// DefaultSeedRequest возвращает параметры генерации по умолчанию — те же, что у фикстур миграции
+func DefaultSeedRequest() api.SeedRequest {
+	generator := DefaultGenerator()
+	return api.SeedRequest{
+		Seed:          generator.Seed,
+		Polls:         generator.Polls,
+		Questions:     generator.Questions,
+		Options:       generator.Options,
+		Respondents:   generator.Respondents,
+		FirstUserID:   generator.FirstUserID,
+		MultipleRatio: generator.MultipleRatio,
+		ResponseRate:  generator.ResponseRate,
+		Distribution:  generator.Distribution,
+	}
+}
+
+// Seed проверяет параметры запроса, генерирует опросы с синтетическими респондентами
+// и создает их в одной транзакции. Незаданные поля запроса нужно заполнить значениями
+// DefaultSeedRequest.
+func Seed(ctx context.Context, tx *gorm.DB, request api.SeedRequest) (api.SeedResponse, error) {
+	if err := service.Validate(request); err != nil {
+		return api.SeedResponse{}, err
+	}
+	if answers := request.Polls * request.Questions * request.Respondents; answers > MaxSeedAnswers {
+		return api.SeedResponse{}, apperror.Invalid("respondents", "max",
+			"polls × questions × respondents must be at most %d, got %d", MaxSeedAnswers, answers)
+	}
+	if err := validateWeights(request); err != nil {
+		return api.SeedResponse{}, err
+	}
+
+	generator := Generator{
+		Seed:          request.Seed,
+		Polls:         request.Polls,
+		Questions:     request.Questions,
+		Options:       request.Options,
+		Respondents:   request.Respondents,
+		FirstUserID:   request.FirstUserID,
+		MultipleRatio: request.MultipleRatio,
+		ResponseRate:  request.ResponseRate,
+		Distribution:  request.Distribution,
+		Weights:       request.Weights,
+		Publish:       request.Publish,
+	}
+
+	var polls []models.Poll
+	if err := tx.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		polls, err = CreateAll(tx, generator.Generate()...)
+		return err
+	}); err != nil {
+		return api.SeedResponse{}, apperror.Internal("Failed to seed polls", err)
+	}
+
+	seedResponse := api.SeedResponse{Seed: request.Seed, Polls: make([]api.PollSummary, 0, len(polls))}
+	for _, poll := range polls {
+		seedResponse.Polls = append(seedResponse.Polls, service.NewPollSummary(poll))
+		seedResponse.Answers += len(poll.Answers)
+	}
+	return seedResponse, nil
+}
+
+// validateWeights проверяет веса вариантов: они задаются только для распределения custom,
+// по одному неотрицательному весу на вариант, хотя бы один больше нуля
+func validateWeights(request api.SeedRequest) error {
+	if request.Distribution != DistributionCustom {
+		if len(request.Weights) > 0 {
+			return apperror.Invalid("weights", "excluded", "is only allowed with distribution %s", DistributionCustom)
+		}
+		return nil
+	}
+
+	if len(request.Weights) != request.Options {
+		return apperror.Invalid("weights", "len", "must have one weight per option: %d, got %d", request.Options, len(request.Weights))
+	}
+	var total float64
+	for _, weight := range request.Weights {
+		if weight < 0 {
+			return apperror.Invalid("weights", "min", "must not be negative")
+		}
+		total += weight
+	}
+	if total == 0 {
+		return apperror.Invalid("weights", "min", "must have at least one positive weight")
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+package graphqlapi
+
+import (
+	"context"
+
+	"service-poll/pkg/db"
+	"service-poll/pkg/models"
+	"service-poll/pkg/service"
+
+	"gorm.io/gorm"
+)
+
+// votes голоса за варианты ответа на вопрос
+type votes struct {
+	Total   int          // количество ответов на вопрос
+	Options map[uint]int // количество голосов по идентификатору варианта
+}
+
+// respondent пользователь, ответивший на вопросы опроса, с его ответами
+type respondent struct {
+	UserID  uint
+	Answers []models.Answer
+}
+
+// result результат по варианту ответа на вопрос опроса
+type result struct {
+	Question models.Question
+	Option   models.PossibleAnswer
+	Votes    votes
+}
+
+// loadQuestions загружает вопросы опросов в порядке создания
+func loadQuestions(ctx context.Context, pollIDs []uint) (map[uint][]models.Question, error) {
+	var questions []models.Question
+	if err := db.DB.WithContext(ctx).Where("poll_id IN ?", pollIDs).Order("id").Find(&questions).Error; err != nil {
+		return nil, err
+	}
+
+	byPoll := make(map[uint][]models.Question, len(pollIDs))
+	for _, question := range questions {
+		byPoll[question.PollID] = append(byPoll[question.PollID], question)
+	}
+	return byPoll, nil
+}
+
+// loadQuestionsByID загружает вопросы по идентификаторам
+func loadQuestionsByID(ctx context.Context, ids []uint) (map[uint]*models.Question, error) {
+	var questions []models.Question
+	if err := db.DB.WithContext(ctx).Where("id IN ?", ids).Find(&questions).Error; err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint]*models.Question, len(questions))
+	for i := range questions {
+		byID[questions[i].ID] = &questions[i]
+	}
+	return byID, nil
+}
+
+// loadOptions загружает варианты ответов на вопросы в порядке их следования
+func loadOptions(ctx context.Context, questionIDs []uint) (map[uint][]models.PossibleAnswer, error) {
+	var options []models.PossibleAnswer
+	if err := db.DB.WithContext(ctx).Where("question_id IN ?", questionIDs).Order("position, id").Find(&options).Error; err != nil {
+		return nil, err
+	}
+
+	byQuestion := make(map[uint][]models.PossibleAnswer, len(questionIDs))
+	for _, option := range options {
+		byQuestion[option.QuestionID] = append(byQuestion[option.QuestionID], option)
+	}
+	return byQuestion, nil
+}
+
+// loadVotes загружает количество ответов на вопросы и голосов за их варианты из счетчиков результатов
+func loadVotes(ctx context.Context, questionIDs []uint) (map[uint]votes, error) {
+	counts, err := service.LoadCounts(ctx, questionIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	byQuestion := make(map[uint]votes, len(counts))
+	for id, questionCounts := range counts {
+		byQuestion[id] = votes{Total: questionCounts.Answers, Options: questionCounts.Votes}
+	}
+	return byQuestion, nil
+}
+
+// respondentsPage страница респондентов опроса
+type respondentsPage struct {
+	PollID uint
+	First  int
+	Offset int
+}
+
+// loadRespondents загружает страницы респондентов опросов. Пользователи опросов нумеруются
+// в базе данных по возрастанию идентификатора (ROW_NUMBER), и номера всех страниц выбираются
+// одним запросом; затем ответы с выбранными вариантами загружаются одним запросом
+// только для пользователей страниц.
+func loadRespondents(ctx context.Context, pages []respondentsPage) (map[respondentsPage][]respondent, error) {
+	byPage := make(map[respondentsPage][]respondent, len(pages))
+
+	// Условие выбирает номера пользователей каждой страницы
+	var pageCondition *gorm.DB
+	pollIDs := make([]uint, 0, len(pages))
+	for _, page := range pages {
+		byPage[page] = []respondent{}
+		if page.First <= 0 {
+			continue
+		}
+		pollIDs = append(pollIDs, page.PollID)
+		query := "poll_id = ? AND position > ? AND position <= ?"
+		if pageCondition == nil {
+			pageCondition = db.DB.Where(query, page.PollID, page.Offset, page.Offset+page.First)
+		} else {
+			pageCondition = pageCondition.Or(query, page.PollID, page.Offset, page.Offset+page.First)
+		}
+	}
+	if pageCondition == nil {
+		return byPage, nil
+	}
+
+	ranked := db.DB.Model(&models.Answer{}).
+		Select("poll_id, user_id, ROW_NUMBER() OVER (PARTITION BY poll_id ORDER BY user_id) AS position").
+		Where("poll_id IN ?", pollIDs).
+		Group("poll_id, user_id")
+
+	var rows []struct {
+		PollID   uint
+		UserID   uint
+		Position int
+	}
+	if err := db.DB.WithContext(ctx).Table("(?) AS ranked", ranked).
+		Select("poll_id, user_id, position").
+		Where(pageCondition).
+		Order("poll_id, position").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	// Страницы одного опроса могут пересекаться, поэтому строка достается каждой своей странице
+	users := make(map[uint]map[uint]bool)
+	for _, row := range rows {
+		if users[row.PollID] == nil {
+			users[row.PollID] = make(map[uint]bool)
+		}
+		users[row.PollID][row.UserID] = true
+		for _, page := range pages {
+			if page.PollID == row.PollID && row.Position > page.Offset && row.Position <= page.Offset+page.First {
+				byPage[page] = append(byPage[page], respondent{UserID: row.UserID})
+			}
+		}
+	}
+
+	// Условие выбирает ответы пользователей страниц каждого опроса
+	var condition *gorm.DB
+	for pollID, userSet := range users {
+		if len(userSet) == 0 {
+			continue
+		}
+		userIDs := make([]uint, 0, len(userSet))
+		for userID := range userSet {
+			userIDs = append(userIDs, userID)
+		}
+		if condition == nil {
+			condition = db.DB.Where("poll_id = ? AND user_id IN ?", pollID, userIDs)
+		} else {
+			condition = condition.Or("poll_id = ? AND user_id IN ?", pollID, userIDs)
+		}
+	}
+	if condition == nil {
+		return byPage, nil
+	}
+
+	var answers []models.Answer
+	if err := db.DB.WithContext(ctx).Preload("PossibleAnswers").Where(condition).Order("id").Find(&answers).Error; err != nil {
+		return nil, err
+	}
+	userAnswers := make(map[[2]uint][]models.Answer)
+	for _, answer := range answers {
+		key := [2]uint{answer.PollID, answer.UserID}
+		userAnswers[key] = append(userAnswers[key], answer)
+	}
+
+	for page, respondents := range byPage {
+		for i := range respondents {
+			respondents[i].Answers = userAnswers[[2]uint{page.PollID, respondents[i].UserID}]
+		}
+	}
+	return byPage, nil
+}
+
+// loadRespondentCounts загружает количество пользователей, ответивших на вопросы опросов
+func loadRespondentCounts(ctx context.Context, pollIDs []uint) (map[uint]int, error) {
+	var counts []struct {
+		PollID uint
+		Count  int
+	}
+	if err := db.DB.WithContext(ctx).Model(&models.Answer{}).
+		Select("poll_id, COUNT(DISTINCT user_id) AS count").
+		Where("poll_id IN ?", pollIDs).
+		Group("poll_id").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+
+	byPoll := make(map[uint]int, len(counts))
+	for _, count := range counts {
+		byPoll[count.PollID] = count.Count
+	}
+	return byPoll, nil
+}
+
+// loadResults формирует результаты опросов по текущим вопросам: все варианты ответа,
+// в том числе без голосов. Вопросы, варианты и голоса загружаются общими запросами для всех опросов.
+func loadResults(ctx context.Context, pollIDs []uint) (map[uint][]result, error) {
+	questions, err := loadQuestions(ctx, pollIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var questionIDs []uint
+	for _, pollID := range pollIDs {
+		for _, question := range questions[pollID] {
+			questionIDs = append(questionIDs, question.ID)
+		}
+	}
+	if len(questionIDs) == 0 {
+		return map[uint][]result{}, nil
+	}
+
+	options, err := loadOptions(ctx, questionIDs)
+	if err != nil {
+		return nil, err
+	}
+	questionVotes, err := loadVotes(ctx, questionIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	byPoll := make(map[uint][]result, len(pollIDs))
+	for _, pollID := range pollIDs {
+		for _, question := range questions[pollID] {
+			for _, option := range options[question.ID] {
+				byPoll[pollID] = append(byPoll[pollID], result{
+					Question: question,
+					Option:   option,
+					Votes:    questionVotes[question.ID],
+				})
+			}
+		}
+	}
+	return byPoll, nil
+}
+
+// loaders загрузчики одного запроса GraphQL
+type loaders struct {
+	questions     *Loader[uint, []models.Question]
+	questionsByID *Loader[uint, *models.Question]
+	options       *Loader[uint, []models.PossibleAnswer]
+	votes         *Loader[uint, votes]
+	respondents   *Loader[respondentsPage, []respondent]
+	respondentCnt *Loader[uint, int]
+	results       *Loader[uint, []result]
+}
+
+// newLoaders создает загрузчики для запроса с контекстом ctx
+func newLoaders(ctx context.Context) *loaders {
+	return &loaders{
+		questions:     NewLoader(ctx, loadQuestions),
+		questionsByID: NewLoader(ctx, loadQuestionsByID),
+		options:       NewLoader(ctx, loadOptions),
+		votes:         NewLoader(ctx, loadVotes),
+		respondents:   NewLoader(ctx, loadRespondents),
+		respondentCnt: NewLoader(ctx, loadRespondentCounts),
+		results:       NewLoader(ctx, loadResults),
+	}
+}
+
+// loadersKey ключ загрузчиков в context.Context
+type loadersKey struct{}
+
+// loadersFrom возвращает загрузчики запроса из контекста
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
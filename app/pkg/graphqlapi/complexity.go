@@ -0,0 +1,151 @@
+package graphqlapi
+
+import (
+	"strconv"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// maxComplexity наибольшая допустимая сложность запроса. Запрос по умолчанию
+// polls { questions { options } respondents { answers { options } } } стоит около 2200,
+// а те же списки по 100 элементов на каждом уровне — больше 50000.
+const maxComplexity = 10000
+
+// pagedFields поля со списками, размер которых задается аргументом first
+var pagedFields = map[string]bool{
+	"polls":       true,
+	"respondents": true,
+}
+
+// complexity оценивает сложность запроса: каждое поле стоит 1 вместе с вложенными полями,
+// а стоимость поля постраничного списка умножается на размер страницы. Для документа
+// из нескольких операций возвращается сложность самой дорогой из них. Запрос, который
+// не удалось разобрать, получает нулевую сложность: ошибку разбора вернет исполнитель.
+func complexity(query string, variables map[string]interface{}) int {
+	document, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return 0
+	}
+
+	estimator := complexityEstimator{
+		fragments: make(map[string]*ast.FragmentDefinition),
+		costs:     make(map[string]int),
+	}
+	for _, definition := range document.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok && fragment.Name != nil {
+			estimator.fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	highest := 0
+	for _, definition := range document.Definitions {
+		if operation, ok := definition.(*ast.OperationDefinition); ok {
+			estimator.variables = operationVariables(operation, variables)
+			highest = max(highest, estimator.selectionSet(operation.SelectionSet))
+		}
+	}
+	return highest
+}
+
+// operationVariables дополняет переданные значения переменных операции значениями по умолчанию
+func operationVariables(operation *ast.OperationDefinition, variables map[string]interface{}) map[string]interface{} {
+	values := make(map[string]interface{}, len(variables))
+	for name, value := range variables {
+		values[name] = value
+	}
+	for _, definition := range operation.VariableDefinitions {
+		if definition.Variable == nil || definition.Variable.Name == nil {
+			continue
+		}
+		name := definition.Variable.Name.Value
+		if _, ok := values[name]; ok {
+			continue
+		}
+		if value, ok := definition.DefaultValue.(*ast.IntValue); ok {
+			if n, err := strconv.Atoi(value.Value); err == nil {
+				values[name] = n
+			}
+		}
+	}
+	return values
+}
+
+// complexityEstimator считает сложность наборов полей одного документа. Стоимость
+// фрагмента не зависит от места его использования, поэтому считается один раз:
+// так вложенные друг в друга фрагменты не приводят к экспоненциальному обходу.
+type complexityEstimator struct {
+	variables map[string]interface{}
+	fragments map[string]*ast.FragmentDefinition
+	costs     map[string]int // стоимость фрагментов; -1 — фрагмент считается сейчас
+}
+
+// selectionSet возвращает стоимость набора полей
+func (e *complexityEstimator) selectionSet(set *ast.SelectionSet) int {
+	if set == nil {
+		return 0
+	}
+
+	cost := 0
+	for _, selection := range set.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			cost += e.pageSize(selection) * (1 + e.selectionSet(selection.SelectionSet))
+		case *ast.InlineFragment:
+			cost += e.selectionSet(selection.SelectionSet)
+		case *ast.FragmentSpread:
+			cost += e.fragment(selection.Name.Value)
+		}
+		// Точное значение сверх предела не нужно, а счет без ограничения может переполниться
+		if cost > maxComplexity {
+			return cost
+		}
+	}
+	return cost
+}
+
+// fragment возвращает стоимость именованного фрагмента. Циклические фрагменты
+// стоят 0: такой запрос отклонит проверка по схеме.
+func (e *complexityEstimator) fragment(name string) int {
+	if cost, ok := e.costs[name]; ok {
+		return max(cost, 0)
+	}
+	fragment, ok := e.fragments[name]
+	if !ok {
+		return 0
+	}
+
+	e.costs[name] = -1
+	cost := e.selectionSet(fragment.SelectionSet)
+	e.costs[name] = cost
+	return cost
+}
+
+// pageSize возвращает множитель стоимости поля: размер страницы для постраничных списков
+// и 1 для остальных полей. Недопустимый размер страницы отклонит обработчик поля.
+func (e *complexityEstimator) pageSize(field *ast.Field) int {
+	if field.Name == nil || !pagedFields[field.Name.Value] {
+		return 1
+	}
+
+	first := defaultPageSize
+	for _, argument := range field.Arguments {
+		if argument.Name == nil || argument.Name.Value != "first" {
+			continue
+		}
+		switch value := argument.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(value.Value); err == nil {
+				first = n
+			}
+		case *ast.Variable:
+			switch n := e.variables[value.Name.Value].(type) {
+			case float64:
+				first = int(n)
+			case int:
+				first = n
+			}
+		}
+	}
+	return min(max(first, 1), maxPageSize)
+}
@@ -0,0 +1,135 @@
+// Package graphqlapi схема GraphQL для гибких запросов опросов, вопросов, вариантов ответа,
+// респондентов и результатов. Связанные объекты загружаются пакетами через Loader,
+// поэтому количество запросов к базе данных зависит от глубины запроса, а не от числа объектов.
+package graphqlapi
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"service-poll/pkg/apperror"
+	"service-poll/pkg/logger"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// Request запрос GraphQL
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Response ответ GraphQL
+type Response struct {
+	Data   interface{} `json:"data"`
+	Errors []Error     `json:"errors,omitempty"`
+}
+
+// Error ошибка выполнения запроса GraphQL. Ошибки полей содержат в extensions
+// стабильный код из pkg/apperror, как и ответы HTTP API.
+type Error struct {
+	Message    string                 `json:"message"`
+	Locations  []Location             `json:"locations,omitempty"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// Location позиция в тексте запроса, к которой относится ошибка
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Do выполняет запрос GraphQL. Возвращает ответ и HTTP-статус: 400, если запрос
+// не удалось разобрать, он не соответствует схеме или слишком сложен, иначе 200,
+// в том числе при ошибках полей.
+func Do(ctx context.Context, request Request) (Response, int) {
+	if complexity(request.Query, request.Variables) > maxComplexity {
+		return Response{Errors: []Error{{
+			Message: fmt.Sprintf("Query complexity exceeds the limit of %d", maxComplexity),
+			Extensions: map[string]interface{}{
+				"code":           apperror.CodeInvalidRequest,
+				"max_complexity": maxComplexity,
+			},
+		}}}, http.StatusBadRequest
+	}
+
+	ctx = context.WithValue(ctx, loadersKey{}, newLoaders(ctx))
+
+	result := graphql.Do(graphql.Params{
+		Schema:         schema,
+		RequestString:  request.Query,
+		OperationName:  request.OperationName,
+		VariableValues: request.Variables,
+		Context:        ctx,
+	})
+
+	response := Response{Data: result.Data}
+	status := http.StatusOK
+	for _, err := range result.Errors {
+		// Ошибки graphql-go, а не обработчиков полей, означают, что запрос не дошел
+		// до выполнения: синтаксическая ошибка или несоответствие схеме
+		if originalError(err) == nil {
+			status = http.StatusBadRequest
+		}
+		response.Errors = append(response.Errors, formatError(ctx, err))
+	}
+	return response, status
+}
+
+// formatError переводит ошибку graphql-go в ответ: ошибкам полей добавляет код и поля
+// из *apperror.Error, причина внутренних ошибок пишется в журнал и не отдается клиенту
+func formatError(ctx context.Context, err gqlerrors.FormattedError) Error {
+	formatted := Error{Message: err.Message, Path: err.Path}
+	for _, location := range err.Locations {
+		formatted.Locations = append(formatted.Locations, Location{Line: location.Line, Column: location.Column})
+	}
+
+	cause := originalError(err)
+	if cause == nil {
+		// Ошибка разбора или проверки запроса по схеме
+		formatted.Extensions = map[string]interface{}{"code": apperror.CodeInvalidRequest}
+		return formatted
+	}
+
+	appErr := apperror.As(cause)
+	if appErr.Status >= http.StatusInternalServerError {
+		attrs := []any{slog.String("code", string(appErr.Code)), slog.Any("path", err.Path)}
+		if appErr.Err != nil {
+			attrs = append(attrs, slog.String("error", appErr.Err.Error()))
+		}
+		logger.FromContext(ctx).ErrorContext(ctx, appErr.Detail, attrs...)
+	}
+
+	formatted.Message = appErr.Detail
+	formatted.Extensions = map[string]interface{}{"code": appErr.Code}
+	if len(appErr.Fields) > 0 {
+		formatted.Extensions["errors"] = appErr.Fields
+	}
+	for key, value := range appErr.Extensions {
+		formatted.Extensions[key] = value
+	}
+	return formatted
+}
+
+// originalError возвращает ошибку, которую вернул (или паникой выбросил) обработчик поля,
+// либо nil для ошибок самого graphql-go
+func originalError(err error) error {
+	for {
+		switch e := err.(type) {
+		case gqlerrors.FormattedError:
+			err = e.OriginalError()
+		case *gqlerrors.Error:
+			err = e.OriginalError
+		default:
+			return err
+		}
+		if err == nil {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package graphqlapi
+
+import "context"
+
+// BatchFunc загружает значения сразу для нескольких ключей. Ключи, которых нет
+// в результате, получают нулевое значение.
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// Loader собирает ключи, запрошенные полями одного уровня запроса GraphQL, и загружает
+// их одним запросом к базе данных вместо запроса на каждый объект (проблема N+1).
+//
+// Load только запоминает ключ и возвращает отложенное значение. Исполнитель graphql-go
+// вычисляет отложенные значения в ширину: сначала все поля уровня регистрируют свои ключи,
+// затем первое отложенное значение загружает их все сразу. Загруженные значения
+// кешируются на время запроса. Loader создается на каждый запрос и не рассчитан
+// на одновременное использование из нескольких горутин.
+type Loader[K comparable, V any] struct {
+	ctx     context.Context
+	batch   BatchFunc[K, V]
+	pending []K
+	queued  map[K]bool
+	values  map[K]V
+	errs    map[K]error
+}
+
+// NewLoader создает загрузчик с функцией пакетной загрузки batch
+func NewLoader[K comparable, V any](ctx context.Context, batch BatchFunc[K, V]) *Loader[K, V] {
+	return &Loader[K, V]{
+		ctx:    ctx,
+		batch:  batch,
+		queued: make(map[K]bool),
+		values: make(map[K]V),
+		errs:   make(map[K]error),
+	}
+}
+
+// Load добавляет ключ в очередную пакетную загрузку и возвращает функцию,
+// которая отдает значение, загружая очередь при первом вызове
+func (l *Loader[K, V]) Load(key K) func() (V, error) {
+	if _, loaded := l.values[key]; !loaded && l.errs[key] == nil && !l.queued[key] {
+		l.queued[key] = true
+		l.pending = append(l.pending, key)
+	}
+
+	return func() (V, error) {
+		if l.queued[key] {
+			l.dispatch()
+		}
+		return l.values[key], l.errs[key]
+	}
+}
+
+// dispatch загружает все ключи из очереди одним вызовом batch
+func (l *Loader[K, V]) dispatch() {
+	keys := l.pending
+	l.pending = nil
+	for _, key := range keys {
+		delete(l.queued, key)
+	}
+
+	values, err := l.batch(l.ctx, keys)
+	for _, key := range keys {
+		if err != nil {
+			l.errs[key] = err
+			continue
+		}
+		l.values[key] = values[key]
+	}
+}
+
+// thunk переводит отложенное значение загрузчика в форму, которую понимает graphql-go
+func thunk[V any](load func() (V, error)) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		return load()
+	}
+}
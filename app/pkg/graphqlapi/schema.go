@@ -0,0 +1,382 @@
+package graphqlapi
+
+import (
+	"strconv"
+
+	"service-poll/pkg/apperror"
+	"service-poll/pkg/db"
+	"service-poll/pkg/models"
+	"service-poll/pkg/service"
+
+	"github.com/graphql-go/graphql"
+)
+
+// Ограничения постраничной выборки списков
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// pageArgs аргументы постраничной выборки списка
+var pageArgs = graphql.FieldConfigArgument{
+	"first": &graphql.ArgumentConfig{
+		Type:         graphql.Int,
+		DefaultValue: defaultPageSize,
+		Description:  "Maximum number of items to return, at most 100",
+	},
+	"offset": &graphql.ArgumentConfig{
+		Type:         graphql.Int,
+		DefaultValue: 0,
+		Description:  "Number of items to skip",
+	},
+}
+
+// page возвращает границы выборки по аргументам first и offset
+func page(args map[string]interface{}) (first, offset int, err error) {
+	first, _ = args["first"].(int)
+	offset, _ = args["offset"].(int)
+	if first < 1 || first > maxPageSize {
+		return 0, 0, apperror.Invalid("first", "range", "must be between 1 and %d", maxPageSize)
+	}
+	if offset < 0 {
+		return 0, 0, apperror.Invalid("offset", "min", "must be non-negative")
+	}
+	return first, offset, nil
+}
+
+// parseID разбирает идентификатор из аргумента типа ID
+func parseID(args map[string]interface{}, name string) (uint, error) {
+	value, _ := args[name].(string)
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || id == 0 {
+		return 0, apperror.Invalid(name, "positive", "must be a positive integer")
+	}
+	return uint(id), nil
+}
+
+// formatID переводит идентификатор записи в значение типа ID
+func formatID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// optionType вариант ответа на вопрос
+var optionType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "Option",
+	Description: "An answer option of a question",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.ID),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return formatID(p.Source.(models.PossibleAnswer).ID), nil
+			},
+		},
+		"text": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(models.PossibleAnswer).Text, nil
+			},
+		},
+		"position": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(models.PossibleAnswer).Position, nil
+			},
+		},
+		"votes": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.Int),
+			Description: "Number of answers that chose the option",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				option := p.Source.(models.PossibleAnswer)
+				load := loadersFrom(p.Context).votes.Load(option.QuestionID)
+				return func() (interface{}, error) {
+					questionVotes, err := load()
+					return questionVotes.Options[option.ID], err
+				}, nil
+			},
+		},
+		"percentage": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.String),
+			Description: "Share of answers to the question that chose the option, e.g. \"33.333\"",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				option := p.Source.(models.PossibleAnswer)
+				load := loadersFrom(p.Context).votes.Load(option.QuestionID)
+				return func() (interface{}, error) {
+					questionVotes, err := load()
+					return service.AnswerPercentage(questionVotes.Options[option.ID], questionVotes.Total), err
+				}, nil
+			},
+		},
+	},
+})
+
+// questionType вопрос опроса
+var questionType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "Question",
+	Description: "A poll question",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.ID),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return formatID(p.Source.(models.Question).ID), nil
+			},
+		},
+		"text": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(models.Question).Text, nil
+			},
+		},
+		"type": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.String),
+			Description: "Question type: \"single\" or \"multiple\"",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(models.Question).Type, nil
+			},
+		},
+		"options": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(optionType))),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return thunk(loadersFrom(p.Context).options.Load(p.Source.(models.Question).ID)), nil
+			},
+		},
+		"answerCount": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.Int),
+			Description: "Number of answers to the question",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				load := loadersFrom(p.Context).votes.Load(p.Source.(models.Question).ID)
+				return func() (interface{}, error) {
+					questionVotes, err := load()
+					return questionVotes.Total, err
+				}, nil
+			},
+		},
+	},
+})
+
+// respondentAnswerType ответ пользователя на вопрос
+var respondentAnswerType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "RespondentAnswer",
+	Description: "A respondent's answer to a question",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.ID),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return formatID(p.Source.(models.Answer).ID), nil
+			},
+		},
+		"question": &graphql.Field{
+			Type:        questionType,
+			Description: "The question, or null if it was deleted",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				load := loadersFrom(p.Context).questionsByID.Load(p.Source.(models.Answer).QuestionID)
+				return func() (interface{}, error) {
+					question, err := load()
+					if err != nil || question == nil {
+						return nil, err
+					}
+					return *question, nil
+				}, nil
+			},
+		},
+		"options": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(optionType))),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(models.Answer).PossibleAnswers, nil
+			},
+		},
+		"answeredAt": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.DateTime),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(models.Answer).CreatedAt, nil
+			},
+		},
+	},
+})
+
+// respondentType пользователь, ответивший на вопросы опроса
+var respondentType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "Respondent",
+	Description: "A user who answered the poll",
+	Fields: graphql.Fields{
+		"userId": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.ID),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return formatID(p.Source.(respondent).UserID), nil
+			},
+		},
+		"answers": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(respondentAnswerType))),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(respondent).Answers, nil
+			},
+		},
+	},
+})
+
+// resultType результат по варианту ответа на вопрос
+var resultType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "Result",
+	Description: "Aggregated votes for an option of a current question",
+	Fields: graphql.Fields{
+		"question": &graphql.Field{
+			Type: graphql.NewNonNull(questionType),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(result).Question, nil
+			},
+		},
+		"option": &graphql.Field{
+			Type: graphql.NewNonNull(optionType),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(result).Option, nil
+			},
+		},
+		"votes": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				r := p.Source.(result)
+				return r.Votes.Options[r.Option.ID], nil
+			},
+		},
+		"percentage": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				r := p.Source.(result)
+				return service.AnswerPercentage(r.Votes.Options[r.Option.ID], r.Votes.Total), nil
+			},
+		},
+	},
+})
+
+// pollType опрос
+var pollType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "Poll",
+	Description: "A poll",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.ID),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return formatID(p.Source.(models.Poll).ID), nil
+			},
+		},
+		"title": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(models.Poll).Title, nil
+			},
+		},
+		"url": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(models.Poll).URL, nil
+			},
+		},
+		"revision": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.Int),
+			Description: "Number of the latest published revision, 0 if the poll was never published",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(models.Poll).Revision, nil
+			},
+		},
+		"createdAt": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.DateTime),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(models.Poll).CreatedAt, nil
+			},
+		},
+		"updatedAt": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.DateTime),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(models.Poll).UpdatedAt, nil
+			},
+		},
+		"questions": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(questionType))),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return thunk(loadersFrom(p.Context).questions.Load(p.Source.(models.Poll).ID)), nil
+			},
+		},
+		"respondents": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(respondentType))),
+			Description: "Users who answered the poll, ordered by user identifier",
+			Args:        pageArgs,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				first, offset, err := page(p.Args)
+				if err != nil {
+					return nil, err
+				}
+				return thunk(loadersFrom(p.Context).respondents.Load(respondentsPage{
+					PollID: p.Source.(models.Poll).ID,
+					First:  first,
+					Offset: offset,
+				})), nil
+			},
+		},
+		"respondentCount": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return thunk(loadersFrom(p.Context).respondentCnt.Load(p.Source.(models.Poll).ID)), nil
+			},
+		},
+		"results": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(resultType))),
+			Description: "Results by current questions, including options without votes",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return thunk(loadersFrom(p.Context).results.Load(p.Source.(models.Poll).ID)), nil
+			},
+		},
+	},
+})
+
+// queryType корневые поля запросов
+var queryType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Query",
+	Fields: graphql.Fields{
+		"poll": &graphql.Field{
+			Type: pollType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				id, err := parseID(p.Args, "id")
+				if err != nil {
+					return nil, err
+				}
+
+				var poll models.Poll
+				if err := db.DB.WithContext(p.Context).First(&poll, id).Error; err != nil {
+					return nil, service.LookupError(err, apperror.ErrPollNotFound)
+				}
+				return poll, nil
+			},
+		},
+		"polls": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(pollType))),
+			Description: "Polls ordered by identifier",
+			Args:        pageArgs,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				first, offset, err := page(p.Args)
+				if err != nil {
+					return nil, err
+				}
+
+				var polls []models.Poll
+				if err := db.DB.WithContext(p.Context).Order("id").Limit(first).Offset(offset).Find(&polls).Error; err != nil {
+					return nil, apperror.Internal("Failed to fetch polls", err)
+				}
+				return polls, nil
+			},
+		},
+	},
+})
+
+// schema схема GraphQL сервиса
+var schema = mustSchema()
+
+// mustSchema собирает схему; ошибка означает ошибку в описании типов
+func mustSchema() graphql.Schema {
+	s, err := graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+	if err != nil {
+		panic("graphqlapi: invalid schema: " + err.Error())
+	}
+	return s
+}
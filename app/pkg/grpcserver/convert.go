@@ -0,0 +1,154 @@
+package grpcserver
+
+import (
+	"sort"
+
+	"service-poll/pkg/api"
+	"service-poll/pkg/definition"
+	"service-poll/pkg/models"
+	"service-poll/pkg/pollpb"
+)
+
+// pollToProto переводит опрос с вопросами в сообщение gRPC
+func pollToProto(poll api.PollResponse) *pollpb.Poll {
+	message := &pollpb.Poll{
+		Id:       uint32(poll.ID),
+		Title:    poll.Title,
+		Url:      poll.URL,
+		Revision: uint32(poll.Revision),
+	}
+	for _, question := range poll.Questions {
+		questionMessage := &pollpb.Question{Id: uint32(question.ID), Text: question.Text, Type: question.Type}
+		for _, option := range question.PossibleAnswers {
+			questionMessage.Options = append(questionMessage.Options, &pollpb.Option{Id: uint32(option.ID), Text: option.Text})
+		}
+		message.Questions = append(message.Questions, questionMessage)
+	}
+	return message
+}
+
+// questionToProto переводит вопрос с загруженными вариантами ответов в сообщение gRPC
+func questionToProto(question models.Question) *pollpb.Question {
+	message := &pollpb.Question{Id: uint32(question.ID), Text: question.Text, Type: question.Type}
+	for _, possibleAnswer := range question.PossibleAnswer {
+		message.Options = append(message.Options, &pollpb.Option{Id: uint32(possibleAnswer.ID), Text: possibleAnswer.Text})
+	}
+	return message
+}
+
+// answerRequest переводит запрос gRPC в запрос на регистрацию ответов
+func answerRequest(req *pollpb.SubmitAnswersRequest) api.AnswerRequest {
+	attributes := api.AnswerAttributes{
+		AccessToken: req.GetAccessToken(),
+		UserID:      uint(req.GetUserId()),
+		Username:    req.GetUsername(),
+		Email:       req.GetEmail(),
+		UserData: api.UserData{
+			Reason: req.GetUserData().GetReason(),
+			State:  req.GetUserData().GetState(),
+		},
+	}
+	// Пустой список остается nil, чтобы сработала та же проверка required, что и в HTTP API
+	for _, result := range req.GetResults() {
+		attributes.Results = append(attributes.Results, api.AnswerResult{Question: result.GetQuestion(), Answer: result.GetAnswer()})
+	}
+	return api.AnswerRequest{Attributes: attributes}
+}
+
+// answerToProto переводит зарегистрированные ответы в сообщение gRPC
+func answerToProto(answer api.AnswerResponse) *pollpb.SubmitAnswersResponse {
+	message := &pollpb.SubmitAnswersResponse{
+		AccessToken: answer.AccessToken,
+		PollId:      uint32(answer.PollID),
+		UserId:      uint32(answer.UserID),
+	}
+	for _, result := range answer.Results {
+		registered := &pollpb.RegisteredAnswer{QuestionId: uint32(result.QuestionID)}
+		for _, id := range result.Answers {
+			registered.Answers = append(registered.Answers, uint32(id))
+		}
+		message.Results = append(message.Results, registered)
+	}
+	return message
+}
+
+// pollResultsToProto переводит результаты по текущим вопросам в сообщение gRPC.
+// Результаты упорядочиваются по вопросу и варианту, чтобы поток не отправлял
+// одинаковые результаты только из-за другого порядка.
+func pollResultsToProto(results api.PollResultsResponse) *pollpb.Results {
+	message := &pollpb.Results{PollId: uint32(results.ID)}
+	for _, result := range results.Results {
+		message.Results = append(message.Results, &pollpb.Result{
+			Question:         result.Question,
+			Answer:           result.Answer,
+			AnswerCnt:        int64(result.AnswerCnt),
+			AnswerPercentage: result.AnswerPercentage,
+		})
+	}
+	sort.SliceStable(message.Results, func(i, j int) bool {
+		a, b := message.Results[i], message.Results[j]
+		if a.Question != b.Question {
+			return a.Question < b.Question
+		}
+		return a.Answer < b.Answer
+	})
+	return message
+}
+
+// revisionResultsToProto переводит результаты по ревизии или объединенные результаты в сообщение gRPC
+func revisionResultsToProto(results api.RevisionResultsResponse) *pollpb.Results {
+	message := &pollpb.Results{PollId: uint32(results.ID), Revision: results.Revision}
+	for _, result := range results.Results {
+		message.Results = append(message.Results, &pollpb.Result{
+			QuestionId:       uint32(result.QuestionID),
+			Question:         result.Question,
+			PossibleAnswerId: uint32(result.PossibleAnswerID),
+			Answer:           result.Answer,
+			AnswerCnt:        int64(result.AnswerCnt),
+			AnswerPercentage: result.AnswerPercentage,
+		})
+	}
+	return message
+}
+
+// definitionFromProto переводит описание опроса из сообщения gRPC; nil, если описание не передано
+func definitionFromProto(message *pollpb.PollDefinition) *definition.Definition {
+	if message == nil {
+		return nil
+	}
+
+	pollDefinition := &definition.Definition{Title: message.GetTitle()}
+	for _, question := range message.GetQuestions() {
+		pollDefinition.Questions = append(pollDefinition.Questions, definition.Question{
+			Text:    question.GetText(),
+			Type:    question.GetType(),
+			Options: question.GetOptions(),
+		})
+	}
+	return pollDefinition
+}
+
+// templateSummaryToProto переводит сведения о шаблоне без описания опроса в сообщение gRPC
+func templateSummaryToProto(template api.TemplateSummary) *pollpb.Template {
+	return &pollpb.Template{
+		Id:            uint32(template.ID),
+		Name:          template.Name,
+		Description:   template.Description,
+		Parameters:    template.Parameters,
+		QuestionCount: uint32(template.Questions),
+	}
+}
+
+// templateToProto переводит шаблон с описанием опроса в сообщение gRPC
+func templateToProto(template api.Template) *pollpb.Template {
+	message := templateSummaryToProto(template.TemplateSummary)
+	message.Definition = &pollpb.PollDefinition{Title: template.Definition.Title}
+	for _, question := range template.Definition.Questions {
+		message.Definition.Questions = append(message.Definition.Questions, &pollpb.DefinitionQuestion{
+			Text:    question.Text,
+			Type:    question.Type,
+			Options: question.Options,
+		})
+	}
+	return message
+}
@@ -0,0 +1,201 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"service-poll/pkg/apperror"
+	"service-poll/pkg/audit"
+	"service-poll/pkg/logger"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// Ключи метаданных запроса: те же заголовки, что и в HTTP API, в нижнем регистре
+const (
+	requestIDKey = "x-request-id"
+	actorKey     = "x-actor"
+)
+
+// ErrorDomain домен ошибок в errdetails.ErrorInfo; Reason содержит код ошибки apperror
+const ErrorDomain = "service-poll"
+
+// maxRequestIDLength ограничивает длину идентификатора, переданного клиентом
+const maxRequestIDLength = 128
+
+// unaryInterceptor готовит контекст запроса, переводит ошибки в статусы gRPC и пишет запрос в журнал
+func unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	start := time.Now()
+	ctx = requestContext(ctx, info.FullMethod)
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			logger.FromContext(ctx).Error("panic recovered", slog.Any("panic", recovered))
+			err = apperror.New(http.StatusInternalServerError, apperror.CodeInternal, "Internal server error")
+		}
+		err = toStatus(ctx, info.FullMethod, err)
+		logRequest(ctx, info.FullMethod, start, err)
+	}()
+
+	return handler(ctx, req)
+}
+
+// streamInterceptor то же, что unaryInterceptor, для потоковых методов
+func streamInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	start := time.Now()
+	ctx := requestContext(stream.Context(), info.FullMethod)
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			logger.FromContext(ctx).Error("panic recovered", slog.Any("panic", recovered))
+			err = apperror.New(http.StatusInternalServerError, apperror.CodeInternal, "Internal server error")
+		}
+		err = toStatus(ctx, info.FullMethod, err)
+		logRequest(ctx, info.FullMethod, start, err)
+	}()
+
+	return handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+}
+
+// contextStream поток с подготовленным контекстом запроса
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context возвращает контекст запроса
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// requestContext добавляет в контекст логгер с идентификатором запроса и автора изменений
+// для журнала аудита. Идентификатор запроса возвращается клиенту в заголовке x-request-id.
+func requestContext(ctx context.Context, method string) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	requestID := first(md, requestIDKey)
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		requestID = logger.NewRequestID()
+	}
+	grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, requestID))
+
+	// Автор — из x-actor, а если он не передан — адрес клиента, как в HTTP API
+	actor := first(md, actorKey)
+	if actor == "" {
+		if p, ok := peer.FromContext(ctx); ok {
+			actor = p.Addr.String()
+		}
+	}
+
+	ctx = logger.WithContext(ctx, slog.Default().With(slog.String("request_id", requestID)))
+	return audit.NewContext(ctx, audit.Source{Actor: actor, Method: "GRPC", Endpoint: method})
+}
+
+// first возвращает первое значение ключа метаданных
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// toStatus переводит ошибку в статус gRPC. *apperror.Error передается с кодом в ErrorInfo
+// и ошибками полей в BadRequest; причина внутренних ошибок пишется в журнал, но не клиенту.
+func toStatus(ctx context.Context, method string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return status.FromContextError(ctxErr).Err()
+	}
+
+	appErr := apperror.As(err)
+	if appErr.Status >= http.StatusInternalServerError {
+		attrs := []any{slog.String("code", string(appErr.Code)), slog.String("method", method)}
+		if appErr.Err != nil {
+			attrs = append(attrs, slog.String("error", appErr.Err.Error()))
+		}
+		logger.FromContext(ctx).ErrorContext(ctx, appErr.Detail, attrs...)
+	}
+
+	st := status.New(grpcCode(appErr), appErr.Detail)
+
+	info := &errdetails.ErrorInfo{Reason: string(appErr.Code), Domain: ErrorDomain}
+	if len(appErr.Extensions) > 0 {
+		info.Metadata = make(map[string]string, len(appErr.Extensions))
+		for key, value := range appErr.Extensions {
+			info.Metadata[key] = fmt.Sprint(value)
+		}
+	}
+	details := []protoadapt.MessageV1{info}
+	if len(appErr.Fields) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, field := range appErr.Fields {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       field.Field,
+				Description: field.Message,
+			})
+		}
+		details = append(details, badRequest)
+	}
+
+	if withDetails, detailsErr := st.WithDetails(details...); detailsErr == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// grpcCode сопоставляет статус HTTP ошибки с кодом gRPC
+func grpcCode(err *apperror.Error) codes.Code {
+	switch err.Status {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		if err.Code == apperror.CodeTemplateExists {
+			return codes.AlreadyExists
+		}
+		return codes.FailedPrecondition
+	case http.StatusMethodNotAllowed:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	}
+	if err.Status >= http.StatusInternalServerError {
+		return codes.Internal
+	}
+	return codes.Unknown
+}
+
+// logRequest пишет в журнал вызов метода: код ответа и длительность
+func logRequest(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+
+	level := slog.LevelInfo
+	switch code {
+	case codes.OK, codes.Canceled:
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		level = slog.LevelError
+	default:
+		level = slog.LevelWarn
+	}
+
+	logger.FromContext(ctx).LogAttrs(ctx, level, "rpc",
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+	)
+}
@@ -0,0 +1,320 @@
+// Package grpcserver сервер gRPC API сервиса опросов (pollpb.PollService).
+// Обработка запросов выполняется слоем pkg/service, как и в HTTP API,
+// ошибки *apperror.Error переводятся в статусы gRPC.
+package grpcserver
+
+import (
+	"context"
+	"time"
+
+	"service-poll/pkg/api"
+	"service-poll/pkg/apperror"
+	"service-poll/pkg/pollpb"
+	"service-poll/pkg/service"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// DefaultStreamInterval период, с которым StreamResults перечитывает результаты,
+// чтобы заметить ответы, принятые другими экземплярами сервиса
+const DefaultStreamInterval = 5 * time.Second
+
+// Server реализация pollpb.PollService
+type Server struct {
+	pollpb.UnimplementedPollServiceServer
+
+	streamInterval time.Duration
+	shutdown       <-chan struct{}
+}
+
+// New создает сервер gRPC с PollService, стандартной проверкой состояния (grpc.health.v1)
+// и reflection для grpcurl. streamInterval — период перечитывания результатов в StreamResults.
+// После отмены ctx потоки результатов завершаются с кодом Unavailable, чтобы клиенты
+// переподключились к другому экземпляру, а остановка сервера не ждала их до таймаута.
+func New(ctx context.Context, streamInterval time.Duration, opts ...grpc.ServerOption) *grpc.Server {
+	if streamInterval <= 0 {
+		streamInterval = DefaultStreamInterval
+	}
+
+	opts = append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unaryInterceptor),
+		grpc.ChainStreamInterceptor(streamInterceptor),
+	}, opts...)
+
+	server := grpc.NewServer(opts...)
+	pollpb.RegisterPollServiceServer(server, &Server{streamInterval: streamInterval, shutdown: ctx.Done()})
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	reflection.Register(server)
+
+	return server
+}
+
+// CreatePoll создает опрос
+func (s *Server) CreatePoll(ctx context.Context, req *pollpb.CreatePollRequest) (*pollpb.Poll, error) {
+	poll, err := service.CreatePoll(ctx, api.CreatePollRequest{Title: req.GetTitle(), URL: req.GetUrl()})
+	if err != nil {
+		return nil, err
+	}
+	return &pollpb.Poll{Id: uint32(poll.ID), Title: poll.Title, Url: poll.URL, Revision: uint32(poll.Revision)}, nil
+}
+
+// GetPoll получает опрос с вопросами и вариантами ответов
+func (s *Server) GetPoll(ctx context.Context, req *pollpb.GetPollRequest) (*pollpb.Poll, error) {
+	if err := validateID("poll_id", req.GetPollId()); err != nil {
+		return nil, err
+	}
+
+	poll, err := service.GetPoll(ctx, uint(req.GetPollId()))
+	if err != nil {
+		return nil, err
+	}
+	return pollToProto(poll), nil
+}
+
+// UpdatePoll изменяет название и (или) URL опроса
+func (s *Server) UpdatePoll(ctx context.Context, req *pollpb.UpdatePollRequest) (*pollpb.Poll, error) {
+	if err := validateID("poll_id", req.GetPollId()); err != nil {
+		return nil, err
+	}
+
+	poll, err := service.UpdatePoll(ctx, uint(req.GetPollId()), api.UpdatePollRequest{Title: req.GetTitle(), URL: req.GetUrl()})
+	if err != nil {
+		return nil, err
+	}
+	return &pollpb.Poll{Id: uint32(poll.ID), Title: poll.Title, Url: poll.URL, Revision: uint32(poll.Revision)}, nil
+}
+
+// DeletePoll удаляет опрос
+func (s *Server) DeletePoll(ctx context.Context, req *pollpb.DeletePollRequest) (*pollpb.DeleteResponse, error) {
+	if err := validateID("poll_id", req.GetPollId()); err != nil {
+		return nil, err
+	}
+
+	message, err := service.DeletePoll(ctx, uint(req.GetPollId()))
+	if err != nil {
+		return nil, err
+	}
+	return &pollpb.DeleteResponse{Message: message.Message}, nil
+}
+
+// AddQuestion добавляет вопрос с вариантами ответов
+func (s *Server) AddQuestion(ctx context.Context, req *pollpb.AddQuestionRequest) (*pollpb.Question, error) {
+	if err := validateID("poll_id", req.GetPollId()); err != nil {
+		return nil, err
+	}
+
+	question, err := service.AddQuestion(ctx, uint(req.GetPollId()), api.AddQuestionRequest{
+		Text:    req.GetText(),
+		Type:    req.GetType(),
+		Answers: req.GetAnswers(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return questionToProto(question), nil
+}
+
+// UpdateQuestion изменяет вопрос и его варианты ответов
+func (s *Server) UpdateQuestion(ctx context.Context, req *pollpb.UpdateQuestionRequest) (*pollpb.Question, error) {
+	if err := validateID("poll_id", req.GetPollId()); err != nil {
+		return nil, err
+	}
+	if err := validateID("question_id", req.GetQuestionId()); err != nil {
+		return nil, err
+	}
+
+	request := api.UpdateQuestionRequest{
+		Text:    req.GetText(),
+		Type:    req.GetType(),
+		Answers: req.GetAnswers(),
+	}
+	for _, option := range req.GetOptions() {
+		request.Options = append(request.Options, api.OptionUpdate{ID: uint(option.GetId()), Text: option.GetText()})
+	}
+
+	question, err := service.UpdateQuestion(ctx, uint(req.GetPollId()), uint(req.GetQuestionId()), request, req.GetForce())
+	if err != nil {
+		return nil, err
+	}
+	return questionToProto(question), nil
+}
+
+// DeleteQuestion удаляет вопрос
+func (s *Server) DeleteQuestion(ctx context.Context, req *pollpb.DeleteQuestionRequest) (*pollpb.DeleteResponse, error) {
+	if err := validateID("poll_id", req.GetPollId()); err != nil {
+		return nil, err
+	}
+	if err := validateID("question_id", req.GetQuestionId()); err != nil {
+		return nil, err
+	}
+
+	message, err := service.DeleteQuestion(ctx, uint(req.GetPollId()), uint(req.GetQuestionId()))
+	if err != nil {
+		return nil, err
+	}
+	return &pollpb.DeleteResponse{Message: message.Message}, nil
+}
+
+// SubmitAnswers регистрирует ответы пользователя
+func (s *Server) SubmitAnswers(ctx context.Context, req *pollpb.SubmitAnswersRequest) (*pollpb.SubmitAnswersResponse, error) {
+	if err := validateID("poll_id", req.GetPollId()); err != nil {
+		return nil, err
+	}
+
+	answer, err := service.RegisterAnswer(ctx, uint(req.GetPollId()), answerRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	return answerToProto(answer), nil
+}
+
+// GetResults получает результаты опроса
+func (s *Server) GetResults(ctx context.Context, req *pollpb.GetResultsRequest) (*pollpb.Results, error) {
+	if err := validateID("poll_id", req.GetPollId()); err != nil {
+		return nil, err
+	}
+	return results(ctx, uint(req.GetPollId()), req.GetRevision())
+}
+
+// StreamResults отправляет результаты опроса сразу и затем после каждого их изменения.
+// Изменения в этом экземпляре сервиса приходят сразу, в остальных — с периодом streamInterval.
+func (s *Server) StreamResults(req *pollpb.GetResultsRequest, stream pollpb.PollService_StreamResultsServer) error {
+	if err := validateID("poll_id", req.GetPollId()); err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	pollID := uint(req.GetPollId())
+
+	// Подписываемся до первого чтения, чтобы не пропустить изменения между ними
+	changed, unsubscribe := service.SubscribeResults(pollID)
+	defer unsubscribe()
+
+	ticker := time.NewTicker(s.streamInterval)
+	defer ticker.Stop()
+
+	var last *pollpb.Results
+	for {
+		current, err := results(ctx, pollID, req.GetRevision())
+		if err != nil {
+			return err
+		}
+		if last == nil || !proto.Equal(current, last) {
+			if err := stream.Send(current); err != nil {
+				return err
+			}
+			last = current
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.shutdown:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		case <-changed:
+		case <-ticker.C:
+		}
+	}
+}
+
+// CreateTemplate создает шаблон опроса из описания или из существующего опроса
+func (s *Server) CreateTemplate(ctx context.Context, req *pollpb.CreateTemplateRequest) (*pollpb.Template, error) {
+	template, err := service.CreateTemplate(ctx, api.CreateTemplateRequest{
+		Name:        req.GetName(),
+		Description: req.GetDescription(),
+		PollID:      uint(req.GetPollId()),
+		Definition:  definitionFromProto(req.GetDefinition()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return templateToProto(template), nil
+}
+
+// ListTemplates получает список шаблонов
+func (s *Server) ListTemplates(ctx context.Context, req *pollpb.ListTemplatesRequest) (*pollpb.ListTemplatesResponse, error) {
+	templates, err := service.ListTemplates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	message := &pollpb.ListTemplatesResponse{}
+	for _, template := range templates.Templates {
+		message.Templates = append(message.Templates, templateSummaryToProto(template))
+	}
+	return message, nil
+}
+
+// GetTemplate получает шаблон с описанием опроса
+func (s *Server) GetTemplate(ctx context.Context, req *pollpb.GetTemplateRequest) (*pollpb.Template, error) {
+	if err := validateID("template_id", req.GetTemplateId()); err != nil {
+		return nil, err
+	}
+
+	template, err := service.GetTemplate(ctx, uint(req.GetTemplateId()))
+	if err != nil {
+		return nil, err
+	}
+	return templateToProto(template), nil
+}
+
+// DeleteTemplate удаляет шаблон
+func (s *Server) DeleteTemplate(ctx context.Context, req *pollpb.DeleteTemplateRequest) (*pollpb.DeleteResponse, error) {
+	if err := validateID("template_id", req.GetTemplateId()); err != nil {
+		return nil, err
+	}
+
+	message, err := service.DeleteTemplate(ctx, uint(req.GetTemplateId()))
+	if err != nil {
+		return nil, err
+	}
+	return &pollpb.DeleteResponse{Message: message.Message}, nil
+}
+
+// InstantiateTemplate создает опрос из шаблона
+func (s *Server) InstantiateTemplate(ctx context.Context, req *pollpb.InstantiateTemplateRequest) (*pollpb.Poll, error) {
+	if err := validateID("template_id", req.GetTemplateId()); err != nil {
+		return nil, err
+	}
+
+	poll, err := service.InstantiateTemplate(ctx, uint(req.GetTemplateId()), api.InstantiateTemplateRequest{
+		URL:     req.GetUrl(),
+		Params:  req.GetParams(),
+		Publish: req.GetPublish(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pollpb.Poll{Id: uint32(poll.ID), Title: poll.Title, Url: poll.URL, Revision: uint32(poll.Revision)}, nil
+}
+
+// results получает результаты опроса по текущим вопросам, по ревизии или объединенные
+func results(ctx context.Context, pollID uint, revision string) (*pollpb.Results, error) {
+	if revision == "" {
+		pollResults, err := service.PollResults(ctx, pollID)
+		if err != nil {
+			return nil, err
+		}
+		return pollResultsToProto(pollResults), nil
+	}
+
+	revisionResults, err := service.RevisionResults(ctx, pollID, revision)
+	if err != nil {
+		return nil, err
+	}
+	return revisionResultsToProto(revisionResults), nil
+}
+
+// validateID проверяет, что идентификатор в запросе — положительное число, как ValidateIDs в HTTP API
+func validateID(field string, id uint32) error {
+	if id == 0 {
+		return apperror.Invalid(field, "positive", "must be a positive number")
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"net/http"
+
+	"service-poll/pkg/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPollAudit возвращает журнал изменений опроса и его вопросов по указанному идентификатору
+// (?question_id= — только записи одного вопроса).
+func GetPollAudit(c *gin.Context) {
+	questionID, err := queryID(c, "question_id")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	auditResponse, err := service.PollAudit(c.Request.Context(), paramID(c, "id"), questionID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, auditResponse)
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"service-poll/pkg/api"
+	"service-poll/pkg/db"
+	"service-poll/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// dbUnavailableMessage сообщение об ошибке в ответе, если база данных недоступна
+const dbUnavailableMessage = "database ping failed"
+
+// GetDBStats проверяет доступность базы данных и возвращает статистику пула соединений.
+func GetDBStats(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	// Проверяем соединение с базой данных
+	start := time.Now()
+	pingErr := db.Ping(ctx)
+	latency := time.Since(start)
+
+	stats := db.Stats()
+
+	var statsResponse api.DBStatsResponse
+
+	statsResponse.Status = "ok"
+	statsResponse.LatencyMs = float64(latency.Microseconds()) / 1000
+	statsResponse.Pool.MaxOpenConnections = stats.MaxOpenConnections
+	statsResponse.Pool.OpenConnections = stats.OpenConnections
+	statsResponse.Pool.InUse = stats.InUse
+	statsResponse.Pool.Idle = stats.Idle
+	statsResponse.Pool.WaitCount = stats.WaitCount
+	statsResponse.Pool.WaitDurationMs = float64(stats.WaitDuration.Microseconds()) / 1000
+	statsResponse.Pool.MaxIdleClosed = stats.MaxIdleClosed
+	statsResponse.Pool.MaxIdleTimeClosed = stats.MaxIdleTimeClosed
+	statsResponse.Pool.MaxLifetimeClosed = stats.MaxLifetimeClosed
+
+	status := http.StatusOK
+	if pingErr != nil {
+		status = http.StatusServiceUnavailable
+		statsResponse.Status = "unavailable"
+		// Ошибка драйвера может содержать адрес, пользователя и имя базы, поэтому
+		// клиенту возвращается постоянное сообщение, а сама ошибка пишется в журнал
+		statsResponse.Error = dbUnavailableMessage
+		logger.FromContext(c.Request.Context()).WarnContext(c.Request.Context(), "database is unavailable",
+			slog.String("error", pingErr.Error()))
+	}
+
+	c.JSON(status, statsResponse)
+}
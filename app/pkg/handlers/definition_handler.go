@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"service-poll/pkg/api"
+	"service-poll/pkg/apperror"
+	"service-poll/pkg/audit"
+	"service-poll/pkg/definition"
+	"service-poll/pkg/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// definitionFormat определяет формат описания опроса по параметру format,
+// а если он не указан — по значению заголовка (Content-Type или Accept).
+func definitionFormat(c *gin.Context, header string) (string, error) {
+	if format := c.Query("format"); format != "" {
+		if format != definition.FormatJSON && format != definition.FormatYAML {
+			return "", apperror.Invalid("format", "oneof", "must be one of: json, yaml")
+		}
+		return format, nil
+	}
+
+	if strings.Contains(c.GetHeader(header), "yaml") {
+		return definition.FormatYAML, nil
+	}
+	return definition.FormatJSON, nil
+}
+
+// ImportPolls создает опросы по описаниям в формате JSON или YAML в одной транзакции.
+func ImportPolls(c *gin.Context) {
+	format, err := definitionFormat(c, "Content-Type")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		respondError(c, apperror.InvalidRequest("Failed to read request body").Wrap(err))
+		return
+	}
+
+	// Разбираем и проверяем все описания до записи в базу данных
+	definitions, err := definition.Parse(body, format)
+	if err != nil {
+		respondError(c, apperror.InvalidRequest(fmt.Sprintf("Poll definition is not valid %s", strings.ToUpper(format))).Wrap(err))
+		return
+	}
+	importResponse, err := service.ImportPolls(audit.Context(c), definitions)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, importResponse)
+}
+
+// GetPollDefinition возвращает описание опроса по указанному идентификатору в формате JSON или YAML.
+func GetPollDefinition(c *gin.Context) {
+	format, err := definitionFormat(c, "Accept")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// Проверяем, существует ли опрос с указанным идентификатором
+	pollDefinition, err := service.PollDefinition(c.Request.Context(), paramID(c, "id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	data, err := definition.Marshal(pollDefinition, format)
+	if err != nil {
+		respondError(c, apperror.Internal("Failed to export poll", err))
+		return
+	}
+
+	contentType := "application/json; charset=utf-8"
+	if format == definition.FormatYAML {
+		contentType = "application/yaml; charset=utf-8"
+	}
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// ClonePoll создает копию опроса с вопросами и вариантами ответов (без ответов) в виде
+// неопубликованного черновика с новым адресом.
+func ClonePoll(c *gin.Context) {
+	var cloneData api.ClonePollRequest
+
+	if err := decodeJSON(c, &cloneData); err != nil {
+		respondError(c, bindingError(err))
+		return
+	}
+
+	clonedPoll, err := service.ClonePoll(audit.Context(c), paramID(c, "id"), cloneData)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, clonedPoll)
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"service-poll/pkg/apperror"
+	"service-poll/pkg/logger"
+	"service-poll/pkg/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondError отвечает клиенту ошибкой в формате application/problem+json.
+// Ошибки, не являющиеся *apperror.Error, считаются внутренними; причина 5xx пишется в журнал.
+func respondError(c *gin.Context, err error) {
+	appErr := apperror.As(err)
+
+	if appErr.Status >= http.StatusInternalServerError {
+		attrs := []any{slog.String("code", string(appErr.Code)), slog.String("route", c.FullPath())}
+		if appErr.Err != nil {
+			attrs = append(attrs, slog.String("error", appErr.Err.Error()))
+		}
+		logger.FromContext(c.Request.Context()).ErrorContext(c.Request.Context(), appErr.Detail, attrs...)
+	}
+
+	c.Header("Content-Type", apperror.ContentType)
+	c.AbortWithStatusJSON(appErr.Status, appErr.Problem(c.Request.URL.Path, logger.GetRequestID(c)))
+}
+
+// decodeJSON разбирает JSON-тело запроса без проверки полей: поля проверяет pkg/service
+// после того, как убедится, что опрос существует
+func decodeJSON(c *gin.Context, obj interface{}) error {
+	if c.Request.Body == nil {
+		return io.EOF
+	}
+	return json.NewDecoder(c.Request.Body).Decode(obj)
+}
+
+// paramID возвращает идентификатор из пути запроса; корректность проверяет ValidateIDs
+func paramID(c *gin.Context, name string) uint {
+	id, _ := strconv.ParseUint(c.Param(name), 10, 32)
+	return uint(id)
+}
+
+// queryInt возвращает целое число из параметра строки запроса или value, если параметр не указан
+func queryInt(c *gin.Context, name string, value int) (int, error) {
+	raw, ok := c.GetQuery(name)
+	if !ok {
+		return value, nil
+	}
+	number, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, apperror.Invalid(name, "integer", "must be an integer")
+	}
+	return number, nil
+}
+
+// queryID возвращает положительный идентификатор из параметра строки запроса
+// или nil, если параметр не указан
+func queryID(c *gin.Context, name string) (*uint, error) {
+	raw, ok := c.GetQuery(name)
+	if !ok {
+		return nil, nil
+	}
+	id, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil || id == 0 {
+		return nil, apperror.Invalid(name, "positive", "must be a positive number")
+	}
+	value := uint(id)
+	return &value, nil
+}
+
+// idParams параметры маршрутов с идентификаторами записей
+var idParams = []string{"id", "qid", "tid"}
+
+// ValidateIDs проверяет, что идентификаторы в пути запроса — положительные числа,
+// чтобы некорректный идентификатор не доходил до базы данных.
+func ValidateIDs() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, name := range idParams {
+			value, ok := c.Params.Get(name)
+			if !ok {
+				continue
+			}
+			if id, err := strconv.ParseUint(value, 10, 32); err != nil || id == 0 {
+				respondError(c, apperror.Invalid(name, "positive", "must be a positive number"))
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// NoRoute отвечает ошибкой на запрос к несуществующему маршруту.
+func NoRoute(c *gin.Context) {
+	respondError(c, apperror.NotFound(apperror.CodeRouteNotFound, "Route not found"))
+}
+
+// NoMethod отвечает ошибкой на запрос с методом, который маршрут не поддерживает.
+func NoMethod(c *gin.Context) {
+	respondError(c, apperror.New(http.StatusMethodNotAllowed, apperror.CodeMethodNotAllowed, "Method not allowed"))
+}
+
+// bindingError переводит ошибку разбора тела запроса в ошибку с перечнем полей.
+// Текст ошибки разбора клиенту не передается.
+func bindingError(err error) *apperror.Error {
+	if appErr, ok := service.ValidationError(err); ok {
+		return appErr
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return apperror.Invalid(typeErr.Field, "type", "must be %s", jsonTypeName(typeErr.Type))
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return apperror.InvalidRequest(fmt.Sprintf("Request body is not valid JSON (offset %d)", syntaxErr.Offset))
+	}
+
+	if errors.Is(err, io.EOF) {
+		return apperror.InvalidRequest("Request body is required")
+	}
+
+	return apperror.InvalidRequest("Request body is invalid")
+}
+
+// jsonTypeName возвращает название типа JSON для типа Go
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
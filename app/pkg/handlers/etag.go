@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"service-poll/pkg/apperror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondCacheable отвечает значением в формате JSON с заголовком ETag по содержимому ответа.
+// Если ETag совпадает с одним из указанных клиентом в If-None-Match, отвечает 304 без тела.
+func respondCacheable(c *gin.Context, value interface{}) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		respondError(c, apperror.Internal("Failed to encode response", err))
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	c.Header("ETag", etag)
+	// Клиент может хранить ответ, но должен проверять его актуальность при каждом запросе
+	c.Header("Cache-Control", "no-cache")
+
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// etagMatches проверяет, есть ли etag в значении If-None-Match. Сравнение слабое (RFC 9110):
+// префикс W/ не учитывается, "*" совпадает с любым ETag.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
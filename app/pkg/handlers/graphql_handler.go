@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"service-poll/pkg/apperror"
+	"service-poll/pkg/graphqlapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GraphQL выполняет запрос GraphQL. POST принимает JSON {"query", "operationName", "variables"},
+// GET — те же параметры в строке запроса (variables — JSON-объект).
+func GraphQL(c *gin.Context) {
+	var request graphqlapi.Request
+
+	if c.Request.Method == http.MethodGet {
+		request.Query = c.Query("query")
+		request.OperationName = c.Query("operationName")
+		if variables := c.Query("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+				respondError(c, apperror.Invalid("variables", "json", "must be a JSON object"))
+				return
+			}
+		}
+	} else if err := decodeJSON(c, &request); err != nil {
+		respondError(c, bindingError(err))
+		return
+	}
+
+	if request.Query == "" {
+		respondError(c, apperror.Invalid("query", "required", "is required"))
+		return
+	}
+
+	response, status := graphqlapi.Do(c.Request.Context(), request)
+	c.JSON(status, response)
+}
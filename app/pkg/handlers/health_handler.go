@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"service-poll/pkg/health"
+	"service-poll/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readinessCheckTimeout ограничение по времени для проверки одного компонента
+const readinessCheckTimeout = 2 * time.Second
+
+// Healthz сообщает, что процесс сервиса жив. Зависимости не проверяются.
+func Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":         health.StatusOK,
+		"uptime_seconds": int64(health.Uptime().Seconds()),
+	})
+}
+
+// Readyz проверяет готовность сервиса принимать запросы: доступность базы данных,
+// версию схемы и работу фоновых задач. Возвращает результат по каждому компоненту.
+func Readyz(c *gin.Context) {
+	report := health.Ready(c.Request.Context(), readinessCheckTimeout)
+
+	status := http.StatusOK
+	if report.Status != health.StatusOK {
+		status = http.StatusServiceUnavailable
+
+		// Пишем в журнал компоненты, из-за которых сервис не готов
+		log := logger.FromContext(c.Request.Context())
+		for name, component := range report.Components {
+			if component.Status != health.StatusOK {
+				log.WarnContext(c.Request.Context(), "component is not ready",
+					slog.String("component", name), slog.String("status", component.Status), slog.String("error", component.Error))
+			}
+		}
+	}
+
+	c.JSON(status, report)
+}
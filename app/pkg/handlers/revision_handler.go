@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+
+	"service-poll/pkg/audit"
+	"service-poll/pkg/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PublishPoll публикует опрос, создавая новую неизменяемую ревизию его вопросов и вариантов ответов.
+func PublishPoll(c *gin.Context) {
+	revision, err := service.PublishPoll(audit.Context(c), paramID(c, "id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, revision)
+}
+
+// GetPollRevisions возвращает список ревизий опроса по указанному идентификатору.
+func GetPollRevisions(c *gin.Context) {
+	revisions, err := service.PollRevisions(c.Request.Context(), paramID(c, "id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+// GetPollRevision возвращает снимок ревизии опроса по номеру ревизии.
+func GetPollRevision(c *gin.Context) {
+	revision, err := service.PollRevision(c.Request.Context(), paramID(c, "id"), c.Param("rev"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, revision)
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"service-poll/pkg/db"
+	"service-poll/pkg/fixtures"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Seed создает опросы с синтетическими респондентами по параметрам генератора.
+// Поля, не указанные в теле запроса, и пустое тело берутся из параметров по умолчанию.
+func Seed(c *gin.Context) {
+	request := fixtures.DefaultSeedRequest()
+	if err := decodeJSON(c, &request); err != nil && !errors.Is(err, io.EOF) {
+		respondError(c, bindingError(err))
+		return
+	}
+
+	seedResponse, err := fixtures.Seed(c.Request.Context(), db.DB, request)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, seedResponse)
+}
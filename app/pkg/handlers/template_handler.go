@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+
+	"service-poll/pkg/api"
+	"service-poll/pkg/audit"
+	"service-poll/pkg/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateTemplate создает шаблон опроса из описания или из существующего опроса.
+func CreateTemplate(c *gin.Context) {
+	var templateData api.CreateTemplateRequest
+
+	if err := decodeJSON(c, &templateData); err != nil {
+		respondError(c, bindingError(err))
+		return
+	}
+
+	template, err := service.CreateTemplate(audit.Context(c), templateData)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// GetTemplates возвращает список шаблонов опросов.
+func GetTemplates(c *gin.Context) {
+	templatesResponse, err := service.ListTemplates(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, templatesResponse)
+}
+
+// GetTemplate возвращает шаблон опроса по указанному идентификатору.
+func GetTemplate(c *gin.Context) {
+	template, err := service.GetTemplate(c.Request.Context(), paramID(c, "tid"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// DeleteTemplate удаляет шаблон опроса по указанному идентификатору.
+func DeleteTemplate(c *gin.Context) {
+	message, err := service.DeleteTemplate(audit.Context(c), paramID(c, "tid"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, message)
+}
+
+// GetTemplateAudit возвращает журнал изменений шаблона опроса, в том числе удаленного.
+func GetTemplateAudit(c *gin.Context) {
+	auditResponse, err := service.TemplateAudit(c.Request.Context(), paramID(c, "tid"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, auditResponse)
+}
+
+// InstantiateTemplate создает опрос из шаблона, подставляя параметры в заголовок и тексты вопросов.
+func InstantiateTemplate(c *gin.Context) {
+	var instantiateData api.InstantiateTemplateRequest
+
+	if err := decodeJSON(c, &instantiateData); err != nil {
+		respondError(c, bindingError(err))
+		return
+	}
+
+	pollSummary, err := service.InstantiateTemplate(audit.Context(c), paramID(c, "tid"), instantiateData)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pollSummary)
+}
@@ -0,0 +1,189 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CheckFunc проверка готовности одного компонента сервиса
+type CheckFunc func(ctx context.Context) error
+
+// Статусы компонентов и сервиса в целом
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+var (
+	draining atomic.Bool
+	mu       sync.RWMutex
+	checks   = make(map[string]CheckFunc)
+	workers  = make(map[string]*Worker)
+	started  = time.Now()
+)
+
+// Register добавляет проверку компонента, от которой зависит готовность сервиса
+func Register(name string, check CheckFunc) {
+	mu.Lock()
+	defer mu.Unlock()
+	checks[name] = check
+}
+
+// SetDraining переводит сервис в режим остановки: проверка готовности
+// начинает возвращать fail, чтобы балансировщик перестал направлять запросы
+func SetDraining(value bool) {
+	draining.Store(value)
+}
+
+// Draining сообщает, что сервис останавливается
+func Draining() bool {
+	return draining.Load()
+}
+
+// Uptime возвращает время работы процесса
+func Uptime() time.Duration {
+	return time.Since(started)
+}
+
+// Worker состояние фоновой задачи, которое учитывается при проверке готовности
+type Worker struct {
+	name     string
+	running  atomic.Bool
+	lastBeat atomic.Int64
+	maxIdle  time.Duration
+}
+
+// RegisterWorker регистрирует фоновую задачу. Если maxIdle больше нуля, задача считается
+// зависшей, когда от нее не было Beat дольше maxIdle.
+func RegisterWorker(name string, maxIdle time.Duration) *Worker {
+	mu.Lock()
+	defer mu.Unlock()
+
+	worker := &Worker{name: name, maxIdle: maxIdle}
+	workers[name] = worker
+	return worker
+}
+
+// UnregisterWorker удаляет фоновую задачу из проверки готовности, когда задача
+// завершила работу и больше не нужна сервису
+func UnregisterWorker(worker *Worker) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if workers[worker.name] == worker {
+		delete(workers, worker.name)
+	}
+}
+
+// Start отмечает, что фоновая задача запущена
+func (w *Worker) Start() {
+	w.lastBeat.Store(time.Now().UnixNano())
+	w.running.Store(true)
+}
+
+// Beat отмечает, что фоновая задача работает
+func (w *Worker) Beat() {
+	w.lastBeat.Store(time.Now().UnixNano())
+}
+
+// Stop отмечает, что фоновая задача остановлена
+func (w *Worker) Stop() {
+	w.running.Store(false)
+}
+
+// check проверяет, что фоновая задача запущена и не зависла
+func (w *Worker) check() error {
+	if !w.running.Load() {
+		return fmt.Errorf("worker %s is not running", w.name)
+	}
+	if w.maxIdle > 0 {
+		if idle := time.Since(time.Unix(0, w.lastBeat.Load())); idle > w.maxIdle {
+			return fmt.Errorf("worker %s has not reported for %s", w.name, idle.Round(time.Second))
+		}
+	}
+	return nil
+}
+
+// ComponentReport результат проверки одного компонента
+type ComponentReport struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report результат проверки готовности сервиса
+type Report struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentReport `json:"components"`
+}
+
+// Ready выполняет все проверки параллельно, каждую с ограничением по времени timeout
+func Ready(ctx context.Context, timeout time.Duration) Report {
+	mu.RLock()
+	allChecks := make(map[string]CheckFunc, len(checks)+2)
+	for name, check := range checks {
+		allChecks[name] = check
+	}
+	workerList := make([]*Worker, 0, len(workers))
+	for _, worker := range workers {
+		workerList = append(workerList, worker)
+	}
+	mu.RUnlock()
+
+	// Фоновые задачи проверяются как один компонент
+	sort.Slice(workerList, func(i, j int) bool { return workerList[i].name < workerList[j].name })
+	allChecks["workers"] = func(ctx context.Context) error {
+		for _, worker := range workerList {
+			if err := worker.check(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	allChecks["shutdown"] = func(ctx context.Context) error {
+		if Draining() {
+			return fmt.Errorf("service is shutting down")
+		}
+		return nil
+	}
+
+	report := Report{Status: StatusOK, Components: make(map[string]ComponentReport, len(allChecks))}
+
+	var wg sync.WaitGroup
+	var reportMu sync.Mutex
+	for name, check := range allChecks {
+		wg.Add(1)
+		go func(name string, check CheckFunc) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check(checkCtx)
+			component := ComponentReport{
+				Status:    StatusOK,
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				component.Status = StatusFail
+				component.Error = err.Error()
+			}
+
+			reportMu.Lock()
+			report.Components[name] = component
+			if err != nil {
+				report.Status = StatusFail
+			}
+			reportMu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	return report
+}
@@ -0,0 +1,345 @@
+// Package loadtest нагрузочный генератор для приема ответов и подсчета результатов.
+// Run готовит опросы (генератором с фиксированным seed или по списку идентификаторов),
+// затем Concurrency исполнителей отправляют POST /poll/:id/answer и GET /poll/:id/results
+// через pkg/client, пока не истечет Duration или не будет выполнено Requests запросов.
+// Отчет содержит пропускную способность и процентили задержки по каждой операции.
+//
+//	c, _ := client.New("http://localhost:5000", client.WithRetry(client.RetryPolicy{MaxAttempts: 1}))
+//	cfg := loadtest.DefaultConfig()
+//	cfg.Concurrency = 50
+//	report, err := loadtest.Run(ctx, c, cfg)
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"service-poll/pkg/api"
+	"service-poll/pkg/client"
+	"service-poll/pkg/fixtures"
+)
+
+// Операции нагрузочного теста
+const (
+	OperationAnswer  = "answer"  // POST /poll/:id/answer
+	OperationResults = "results" // GET /poll/:id/results
+	OperationTotal   = "total"   // все запросы вместе
+)
+
+// errorNetwork код ошибок, для которых сервис не вернул ответ
+const errorNetwork = "network"
+
+// Config параметры нагрузки
+type Config struct {
+	Concurrency int           // количество одновременно работающих исполнителей
+	Duration    time.Duration // продолжительность теста; 0 — пока не выполнено Requests запросов
+	Requests    int           // общее количество запросов; 0 — пока не истечет Duration
+	// Доля запросов результатов среди всех запросов (0-1)
+	ReadRatio float64
+	// Seed задает выбор опросов, вариантов ответа и операций, а также генерацию опросов
+	Seed int64
+	// Опросы, на которые отвечают исполнители; если не заданы, они создаются через POST /seed
+	// с параметрами Polls, Questions и Options
+	PollIDs   []uint
+	Polls     int
+	Questions int
+	Options   int
+	// Идентификатор первого пользователя; каждый ответ отправляется от нового пользователя
+	FirstUserID uint
+}
+
+// DefaultConfig возвращает параметры по умолчанию: 10 исполнителей в течение 30 секунд,
+// каждый пятый запрос — результаты одного из трех новых опросов
+func DefaultConfig() Config {
+	return Config{
+		Concurrency: 10,
+		Duration:    30 * time.Second,
+		ReadRatio:   0.2,
+		Seed:        1,
+		Polls:       3,
+		Questions:   3,
+		Options:     4,
+		FirstUserID: 1_000_000,
+	}
+}
+
+// validate проверяет параметры нагрузки
+func (cfg Config) validate() error {
+	switch {
+	case cfg.Concurrency < 1:
+		return errors.New("loadtest: concurrency must be at least 1")
+	case cfg.Duration <= 0 && cfg.Requests <= 0:
+		return errors.New("loadtest: duration or number of requests is required")
+	case cfg.Duration < 0 || cfg.Requests < 0:
+		return errors.New("loadtest: duration and number of requests must not be negative")
+	case cfg.ReadRatio < 0 || cfg.ReadRatio > 1:
+		return errors.New("loadtest: read ratio must be between 0 and 1")
+	case cfg.FirstUserID == 0:
+		return errors.New("loadtest: first user ID must be positive")
+	}
+	return nil
+}
+
+// Report результаты нагрузочного теста
+type Report struct {
+	Concurrency int               `json:"concurrency"`
+	ElapsedMs   float64           `json:"elapsed_ms"`
+	Polls       []uint            `json:"polls"`
+	Operations  []OperationReport `json:"operations"` // answer, results и total
+}
+
+// OperationReport количество запросов операции, ошибки, пропускная способность и задержки.
+// Задержки считаются по всем завершенным запросам, включая ошибки.
+type OperationReport struct {
+	Operation  string         `json:"operation"`
+	Requests   int            `json:"requests"`
+	Errors     int            `json:"errors"`
+	ErrorCodes map[string]int `json:"error_codes,omitempty"` // код ошибки сервиса или network
+	Throughput float64        `json:"throughput"`            // запросов в секунду
+	MeanMs     float64        `json:"mean_ms"`
+	P50Ms      float64        `json:"p50_ms"`
+	P90Ms      float64        `json:"p90_ms"`
+	P95Ms      float64        `json:"p95_ms"`
+	P99Ms      float64        `json:"p99_ms"`
+	MaxMs      float64        `json:"max_ms"`
+}
+
+// target опрос, на который отвечают исполнители
+type target struct {
+	id        uint
+	questions []api.PollQuestion
+}
+
+// sample завершенный запрос
+type sample struct {
+	operation string
+	latency   time.Duration
+	code      string // пусто, если запрос успешен
+}
+
+// Run готовит опросы и нагружает сервис по параметрам cfg. Запросы, прерванные
+// отменой ctx или окончанием Duration, в отчет не попадают.
+func Run(ctx context.Context, c *client.Client, cfg Config) (Report, error) {
+	if err := cfg.validate(); err != nil {
+		return Report{}, err
+	}
+
+	targets, err := prepare(ctx, c, cfg)
+	if err != nil {
+		return Report{}, err
+	}
+
+	runCtx := ctx
+	if cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, cfg.Duration)
+		defer cancel()
+	}
+
+	var (
+		issued  atomic.Int64 // выданные исполнителям запросы, для ограничения Requests
+		users   atomic.Uint64
+		wg      sync.WaitGroup
+		samples = make([][]sample, cfg.Concurrency)
+	)
+	start := time.Now()
+	for worker := 0; worker < cfg.Concurrency; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+
+			// У каждого исполнителя свой генератор: rand.Rand не безопасен для горутин
+			rng := rand.New(rand.NewSource(cfg.Seed + int64(worker)))
+			for runCtx.Err() == nil {
+				if cfg.Requests > 0 && issued.Add(1) > int64(cfg.Requests) {
+					return
+				}
+
+				poll := targets[rng.Intn(len(targets))]
+				s := sample{operation: OperationResults}
+				requestStart := time.Now()
+				var err error
+				if rng.Float64() < cfg.ReadRatio {
+					_, err = c.GetPollResults(runCtx, poll.id)
+				} else {
+					s.operation = OperationAnswer
+					userID := cfg.FirstUserID + uint(users.Add(1)-1)
+					_, err = c.RegisterAnswer(runCtx, poll.id, answer(rng, poll, userID))
+				}
+				s.latency = time.Since(requestStart)
+
+				if err != nil && runCtx.Err() != nil {
+					return
+				}
+				if err != nil {
+					s.code = errorCode(err)
+				}
+				samples[worker] = append(samples[worker], s)
+			}
+		}(worker)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	report := Report{Concurrency: cfg.Concurrency, ElapsedMs: milliseconds(elapsed)}
+	for _, poll := range targets {
+		report.Polls = append(report.Polls, poll.id)
+	}
+	var all []sample
+	for _, workerSamples := range samples {
+		all = append(all, workerSamples...)
+	}
+	for _, operation := range []string{OperationAnswer, OperationResults} {
+		report.Operations = append(report.Operations, summarize(operation, all, elapsed, func(s sample) bool {
+			return s.operation == operation
+		}))
+	}
+	report.Operations = append(report.Operations, summarize(OperationTotal, all, elapsed, func(sample) bool { return true }))
+
+	if ctx.Err() != nil {
+		return report, ctx.Err()
+	}
+	return report, nil
+}
+
+// prepare загружает вопросы опросов cfg.PollIDs или создает опросы генератором без ответов
+func prepare(ctx context.Context, c *client.Client, cfg Config) ([]target, error) {
+	pollIDs := cfg.PollIDs
+	if len(pollIDs) == 0 {
+		seed := api.SeedRequest{
+			Seed:          cfg.Seed,
+			Polls:         cfg.Polls,
+			Questions:     cfg.Questions,
+			Options:       cfg.Options,
+			FirstUserID:   cfg.FirstUserID,
+			MultipleRatio: fixtures.DefaultGenerator().MultipleRatio,
+			ResponseRate:  1,
+			Distribution:  fixtures.DistributionUniform,
+		}
+		seeded, err := c.Seed(ctx, seed)
+		if err != nil {
+			return nil, fmt.Errorf("loadtest: create polls: %w", err)
+		}
+		for _, poll := range seeded.Polls {
+			pollIDs = append(pollIDs, poll.ID)
+		}
+	}
+
+	targets := make([]target, 0, len(pollIDs))
+	for _, pollID := range pollIDs {
+		poll, err := c.GetPoll(ctx, pollID)
+		if err != nil {
+			return nil, fmt.Errorf("loadtest: load poll %d: %w", pollID, err)
+		}
+		if len(poll.Questions) == 0 {
+			return nil, fmt.Errorf("loadtest: poll %d has no questions", pollID)
+		}
+		for _, question := range poll.Questions {
+			if len(question.PossibleAnswers) == 0 || question.Type == fixtures.Multiple && len(question.PossibleAnswers) < 2 {
+				return nil, fmt.Errorf("loadtest: question %d of poll %d has too few options", question.ID, pollID)
+			}
+		}
+		targets = append(targets, target{id: poll.ID, questions: poll.Questions})
+	}
+	return targets, nil
+}
+
+// answer составляет ответ пользователя на все вопросы опроса: один случайный вариант
+// на вопрос single и два разных на вопрос multiple
+func answer(rng *rand.Rand, poll target, userID uint) api.AnswerAttributes {
+	attributes := api.AnswerAttributes{
+		AccessToken: fmt.Sprintf("load-%d", userID),
+		UserID:      userID,
+		Results:     make([]api.AnswerResult, 0, len(poll.questions)),
+	}
+	for _, question := range poll.questions {
+		options := question.PossibleAnswers
+		choice := options[rng.Intn(len(options))].Text
+		if question.Type == fixtures.Multiple {
+			picks := rng.Perm(len(options))[:2]
+			choice = options[picks[0]].Text + "," + options[picks[1]].Text
+		}
+		attributes.Results = append(attributes.Results, api.AnswerResult{Question: question.Text, Answer: choice})
+	}
+	return attributes
+}
+
+// errorCode возвращает код ошибки сервиса или network, если ответа нет
+func errorCode(err error) string {
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) {
+		return errorNetwork
+	}
+	if apiErr.Problem.Code != "" {
+		return string(apiErr.Problem.Code)
+	}
+	return fmt.Sprintf("http_%d", apiErr.StatusCode)
+}
+
+// summarize собирает отчет по запросам, для которых match возвращает true
+func summarize(operation string, samples []sample, elapsed time.Duration, match func(sample) bool) OperationReport {
+	report := OperationReport{Operation: operation}
+
+	var latencies []time.Duration
+	var total time.Duration
+	for _, s := range samples {
+		if !match(s) {
+			continue
+		}
+		latencies = append(latencies, s.latency)
+		total += s.latency
+		if s.code != "" {
+			if report.ErrorCodes == nil {
+				report.ErrorCodes = make(map[string]int)
+			}
+			report.Errors++
+			report.ErrorCodes[s.code]++
+		}
+	}
+	report.Requests = len(latencies)
+	if report.Requests == 0 {
+		return report
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	if elapsed > 0 {
+		report.Throughput = float64(report.Requests) / elapsed.Seconds()
+	}
+	report.MeanMs = milliseconds(total / time.Duration(report.Requests))
+	report.P50Ms = milliseconds(Percentile(latencies, 50))
+	report.P90Ms = milliseconds(Percentile(latencies, 90))
+	report.P95Ms = milliseconds(Percentile(latencies, 95))
+	report.P99Ms = milliseconds(Percentile(latencies, 99))
+	report.MaxMs = milliseconds(latencies[len(latencies)-1])
+	return report
+}
+
+// Percentile возвращает процентиль p (0-100) отсортированных по возрастанию задержек
+// методом ближайшего ранга: наименьшее значение, не меньше которого p% значений
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p / 100 * float64(len(sorted)))
+	if float64(rank) < p/100*float64(len(sorted)) {
+		rank++
+	}
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+// milliseconds переводит продолжительность в миллисекунды, как в отчетах сервиса
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,53 @@
+package loadtest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	latencies := make([]time.Duration, 100)
+	for i := range latencies {
+		latencies[i] = time.Duration(i+1) * time.Millisecond
+	}
+
+	for _, tc := range []struct {
+		sorted []time.Duration
+		p      float64
+		want   time.Duration
+	}{
+		{latencies, 50, 50 * time.Millisecond},
+		{latencies, 99, 99 * time.Millisecond},
+		{latencies, 99.5, 100 * time.Millisecond},
+		{latencies, 100, 100 * time.Millisecond},
+		{latencies, 0, 1 * time.Millisecond},
+		{latencies[:3], 50, 2 * time.Millisecond},
+		{latencies[:1], 99, 1 * time.Millisecond},
+		{nil, 50, 0},
+	} {
+		if got := Percentile(tc.sorted, tc.p); got != tc.want {
+			t.Errorf("Percentile(%d values, %v) = %v, want %v", len(tc.sorted), tc.p, got, tc.want)
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	valid := DefaultConfig()
+	if err := valid.validate(); err != nil {
+		t.Fatalf("default config: %v", err)
+	}
+
+	for name, change := range map[string]func(*Config){
+		"no workers":         func(cfg *Config) { cfg.Concurrency = 0 },
+		"no limit":           func(cfg *Config) { cfg.Duration, cfg.Requests = 0, 0 },
+		"negative requests":  func(cfg *Config) { cfg.Requests = -1 },
+		"read ratio above 1": func(cfg *Config) { cfg.ReadRatio = 1.5 },
+		"zero first user":    func(cfg *Config) { cfg.FirstUserID = 0 },
+	} {
+		cfg := DefaultConfig()
+		change(&cfg)
+		if err := cfg.validate(); err == nil {
+			t.Errorf("%s: validate() = nil, want error", name)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// GormLogger направляет журнал SQL-запросов GORM в slog.
+// Запросы пишутся на уровне debug, медленные запросы — на уровне warn, ошибки — на уровне error.
+type GormLogger struct {
+	// SlowThreshold порог, начиная с которого запрос считается медленным (0 — не отслеживать)
+	SlowThreshold time.Duration
+	level         gormlogger.LogLevel
+}
+
+// NewGormLogger создает журнал SQL-запросов GORM с указанным порогом медленных запросов
+func NewGormLogger(slowThreshold time.Duration) *GormLogger {
+	return &GormLogger{SlowThreshold: slowThreshold, level: gormlogger.Info}
+}
+
+// LogMode возвращает копию журнала с указанным уровнем GORM
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	copied := *l
+	copied.level = level
+	return &copied
+}
+
+func (l *GormLogger) Info(ctx context.Context, message string, args ...interface{}) {
+	if l.level >= gormlogger.Info {
+		FromContext(ctx).InfoContext(ctx, fmt.Sprintf(message, args...))
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, message string, args ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		FromContext(ctx).WarnContext(ctx, fmt.Sprintf(message, args...))
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, message string, args ...interface{}) {
+	if l.level >= gormlogger.Error {
+		FromContext(ctx).ErrorContext(ctx, fmt.Sprintf(message, args...))
+	}
+}
+
+// Trace пишет в журнал выполненный запрос
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	log := FromContext(ctx)
+
+	switch {
+	// Отсутствие записи — штатная ситуация, обработчики отвечают на нее 404
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= gormlogger.Error:
+		sql, rows := fc()
+		log.LogAttrs(ctx, slog.LevelError, "sql query failed",
+			slog.String("sql", sql), slog.Int64("rows", rows), milliseconds("elapsed_ms", elapsed), slog.String("error", err.Error()))
+	case l.SlowThreshold > 0 && elapsed > l.SlowThreshold && l.level >= gormlogger.Warn:
+		sql, rows := fc()
+		log.LogAttrs(ctx, slog.LevelWarn, "slow sql query",
+			slog.String("sql", sql), slog.Int64("rows", rows), milliseconds("elapsed_ms", elapsed), milliseconds("threshold_ms", l.SlowThreshold))
+	case l.level >= gormlogger.Info && log.Enabled(ctx, slog.LevelDebug):
+		sql, rows := fc()
+		log.LogAttrs(ctx, slog.LevelDebug, "sql query",
+			slog.String("sql", sql), slog.Int64("rows", rows), milliseconds("elapsed_ms", elapsed))
+	}
+}
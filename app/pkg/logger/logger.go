@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ParseLevel переводит уровень журналирования из настроек в slog.Level
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("unknown log level %q", level)
+}
+
+// New создает структурированный логгер, пишущий JSON в w
+func New(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level}))
+}
+
+// Init настраивает логгер по умолчанию: slog, стандартный log и вывод gin
+// пишут JSON в stdout с указанным уровнем
+func Init(level string) (*slog.Logger, error) {
+	slogLevel, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	l := New(os.Stdout, slogLevel)
+	slog.SetDefault(l)
+
+	// Сообщения стандартного log и gin (например, о регистрации маршрутов) идут через slog
+	log.SetFlags(0)
+	log.SetOutput(slogWriter{logger: l, level: slog.LevelInfo})
+	gin.DefaultWriter = slogWriter{logger: l, level: slog.LevelDebug}
+	gin.DefaultErrorWriter = slogWriter{logger: l, level: slog.LevelError}
+
+	return l, nil
+}
+
+// slogWriter перенаправляет построчный текстовый вывод в slog
+type slogWriter struct {
+	logger *slog.Logger
+	level  slog.Level
+}
+
+func (w slogWriter) Write(p []byte) (int, error) {
+	message := strings.TrimSpace(string(p))
+	if message != "" {
+		w.logger.Log(context.Background(), w.level, message)
+	}
+	return len(p), nil
+}
+
+// contextKey ключ логгера в context.Context
+type contextKey struct{}
+
+// WithContext возвращает контекст с логгером запроса
+func WithContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext возвращает логгер запроса из контекста или логгер по умолчанию
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// milliseconds записывает длительность в миллисекундах
+func milliseconds(key string, d time.Duration) slog.Attr {
+	return slog.Float64(key, float64(d.Microseconds())/1000)
+}
@@ -0,0 +1,91 @@
+package logger
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"service-poll/pkg/apperror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader заголовок с идентификатором запроса
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey ключ идентификатора запроса в gin.Context
+const requestIDKey = "request_id"
+
+// maxRequestIDLength ограничивает длину идентификатора, переданного клиентом
+const maxRequestIDLength = 128
+
+// NewRequestID генерирует случайный идентификатор запроса
+func NewRequestID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buf)
+}
+
+// RequestID берет идентификатор запроса из заголовка X-Request-ID или генерирует новый,
+// возвращает его в ответе и кладет в контекст запроса логгер с этим идентификатором
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = NewRequestID()
+		}
+
+		c.Set(requestIDKey, requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		requestLogger := slog.Default().With(slog.String("request_id", requestID))
+		c.Request = c.Request.WithContext(WithContext(c.Request.Context(), requestLogger))
+
+		c.Next()
+	}
+}
+
+// GetRequestID возвращает идентификатор текущего запроса
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// Middleware пишет в журнал каждый запрос: метод, маршрут, статус и длительность
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		} else if status >= http.StatusBadRequest {
+			level = slog.LevelWarn
+		}
+
+		FromContext(c.Request.Context()).LogAttrs(c.Request.Context(), level, "request",
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			milliseconds("latency_ms", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+			slog.Int("bytes", c.Writer.Size()),
+		)
+	}
+}
+
+// Recovery перехватывает панику в обработчике, пишет ее в журнал и отвечает 500
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, recovered any) {
+		FromContext(c.Request.Context()).Error("panic recovered", slog.Any("panic", recovered))
+		problem := apperror.New(http.StatusInternalServerError, apperror.CodeInternal, "Internal server error")
+		c.Header("Content-Type", apperror.ContentType)
+		c.AbortWithStatusJSON(problem.Status, problem.Problem(c.Request.URL.Path, GetRequestID(c)))
+	})
+}
@@ -0,0 +1,222 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace общий префикс метрик сервиса
+const namespace = "service_poll"
+
+// Registry реестр метрик сервиса, который отдается на /metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Database query latency by operation and table.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+
+	answersRegistered = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "answers_registered_total",
+		Help:      "Number of registered answers (one per question) by poll and mode: sync (saved with the request) or async (saved by the ingest worker). Series of a deleted poll are removed.",
+	}, []string{"poll_id", "mode"})
+
+	answerValidationFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "answer_validation_failures_total",
+		Help:      "Number of rejected answer submissions by reason.",
+	}, []string{"reason"})
+
+	ingestQueueLength = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ingest_queue_length",
+		Help:      "Number of asynchronously accepted submissions waiting to be saved.",
+	})
+
+	ingestSubmissions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ingest_submissions_total",
+		Help:      "Number of asynchronous answer submissions by outcome: accepted, rejected (queue full), persisted or failed.",
+	}, []string{"outcome"})
+
+	ingestBatchSize = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ingest_batch_size",
+		Help:      "Number of submissions saved in one transaction by the ingest worker.",
+		Buckets:   prometheus.ExponentialBuckets(1, 4, 7),
+	})
+
+	cacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Number of poll and results cache lookups by cache and result (hit or miss).",
+	}, []string{"cache", "result"})
+
+	counterDrift = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "result_counter_drift_total",
+		Help:      "Number of result counters found to differ from recounted answers and corrected, by counter (question or option).",
+	}, []string{"counter"})
+
+	countersReconciled = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "result_counters_reconciled_timestamp_seconds",
+		Help:      "Unix time of the last successful reconciliation of result counters with answers.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpRequestDuration,
+		dbQueryDuration,
+		answersRegistered,
+		answerValidationFailures,
+		ingestQueueLength,
+		ingestSubmissions,
+		ingestBatchSize,
+		cacheRequests,
+		counterDrift,
+		countersReconciled,
+	)
+}
+
+// Handler отдает метрики в формате Prometheus
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// Middleware считает HTTP-запросы и их длительность по маршрутам gin
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		// Маршрут берется из шаблона, чтобы идентификаторы не попадали в метки
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		httpRequests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		httpRequestDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// RegisterDBStats добавляет метрики пула соединений с базой данных
+func RegisterDBStats(sqlDB *sql.DB) error {
+	return Registry.Register(collectors.NewDBStatsCollector(sqlDB, "service_poll"))
+}
+
+// Способы сохранения ответов
+const (
+	AnswersSync  = "sync"  // ответы сохранены при обработке запроса
+	AnswersAsync = "async" // ответы сохранены фоновым обработчиком асинхронного приема
+)
+
+// AnswersRegistered учитывает ответы, сохраненные в опросе указанным способом
+func AnswersRegistered(pollID uint, mode string, count int) {
+	answersRegistered.WithLabelValues(strconv.FormatUint(uint64(pollID), 10), mode).Add(float64(count))
+}
+
+// ForgetPoll удаляет ряды метрик удаленного опроса, чтобы их количество ограничивалось
+// существующими опросами. Ряды удаляются только в экземпляре сервиса, который удалил опрос;
+// в остальных они пропадут при перезапуске.
+func ForgetPoll(pollID uint) {
+	answersRegistered.DeletePartialMatch(prometheus.Labels{"poll_id": strconv.FormatUint(uint64(pollID), 10)})
+}
+
+// Причины отклонения ответов в RegisterAnswer
+const (
+	ReasonInvalidRequest   = "invalid_request"
+	ReasonPollNotFound     = "poll_not_found"
+	ReasonQuestionNotFound = "question_not_found"
+	ReasonSingleChoice     = "single_choice_violation"
+	ReasonMultipleChoice   = "multiple_choice_violation"
+	ReasonOptionNotFound   = "option_not_found"
+)
+
+// AnswerValidationFailed учитывает отклоненный ответ
+func AnswerValidationFailed(reason string) {
+	answerValidationFailures.WithLabelValues(reason).Inc()
+}
+
+// Исходы асинхронно принятых ответов
+const (
+	IngestAccepted  = "accepted"
+	IngestRejected  = "rejected"
+	IngestPersisted = "persisted"
+	IngestFailed    = "failed"
+)
+
+// IngestSubmissions учитывает асинхронно принятые ответы с указанным исходом
+func IngestSubmissions(outcome string, count int) {
+	ingestSubmissions.WithLabelValues(outcome).Add(float64(count))
+}
+
+// IngestQueueLength запоминает количество ответов в очереди на сохранение
+func IngestQueueLength(length int) {
+	ingestQueueLength.Set(float64(length))
+}
+
+// IngestBatch учитывает пачку ответов, сохраненную фоновым обработчиком
+func IngestBatch(size int) {
+	ingestBatchSize.Observe(float64(size))
+}
+
+// Кэши сервиса
+const (
+	CachePoll    = "poll"    // описания опросов
+	CacheResults = "results" // результаты опросов
+)
+
+// CacheRequest учитывает обращение к кэшу: hit — значение найдено в кэше
+func CacheRequest(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheRequests.WithLabelValues(cache, result).Inc()
+}
+
+// Счетчики результатов
+const (
+	CounterQuestion = "question" // количество ответов на вопрос
+	CounterOption   = "option"   // количество голосов за вариант ответа
+)
+
+// CounterDrift учитывает счетчик результатов, исправленный при сверке с ответами
+func CounterDrift(counter string) {
+	counterDrift.WithLabelValues(counter).Inc()
+}
+
+// CountersReconciled запоминает время успешной сверки счетчиков результатов
+func CountersReconciled(at time.Time) {
+	countersReconciled.Set(float64(at.UnixNano()) / 1e9)
+}
@@ -16,6 +16,7 @@ type Answer struct {
 	AccessToken     string
 	QuestionID      uint
 	PollID          uint
+	RevisionID      *uint            `gorm:"index"` // ревизия опроса, по которой был дан ответ
 	PossibleAnswers []PossibleAnswer `gorm:"many2many:answer_possible_answers"`
 }
 
@@ -24,5 +25,6 @@ type PossibleAnswer struct {
 	gorm.Model
 	Text       string `gorm:"not null"`
 	QuestionID uint
+	Position   int      `gorm:"not null;default:0"` // порядок варианта в вопросе
 	Answers    []Answer `gorm:"many2many:answer_possible_answers"`
 }
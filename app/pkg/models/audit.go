@@ -0,0 +1,17 @@
+package models
+
+import "gorm.io/gorm"
+
+// AuditLog таблица журнала изменений опросов, вопросов и шаблонов.
+// У записей шаблонов PollID равен 0, а TemplateID указывает на шаблон.
+type AuditLog struct {
+	gorm.Model
+	PollID     uint   `gorm:"index;not null"`
+	QuestionID *uint  `gorm:"index"`
+	TemplateID *uint  `gorm:"index"`
+	Actor      string `gorm:"not null"`
+	Method     string `gorm:"not null"`
+	Endpoint   string `gorm:"not null"`
+	Action     string `gorm:"not null"`  // тип изменения: "update_poll", "add_question" и т.д.
+	Diff       string `gorm:"type:text"` // JSON вида {"поле": {"before": ..., "after": ...}}
+}
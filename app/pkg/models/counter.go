@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// QuestionCounter таблица количества ответов на вопрос. Обновляется в одной транзакции
+// с ответами, поэтому результаты опроса не пересчитываются по сырым ответам.
+type QuestionCounter struct {
+	QuestionID uint `gorm:"primaryKey;autoIncrement:false"`
+	PollID     uint `gorm:"index;not null"`
+	Answers    int  `gorm:"not null;default:0"`
+	UpdatedAt  time.Time
+}
+
+// OptionCounter таблица количества голосов за вариант ответа
+type OptionCounter struct {
+	PossibleAnswerID uint `gorm:"primaryKey;autoIncrement:false"`
+	QuestionID       uint `gorm:"index;not null"`
+	Votes            int  `gorm:"not null;default:0"`
+	UpdatedAt        time.Time
+}
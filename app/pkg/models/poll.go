@@ -7,6 +7,8 @@ type Poll struct {
 	gorm.Model
 	Title     string `gorm:"not null"`
 	URL       string `gorm:"not null"`
+	Revision  uint   `gorm:"not null;default:0"` // номер последней опубликованной ревизии, 0 — опрос не публиковался
 	Questions []Question
-	Answers   []Answer `gorm:"foreignKey:PollID"`
+	Answers   []Answer       `gorm:"foreignKey:PollID"`
+	Revisions []PollRevision `gorm:"foreignKey:PollID"`
 }
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// AnswerReceipt таблица квитанций ответов, принятых асинхронно. Квитанция создается
+// в одной транзакции с ответами, поэтому запись в таблице означает, что ответы сохранены.
+type AnswerReceipt struct {
+	ID         string    `gorm:"primaryKey;size:32"`
+	PollID     uint      `gorm:"index;not null"`
+	UserID     uint      `gorm:"not null"`
+	Answers    int       `gorm:"not null"` // количество сохраненных ответов, по одному на вопрос
+	AcceptedAt time.Time `gorm:"not null"`
+	CreatedAt  time.Time // время сохранения ответов
+}
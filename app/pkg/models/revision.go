@@ -0,0 +1,33 @@
+package models
+
+import "gorm.io/gorm"
+
+// PollRevision таблица неизменяемых ревизий опроса, создаваемых при публикации
+type PollRevision struct {
+	gorm.Model
+	PollID    uint               `gorm:"uniqueIndex:idx_poll_revision;not null"`
+	Number    uint               `gorm:"uniqueIndex:idx_poll_revision;not null"`
+	Title     string             `gorm:"not null"`
+	URL       string             `gorm:"not null"`
+	Questions []RevisionQuestion `gorm:"foreignKey:RevisionID"`
+}
+
+// RevisionQuestion таблица снимков вопросов в ревизии опроса
+type RevisionQuestion struct {
+	gorm.Model
+	RevisionID uint             `gorm:"index;not null"`
+	QuestionID uint             `gorm:"not null"` // идентификатор исходного вопроса
+	Position   int              `gorm:"not null"`
+	Text       string           `gorm:"not null"`
+	Type       string           `gorm:"not null"`
+	Options    []RevisionOption `gorm:"foreignKey:RevisionQuestionID"`
+}
+
+// RevisionOption таблица снимков вариантов ответа в ревизии опроса
+type RevisionOption struct {
+	gorm.Model
+	RevisionQuestionID uint   `gorm:"index;not null"`
+	PossibleAnswerID   uint   `gorm:"not null"` // идентификатор исходного варианта ответа
+	Position           int    `gorm:"not null"`
+	Text               string `gorm:"not null"`
+}
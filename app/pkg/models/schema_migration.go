@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+// SchemaMigration таблица примененных версий схемы базы данных
+type SchemaMigration struct {
+	Version   uint `gorm:"primaryKey;autoIncrement:false"`
+	AppliedAt time.Time
+}
@@ -0,0 +1,11 @@
+package models
+
+import "gorm.io/gorm"
+
+// Template таблица шаблонов опросов
+type Template struct {
+	gorm.Model
+	Name        string `gorm:"uniqueIndex;not null"`
+	Description string
+	Definition  string `gorm:"type:text;not null"` // описание опроса в формате JSON
+}
@@ -0,0 +1,310 @@
+// Package openapi формирует описание OpenAPI 3 по списку операций и типам их запросов
+// и ответов и проверяет тела ответов на соответствие этому описанию.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"service-poll/pkg/apperror"
+)
+
+// Version версия спецификации OpenAPI, по которой строится описание
+const Version = "3.0.3"
+
+// JSON тип содержимого запросов и ответов по умолчанию
+const JSON = "application/json"
+
+// Document описание API в формате OpenAPI 3
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Servers    []Server            `json:"servers,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Info общие сведения об API
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// Server адрес, относительно которого указаны пути операций
+type Server struct {
+	URL string `json:"url"`
+}
+
+// PathItem операции одного пути по методам HTTP в нижнем регистре
+type PathItem map[string]*OperationObject
+
+// OperationObject описание одной операции
+type OperationObject struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+// Parameter параметр пути или строки запроса
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody тело запроса по типам содержимого
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response ответ операции по типам содержимого
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType схема тела для одного типа содержимого
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components именованные схемы, на которые ссылаются операции
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Schema схема значения JSON (подмножество OpenAPI 3.0, достаточное для типов сервиса).
+// AdditionalProperties — false, true или *Schema для значений словаря.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+// Operation операция API, по которой строится описание.
+// Request и Response — значения типов тела запроса и ответа 200 (nil — тела нет),
+// либо OneOf с несколькими допустимыми типами.
+type Operation struct {
+	ID      string  // идентификатор операции, например "CreatePoll"
+	Method  string  // метод HTTP
+	Path    string  // путь в формате gin, например "/poll/:id"
+	Summary string  // краткое описание для документации
+	Tag     string  // группа операций в документации
+	Query   []Param // параметры строки запроса
+	// Параметры пути, которые не являются числовыми идентификаторами
+	PathParams    []Param
+	Headers       []Param // заголовки запроса
+	Request       interface{}
+	RequestTypes  []string // типы содержимого тела запроса, по умолчанию application/json
+	Response      interface{}
+	ResponseTypes []string // типы содержимого ответа 200, по умолчанию application/json
+
+	// Ответы с кодом, отличным от 200, тело которых не является ошибкой problem+json;
+	// nil — ответ без тела, например 304
+	Responses map[int]interface{}
+
+	// Коды ошибок, которые операция возвращает в формате application/problem+json
+	Errors []int
+}
+
+// Param параметр строки запроса
+type Param struct {
+	Name        string
+	Description string
+	Schema      *Schema
+}
+
+// OneOf тело, которое соответствует ровно одному из перечисленных типов,
+// например openapi.OneOf{api.PollResultsResponse{}, api.RevisionResultsResponse{}}
+type OneOf []interface{}
+
+// Path переводит путь в формате gin ("/poll/:id") в формат OpenAPI ("/poll/{id}")
+func Path(ginPath string) string {
+	segments := strings.Split(ginPath, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// pathParams возвращает имена параметров пути в формате gin
+func pathParams(ginPath string) []string {
+	var params []string
+	for _, segment := range strings.Split(ginPath, "/") {
+		if strings.HasPrefix(segment, ":") {
+			params = append(params, segment[1:])
+		}
+	}
+	return params
+}
+
+// Build строит описание API по операциям. Пути операций указываются относительно server.
+// Схемы типов запросов строятся раньше схем ответов: поля типа запроса обязательны,
+// если у них есть правило binding:"required", поля типа ответа — если у них нет omitempty.
+// Тип, который встречается и в запросах, и в ответах, описывается по правилам запроса.
+func Build(info Info, server string, operations []Operation) (*Document, error) {
+	doc := &Document{
+		OpenAPI:    Version,
+		Info:       info,
+		Paths:      make(map[string]PathItem),
+		Components: Components{Schemas: make(map[string]*Schema)},
+	}
+	if server != "" {
+		doc.Servers = []Server{{URL: server}}
+	}
+
+	g := &generator{doc: doc, names: make(map[reflect.Type]string)}
+
+	// Сначала тела запросов, затем ответы, чтобы правила обязательности не зависели от порядка операций
+	g.request = true
+	requests := make([]*RequestBody, len(operations))
+	for i, operation := range operations {
+		if operation.Request == nil {
+			continue
+		}
+		requests[i] = &RequestBody{Required: true, Content: g.content(operation.Request, operation.RequestTypes)}
+	}
+	g.request = false
+
+	problem := g.schema(reflect.TypeOf(apperror.Problem{}))
+	seen := make(map[string]bool, len(operations))
+
+	for i, operation := range operations {
+		if operation.ID == "" {
+			return nil, fmt.Errorf("openapi: operation %s %s has no id", operation.Method, operation.Path)
+		}
+		if seen[operation.ID] {
+			return nil, fmt.Errorf("openapi: duplicate operation id %s", operation.ID)
+		}
+		seen[operation.ID] = true
+
+		path := Path(operation.Path)
+		item := doc.Paths[path]
+		if item == nil {
+			item = make(PathItem)
+			doc.Paths[path] = item
+		}
+		method := strings.ToLower(operation.Method)
+		if _, ok := item[method]; ok {
+			return nil, fmt.Errorf("openapi: duplicate operation %s %s", operation.Method, operation.Path)
+		}
+
+		object := &OperationObject{
+			OperationID: operation.ID,
+			Summary:     operation.Summary,
+			RequestBody: requests[i],
+			Responses:   make(map[string]Response),
+		}
+		if operation.Tag != "" {
+			object.Tags = []string{operation.Tag}
+		}
+
+		// Идентификаторы в пути — положительные числа, это проверяется до обработчиков;
+		// остальные параметры пути описаны в PathParams
+		for _, name := range pathParams(operation.Path) {
+			parameter := Parameter{
+				Name:     name,
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "integer", Minimum: float(1)},
+			}
+			for _, param := range operation.PathParams {
+				if param.Name == name {
+					parameter.Description = param.Description
+					parameter.Schema = param.Schema
+				}
+			}
+			object.Parameters = append(object.Parameters, parameter)
+		}
+		for _, param := range operation.Query {
+			object.Parameters = append(object.Parameters, Parameter{
+				Name:        param.Name,
+				In:          "query",
+				Description: param.Description,
+				Schema:      param.Schema,
+			})
+		}
+		for _, param := range operation.Headers {
+			object.Parameters = append(object.Parameters, Parameter{
+				Name:        param.Name,
+				In:          "header",
+				Description: param.Description,
+				Schema:      param.Schema,
+			})
+		}
+
+		ok := Response{Description: http.StatusText(http.StatusOK)}
+		if operation.Response != nil {
+			ok.Content = g.content(operation.Response, operation.ResponseTypes)
+		}
+		object.Responses[strconv.Itoa(http.StatusOK)] = ok
+
+		for status, body := range operation.Responses {
+			response := Response{Description: http.StatusText(status)}
+			if body != nil {
+				response.Content = g.content(body, nil)
+			}
+			object.Responses[strconv.Itoa(status)] = response
+		}
+
+		for _, status := range operation.Errors {
+			object.Responses[strconv.Itoa(status)] = Response{
+				Description: http.StatusText(status),
+				Content:     map[string]MediaType{apperror.ContentType: {Schema: problem}},
+			}
+		}
+
+		item[method] = object
+	}
+
+	return doc, nil
+}
+
+// Operation возвращает описание операции по методу и пути в формате gin
+func (d *Document) Operation(method, ginPath string) (*OperationObject, bool) {
+	object, ok := d.Paths[Path(ginPath)][strings.ToLower(method)]
+	return object, ok
+}
+
+// Methods возвращает пары "МЕТОД путь" всех операций описания в формате OpenAPI
+func (d *Document) Methods() []string {
+	var methods []string
+	for path, item := range d.Paths {
+		for method := range item {
+			methods = append(methods, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// float возвращает указатель на число для полей схемы
+func float(value float64) *float64 {
+	return &value
+}
@@ -0,0 +1,212 @@
+package openapi
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	deletedAtType  = reflect.TypeOf(gorm.DeletedAt{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	marshalerType  = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// generator строит схемы типов Go и складывает именованные структуры в компоненты описания
+type generator struct {
+	doc     *Document
+	names   map[reflect.Type]string
+	request bool // схемы строятся для тел запросов
+}
+
+// content возвращает тело запроса или ответа для каждого типа содержимого
+func (g *generator) content(body interface{}, types []string) map[string]MediaType {
+	if len(types) == 0 {
+		types = []string{JSON}
+	}
+
+	var schema *Schema
+	if variants, ok := body.(OneOf); ok {
+		schema = &Schema{}
+		for _, variant := range variants {
+			schema.OneOf = append(schema.OneOf, g.schema(reflect.TypeOf(variant)))
+		}
+	} else {
+		schema = g.schema(reflect.TypeOf(body))
+	}
+
+	content := make(map[string]MediaType, len(types))
+	for _, contentType := range types {
+		content[contentType] = MediaType{Schema: schema}
+	}
+	return content
+}
+
+// schema возвращает схему значения типа t так, как его записывает encoding/json
+func (g *generator) schema(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case deletedAtType:
+		return &Schema{Type: "string", Format: "date-time", Nullable: true}
+	case rawMessageType:
+		// Произвольное значение JSON
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		elem := g.schema(t.Elem())
+		if elem.Ref != "" {
+			// Соседние с $ref поля не учитываются, поэтому ссылка оборачивается в allOf
+			return &Schema{AllOf: []*Schema{elem}, Nullable: true}
+		}
+		elem.Nullable = true
+		return elem
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: float(0)}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		// Пустой срез записывается как null
+		return &Schema{Type: "array", Items: g.schema(t.Elem()), Nullable: true}
+	case reflect.Array:
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		return g.ref(t)
+	}
+
+	// interface{} и прочие типы — произвольное значение
+	return &Schema{}
+}
+
+// ref возвращает ссылку на схему именованной структуры, при первом обращении добавляя ее в компоненты.
+// Имя компонента включает имя пакета: "api.PollResponse", "models.Poll".
+func (g *generator) ref(t reflect.Type) *Schema {
+	name, ok := g.names[t]
+	if !ok {
+		name = path.Base(t.PkgPath()) + "." + t.Name()
+		g.names[t] = name
+		// Имя регистрируется до обхода полей, чтобы рекурсивные типы ссылались сами на себя
+		g.doc.Components.Schemas[name] = nil
+		g.doc.Components.Schemas[name] = g.object(t)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// object возвращает схему объекта со всеми полями структуры, включая поля встроенных структур
+func (g *generator) object(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.fields(t, schema)
+
+	// Тип с собственной сериализацией может дописывать поля, например Problem
+	schema.AdditionalProperties = t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType)
+	return schema
+}
+
+// fields добавляет в схему поля структуры t
+func (g *generator) fields(t reflect.Type, schema *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" && options == "" {
+			continue
+		}
+
+		// Поля встроенной структуры без имени в JSON записываются на одном уровне с остальными
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				g.fields(embedded, schema)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := g.schema(field.Type)
+		rules := strings.Split(field.Tag.Get("binding"), ",")
+		if property.Ref == "" {
+			applyRules(property, rules)
+		}
+		schema.Properties[name] = property
+
+		omitempty := strings.Contains(","+options+",", ",omitempty,")
+		if contains(rules, "required") || (!g.request && !omitempty) {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// applyRules переносит в схему правила проверки gin (oneof, min, max)
+func applyRules(schema *Schema, rules []string) {
+	for _, rule := range rules {
+		if rule == "dive" {
+			return
+		}
+
+		key, value, _ := strings.Cut(rule, "=")
+		switch key {
+		case "oneof":
+			schema.Enum = strings.Fields(value)
+		case "min":
+			number, err := strconv.Atoi(value)
+			if err != nil {
+				continue
+			}
+			if schema.Type == "array" {
+				schema.MinItems = &number
+			} else if schema.Type == "integer" || schema.Type == "number" {
+				schema.Minimum = float(float64(number))
+			}
+		case "max":
+			number, err := strconv.Atoi(value)
+			if err != nil {
+				continue
+			}
+			if schema.Type == "integer" || schema.Type == "number" {
+				schema.Maximum = float(float64(number))
+			}
+		}
+	}
+}
+
+// contains сообщает, есть ли значение в списке
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
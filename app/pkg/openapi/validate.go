@@ -0,0 +1,206 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ResponseSchema возвращает схему тела ответа операции с указанным кодом и типом содержимого.
+// Ошибка означает, что такой ответ в описании не задокументирован.
+func (d *Document) ResponseSchema(method, ginPath string, status int, contentType string) (*Schema, error) {
+	object, ok := d.Operation(method, ginPath)
+	if !ok {
+		return nil, fmt.Errorf("operation %s %s is not documented", method, Path(ginPath))
+	}
+
+	response, ok := object.Responses[strconv.Itoa(status)]
+	if !ok {
+		return nil, fmt.Errorf("%s %s: status %d is not documented", method, Path(ginPath), status)
+	}
+
+	// Параметры типа содержимого, например charset, не учитываются
+	mediaType := strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+	if len(response.Content) == 0 {
+		return nil, nil
+	}
+	content, ok := response.Content[mediaType]
+	if !ok {
+		return nil, fmt.Errorf("%s %s: content type %q is not documented for status %d", method, Path(ginPath), mediaType, status)
+	}
+	return content.Schema, nil
+}
+
+// Validate проверяет документ JSON на соответствие схеме. Ссылки разрешаются по компонентам описания.
+func (d *Document) Validate(schema *Schema, data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return d.validate(schema, value, "$")
+}
+
+// validate проверяет значение value, расположенное по пути path
+func (d *Document) validate(schema *Schema, value interface{}, path string) error {
+	if schema.Ref != "" {
+		name := strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+		target, ok := d.Components.Schemas[name]
+		if !ok || target == nil {
+			return fmt.Errorf("%s: unknown schema %s", path, schema.Ref)
+		}
+		return d.validate(target, value, path)
+	}
+
+	if value == nil {
+		if schema.Nullable || (schema.Type == "" && len(schema.AllOf) == 0 && len(schema.OneOf) == 0) {
+			return nil
+		}
+		return fmt.Errorf("%s: must not be null", path)
+	}
+
+	for _, part := range schema.AllOf {
+		if err := d.validate(part, value, path); err != nil {
+			return err
+		}
+	}
+
+	if len(schema.OneOf) > 0 {
+		var matched int
+		var errs []string
+		for _, variant := range schema.OneOf {
+			if err := d.validate(variant, value, path); err != nil {
+				errs = append(errs, err.Error())
+				continue
+			}
+			matched++
+		}
+		if matched != 1 {
+			return fmt.Errorf("%s: must match exactly one schema, matched %d (%s)", path, matched, strings.Join(errs, "; "))
+		}
+	}
+
+	switch schema.Type {
+	case "object":
+		return d.validateObject(schema, value, path)
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: must be an array", path)
+		}
+		if schema.MinItems != nil && len(items) < *schema.MinItems {
+			return fmt.Errorf("%s: must contain at least %d item(s)", path, *schema.MinItems)
+		}
+		for i, item := range items {
+			if err := d.validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: must be a string", path)
+		}
+		return validateString(schema, text, path)
+	case "integer", "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s: must be of type %s", path, schema.Type)
+		}
+		return validateNumber(schema, number, path)
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: must be a boolean", path)
+		}
+	}
+
+	return nil
+}
+
+// validateObject проверяет обязательные поля объекта, значения известных полей
+// и отсутствие полей, не описанных в схеме
+func (d *Document) validateObject(schema *Schema, value interface{}, path string) error {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s: must be an object", path)
+	}
+
+	for _, name := range schema.Required {
+		if _, ok := object[name]; !ok {
+			return fmt.Errorf("%s: required property %q is missing", path, name)
+		}
+	}
+
+	for name, property := range object {
+		propertyPath := path + "." + name
+		if propertySchema, ok := schema.Properties[name]; ok {
+			if err := d.validate(propertySchema, property, propertyPath); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch additional := schema.AdditionalProperties.(type) {
+		case *Schema:
+			if err := d.validate(additional, property, propertyPath); err != nil {
+				return err
+			}
+		case bool:
+			if !additional {
+				return fmt.Errorf("%s: property is not documented", propertyPath)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateString проверяет перечисление, шаблон и формат строки
+func validateString(schema *Schema, text string, path string) error {
+	if len(schema.Enum) > 0 && !contains(schema.Enum, text) {
+		return fmt.Errorf("%s: must be one of %s", path, strings.Join(schema.Enum, ", "))
+	}
+	if schema.Pattern != "" {
+		matched, err := regexp.MatchString(schema.Pattern, text)
+		if err != nil {
+			return fmt.Errorf("%s: invalid pattern %q: %w", path, schema.Pattern, err)
+		}
+		if !matched {
+			return fmt.Errorf("%s: must match %s", path, schema.Pattern)
+		}
+	}
+	if schema.Format == "date-time" {
+		if _, err := time.Parse(time.RFC3339Nano, text); err != nil {
+			return fmt.Errorf("%s: must be a date-time", path)
+		}
+	}
+	return nil
+}
+
+// validateNumber проверяет, что число целое (для integer) и не меньше минимума
+func validateNumber(schema *Schema, number json.Number, path string) error {
+	value, err := number.Float64()
+	if err != nil {
+		return fmt.Errorf("%s: must be of type %s", path, schema.Type)
+	}
+	if schema.Type == "integer" {
+		if _, err := strconv.ParseInt(number.String(), 10, 64); err != nil {
+			if _, err := strconv.ParseUint(number.String(), 10, 64); err != nil {
+				return fmt.Errorf("%s: must be an integer", path)
+			}
+		}
+	}
+	if schema.Minimum != nil && value < *schema.Minimum {
+		return fmt.Errorf("%s: must be at least %v", path, *schema.Minimum)
+	}
+	if schema.Maximum != nil && value > *schema.Maximum {
+		return fmt.Errorf("%s: must be at most %v", path, *schema.Maximum)
+	}
+	return nil
+}
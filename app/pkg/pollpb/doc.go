@@ -0,0 +1,4 @@
+// Package pollpb код gRPC API сервиса опросов, сгенерированный по poll.proto.
+package pollpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative poll.proto
@@ -0,0 +1,688 @@
+// API сервиса опросов по gRPC. Сообщения повторяют тела запросов и ответов
+// HTTP API (pkg/api), обработку выполняет тот же слой pkg/service.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: poll.proto
+
+package pollpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	PollService_CreatePoll_FullMethodName          = "/poll.v1.PollService/CreatePoll"
+	PollService_GetPoll_FullMethodName             = "/poll.v1.PollService/GetPoll"
+	PollService_UpdatePoll_FullMethodName          = "/poll.v1.PollService/UpdatePoll"
+	PollService_DeletePoll_FullMethodName          = "/poll.v1.PollService/DeletePoll"
+	PollService_AddQuestion_FullMethodName         = "/poll.v1.PollService/AddQuestion"
+	PollService_UpdateQuestion_FullMethodName      = "/poll.v1.PollService/UpdateQuestion"
+	PollService_DeleteQuestion_FullMethodName      = "/poll.v1.PollService/DeleteQuestion"
+	PollService_SubmitAnswers_FullMethodName       = "/poll.v1.PollService/SubmitAnswers"
+	PollService_GetResults_FullMethodName          = "/poll.v1.PollService/GetResults"
+	PollService_StreamResults_FullMethodName       = "/poll.v1.PollService/StreamResults"
+	PollService_CreateTemplate_FullMethodName      = "/poll.v1.PollService/CreateTemplate"
+	PollService_ListTemplates_FullMethodName       = "/poll.v1.PollService/ListTemplates"
+	PollService_GetTemplate_FullMethodName         = "/poll.v1.PollService/GetTemplate"
+	PollService_DeleteTemplate_FullMethodName      = "/poll.v1.PollService/DeleteTemplate"
+	PollService_InstantiateTemplate_FullMethodName = "/poll.v1.PollService/InstantiateTemplate"
+)
+
+// PollServiceClient is the client API for PollService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PollServiceClient interface {
+	// Создает опрос
+	CreatePoll(ctx context.Context, in *CreatePollRequest, opts ...grpc.CallOption) (*Poll, error)
+	// Получает опрос с вопросами и вариантами ответов
+	GetPoll(ctx context.Context, in *GetPollRequest, opts ...grpc.CallOption) (*Poll, error)
+	// Изменяет название и (или) URL опроса; пустые поля не меняются
+	UpdatePoll(ctx context.Context, in *UpdatePollRequest, opts ...grpc.CallOption) (*Poll, error)
+	// Удаляет опрос вместе с вопросами и ответами
+	DeletePoll(ctx context.Context, in *DeletePollRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
+	// Добавляет вопрос с вариантами ответов
+	AddQuestion(ctx context.Context, in *AddQuestionRequest, opts ...grpc.CallOption) (*Question, error)
+	// Изменяет вопрос и его варианты ответов
+	UpdateQuestion(ctx context.Context, in *UpdateQuestionRequest, opts ...grpc.CallOption) (*Question, error)
+	// Удаляет вопрос вместе с вариантами ответов и голосами за них
+	DeleteQuestion(ctx context.Context, in *DeleteQuestionRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
+	// Регистрирует ответы пользователя на вопросы опроса
+	SubmitAnswers(ctx context.Context, in *SubmitAnswersRequest, opts ...grpc.CallOption) (*SubmitAnswersResponse, error)
+	// Получает результаты опроса
+	GetResults(ctx context.Context, in *GetResultsRequest, opts ...grpc.CallOption) (*Results, error)
+	// Отправляет результаты опроса сразу и затем после каждого их изменения
+	StreamResults(ctx context.Context, in *GetResultsRequest, opts ...grpc.CallOption) (PollService_StreamResultsClient, error)
+	// Создает шаблон опроса из описания или из существующего опроса
+	CreateTemplate(ctx context.Context, in *CreateTemplateRequest, opts ...grpc.CallOption) (*Template, error)
+	// Получает список шаблонов по имени
+	ListTemplates(ctx context.Context, in *ListTemplatesRequest, opts ...grpc.CallOption) (*ListTemplatesResponse, error)
+	// Получает шаблон с описанием опроса
+	GetTemplate(ctx context.Context, in *GetTemplateRequest, opts ...grpc.CallOption) (*Template, error)
+	// Удаляет шаблон; опросы, созданные из него, не меняются
+	DeleteTemplate(ctx context.Context, in *DeleteTemplateRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
+	// Создает опрос из шаблона с подстановкой параметров
+	InstantiateTemplate(ctx context.Context, in *InstantiateTemplateRequest, opts ...grpc.CallOption) (*Poll, error)
+}
+
+type pollServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPollServiceClient(cc grpc.ClientConnInterface) PollServiceClient {
+	return &pollServiceClient{cc}
+}
+
+func (c *pollServiceClient) CreatePoll(ctx context.Context, in *CreatePollRequest, opts ...grpc.CallOption) (*Poll, error) {
+	out := new(Poll)
+	err := c.cc.Invoke(ctx, PollService_CreatePoll_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pollServiceClient) GetPoll(ctx context.Context, in *GetPollRequest, opts ...grpc.CallOption) (*Poll, error) {
+	out := new(Poll)
+	err := c.cc.Invoke(ctx, PollService_GetPoll_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pollServiceClient) UpdatePoll(ctx context.Context, in *UpdatePollRequest, opts ...grpc.CallOption) (*Poll, error) {
+	out := new(Poll)
+	err := c.cc.Invoke(ctx, PollService_UpdatePoll_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pollServiceClient) DeletePoll(ctx context.Context, in *DeletePollRequest, opts ...grpc.CallOption) (*DeleteResponse, error) {
+	out := new(DeleteResponse)
+	err := c.cc.Invoke(ctx, PollService_DeletePoll_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pollServiceClient) AddQuestion(ctx context.Context, in *AddQuestionRequest, opts ...grpc.CallOption) (*Question, error) {
+	out := new(Question)
+	err := c.cc.Invoke(ctx, PollService_AddQuestion_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pollServiceClient) UpdateQuestion(ctx context.Context, in *UpdateQuestionRequest, opts ...grpc.CallOption) (*Question, error) {
+	out := new(Question)
+	err := c.cc.Invoke(ctx, PollService_UpdateQuestion_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pollServiceClient) DeleteQuestion(ctx context.Context, in *DeleteQuestionRequest, opts ...grpc.CallOption) (*DeleteResponse, error) {
+	out := new(DeleteResponse)
+	err := c.cc.Invoke(ctx, PollService_DeleteQuestion_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pollServiceClient) SubmitAnswers(ctx context.Context, in *SubmitAnswersRequest, opts ...grpc.CallOption) (*SubmitAnswersResponse, error) {
+	out := new(SubmitAnswersResponse)
+	err := c.cc.Invoke(ctx, PollService_SubmitAnswers_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pollServiceClient) GetResults(ctx context.Context, in *GetResultsRequest, opts ...grpc.CallOption) (*Results, error) {
+	out := new(Results)
+	err := c.cc.Invoke(ctx, PollService_GetResults_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pollServiceClient) StreamResults(ctx context.Context, in *GetResultsRequest, opts ...grpc.CallOption) (PollService_StreamResultsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &PollService_ServiceDesc.Streams[0], PollService_StreamResults_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &pollServiceStreamResultsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type PollService_StreamResultsClient interface {
+	Recv() (*Results, error)
+	grpc.ClientStream
+}
+
+type pollServiceStreamResultsClient struct {
+	grpc.ClientStream
+}
+
+func (x *pollServiceStreamResultsClient) Recv() (*Results, error) {
+	m := new(Results)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *pollServiceClient) CreateTemplate(ctx context.Context, in *CreateTemplateRequest, opts ...grpc.CallOption) (*Template, error) {
+	out := new(Template)
+	err := c.cc.Invoke(ctx, PollService_CreateTemplate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pollServiceClient) ListTemplates(ctx context.Context, in *ListTemplatesRequest, opts ...grpc.CallOption) (*ListTemplatesResponse, error) {
+	out := new(ListTemplatesResponse)
+	err := c.cc.Invoke(ctx, PollService_ListTemplates_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pollServiceClient) GetTemplate(ctx context.Context, in *GetTemplateRequest, opts ...grpc.CallOption) (*Template, error) {
+	out := new(Template)
+	err := c.cc.Invoke(ctx, PollService_GetTemplate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pollServiceClient) DeleteTemplate(ctx context.Context, in *DeleteTemplateRequest, opts ...grpc.CallOption) (*DeleteResponse, error) {
+	out := new(DeleteResponse)
+	err := c.cc.Invoke(ctx, PollService_DeleteTemplate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pollServiceClient) InstantiateTemplate(ctx context.Context, in *InstantiateTemplateRequest, opts ...grpc.CallOption) (*Poll, error) {
+	out := new(Poll)
+	err := c.cc.Invoke(ctx, PollService_InstantiateTemplate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PollServiceServer is the server API for PollService service.
+// All implementations must embed UnimplementedPollServiceServer
+// for forward compatibility
+type PollServiceServer interface {
+	// Создает опрос
+	CreatePoll(context.Context, *CreatePollRequest) (*Poll, error)
+	// Получает опрос с вопросами и вариантами ответов
+	GetPoll(context.Context, *GetPollRequest) (*Poll, error)
+	// Изменяет название и (или) URL опроса; пустые поля не меняются
+	UpdatePoll(context.Context, *UpdatePollRequest) (*Poll, error)
+	// Удаляет опрос вместе с вопросами и ответами
+	DeletePoll(context.Context, *DeletePollRequest) (*DeleteResponse, error)
+	// Добавляет вопрос с вариантами ответов
+	AddQuestion(context.Context, *AddQuestionRequest) (*Question, error)
+	// Изменяет вопрос и его варианты ответов
+	UpdateQuestion(context.Context, *UpdateQuestionRequest) (*Question, error)
+	// Удаляет вопрос вместе с вариантами ответов и голосами за них
+	DeleteQuestion(context.Context, *DeleteQuestionRequest) (*DeleteResponse, error)
+	// Регистрирует ответы пользователя на вопросы опроса
+	SubmitAnswers(context.Context, *SubmitAnswersRequest) (*SubmitAnswersResponse, error)
+	// Получает результаты опроса
+	GetResults(context.Context, *GetResultsRequest) (*Results, error)
+	// Отправляет результаты опроса сразу и затем после каждого их изменения
+	StreamResults(*GetResultsRequest, PollService_StreamResultsServer) error
+	// Создает шаблон опроса из описания или из существующего опроса
+	CreateTemplate(context.Context, *CreateTemplateRequest) (*Template, error)
+	// Получает список шаблонов по имени
+	ListTemplates(context.Context, *ListTemplatesRequest) (*ListTemplatesResponse, error)
+	// Получает шаблон с описанием опроса
+	GetTemplate(context.Context, *GetTemplateRequest) (*Template, error)
+	// Удаляет шаблон; опросы, созданные из него, не меняются
+	DeleteTemplate(context.Context, *DeleteTemplateRequest) (*DeleteResponse, error)
+	// Создает опрос из шаблона с подстановкой параметров
+	InstantiateTemplate(context.Context, *InstantiateTemplateRequest) (*Poll, error)
+	mustEmbedUnimplementedPollServiceServer()
+}
+
+// UnimplementedPollServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedPollServiceServer struct {
+}
+
+func (UnimplementedPollServiceServer) CreatePoll(context.Context, *CreatePollRequest) (*Poll, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreatePoll not implemented")
+}
+func (UnimplementedPollServiceServer) GetPoll(context.Context, *GetPollRequest) (*Poll, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPoll not implemented")
+}
+func (UnimplementedPollServiceServer) UpdatePoll(context.Context, *UpdatePollRequest) (*Poll, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdatePoll not implemented")
+}
+func (UnimplementedPollServiceServer) DeletePoll(context.Context, *DeletePollRequest) (*DeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeletePoll not implemented")
+}
+func (UnimplementedPollServiceServer) AddQuestion(context.Context, *AddQuestionRequest) (*Question, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddQuestion not implemented")
+}
+func (UnimplementedPollServiceServer) UpdateQuestion(context.Context, *UpdateQuestionRequest) (*Question, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateQuestion not implemented")
+}
+func (UnimplementedPollServiceServer) DeleteQuestion(context.Context, *DeleteQuestionRequest) (*DeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteQuestion not implemented")
+}
+func (UnimplementedPollServiceServer) SubmitAnswers(context.Context, *SubmitAnswersRequest) (*SubmitAnswersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitAnswers not implemented")
+}
+func (UnimplementedPollServiceServer) GetResults(context.Context, *GetResultsRequest) (*Results, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetResults not implemented")
+}
+func (UnimplementedPollServiceServer) StreamResults(*GetResultsRequest, PollService_StreamResultsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamResults not implemented")
+}
+func (UnimplementedPollServiceServer) CreateTemplate(context.Context, *CreateTemplateRequest) (*Template, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateTemplate not implemented")
+}
+func (UnimplementedPollServiceServer) ListTemplates(context.Context, *ListTemplatesRequest) (*ListTemplatesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTemplates not implemented")
+}
+func (UnimplementedPollServiceServer) GetTemplate(context.Context, *GetTemplateRequest) (*Template, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTemplate not implemented")
+}
+func (UnimplementedPollServiceServer) DeleteTemplate(context.Context, *DeleteTemplateRequest) (*DeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteTemplate not implemented")
+}
+func (UnimplementedPollServiceServer) InstantiateTemplate(context.Context, *InstantiateTemplateRequest) (*Poll, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InstantiateTemplate not implemented")
+}
+func (UnimplementedPollServiceServer) mustEmbedUnimplementedPollServiceServer() {}
+
+// UnsafePollServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PollServiceServer will
+// result in compilation errors.
+type UnsafePollServiceServer interface {
+	mustEmbedUnimplementedPollServiceServer()
+}
+
+func RegisterPollServiceServer(s grpc.ServiceRegistrar, srv PollServiceServer) {
+	s.RegisterService(&PollService_ServiceDesc, srv)
+}
+
+func _PollService_CreatePoll_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreatePollRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PollServiceServer).CreatePoll(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PollService_CreatePoll_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PollServiceServer).CreatePoll(ctx, req.(*CreatePollRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PollService_GetPoll_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPollRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PollServiceServer).GetPoll(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PollService_GetPoll_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PollServiceServer).GetPoll(ctx, req.(*GetPollRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PollService_UpdatePoll_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdatePollRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PollServiceServer).UpdatePoll(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PollService_UpdatePoll_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PollServiceServer).UpdatePoll(ctx, req.(*UpdatePollRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PollService_DeletePoll_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeletePollRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PollServiceServer).DeletePoll(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PollService_DeletePoll_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PollServiceServer).DeletePoll(ctx, req.(*DeletePollRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PollService_AddQuestion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddQuestionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PollServiceServer).AddQuestion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PollService_AddQuestion_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PollServiceServer).AddQuestion(ctx, req.(*AddQuestionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PollService_UpdateQuestion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateQuestionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PollServiceServer).UpdateQuestion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PollService_UpdateQuestion_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PollServiceServer).UpdateQuestion(ctx, req.(*UpdateQuestionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PollService_DeleteQuestion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteQuestionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PollServiceServer).DeleteQuestion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PollService_DeleteQuestion_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PollServiceServer).DeleteQuestion(ctx, req.(*DeleteQuestionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PollService_SubmitAnswers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitAnswersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PollServiceServer).SubmitAnswers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PollService_SubmitAnswers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PollServiceServer).SubmitAnswers(ctx, req.(*SubmitAnswersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PollService_GetResults_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetResultsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PollServiceServer).GetResults(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PollService_GetResults_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PollServiceServer).GetResults(ctx, req.(*GetResultsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PollService_StreamResults_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetResultsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PollServiceServer).StreamResults(m, &pollServiceStreamResultsServer{stream})
+}
+
+type PollService_StreamResultsServer interface {
+	Send(*Results) error
+	grpc.ServerStream
+}
+
+type pollServiceStreamResultsServer struct {
+	grpc.ServerStream
+}
+
+func (x *pollServiceStreamResultsServer) Send(m *Results) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _PollService_CreateTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateTemplateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PollServiceServer).CreateTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PollService_CreateTemplate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PollServiceServer).CreateTemplate(ctx, req.(*CreateTemplateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PollService_ListTemplates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTemplatesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PollServiceServer).ListTemplates(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PollService_ListTemplates_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PollServiceServer).ListTemplates(ctx, req.(*ListTemplatesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PollService_GetTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTemplateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PollServiceServer).GetTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PollService_GetTemplate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PollServiceServer).GetTemplate(ctx, req.(*GetTemplateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PollService_DeleteTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteTemplateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PollServiceServer).DeleteTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PollService_DeleteTemplate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PollServiceServer).DeleteTemplate(ctx, req.(*DeleteTemplateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PollService_InstantiateTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InstantiateTemplateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PollServiceServer).InstantiateTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PollService_InstantiateTemplate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PollServiceServer).InstantiateTemplate(ctx, req.(*InstantiateTemplateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// PollService_ServiceDesc is the grpc.ServiceDesc for PollService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PollService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "poll.v1.PollService",
+	HandlerType: (*PollServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreatePoll",
+			Handler:    _PollService_CreatePoll_Handler,
+		},
+		{
+			MethodName: "GetPoll",
+			Handler:    _PollService_GetPoll_Handler,
+		},
+		{
+			MethodName: "UpdatePoll",
+			Handler:    _PollService_UpdatePoll_Handler,
+		},
+		{
+			MethodName: "DeletePoll",
+			Handler:    _PollService_DeletePoll_Handler,
+		},
+		{
+			MethodName: "AddQuestion",
+			Handler:    _PollService_AddQuestion_Handler,
+		},
+		{
+			MethodName: "UpdateQuestion",
+			Handler:    _PollService_UpdateQuestion_Handler,
+		},
+		{
+			MethodName: "DeleteQuestion",
+			Handler:    _PollService_DeleteQuestion_Handler,
+		},
+		{
+			MethodName: "SubmitAnswers",
+			Handler:    _PollService_SubmitAnswers_Handler,
+		},
+		{
+			MethodName: "GetResults",
+			Handler:    _PollService_GetResults_Handler,
+		},
+		{
+			MethodName: "CreateTemplate",
+			Handler:    _PollService_CreateTemplate_Handler,
+		},
+		{
+			MethodName: "ListTemplates",
+			Handler:    _PollService_ListTemplates_Handler,
+		},
+		{
+			MethodName: "GetTemplate",
+			Handler:    _PollService_GetTemplate_Handler,
+		},
+		{
+			MethodName: "DeleteTemplate",
+			Handler:    _PollService_DeleteTemplate_Handler,
+		},
+		{
+			MethodName: "InstantiateTemplate",
+			Handler:    _PollService_InstantiateTemplate_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamResults",
+			Handler:       _PollService_StreamResults_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "poll.proto",
+}
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	swaggerfiles "github.com/swaggo/files/v2"
+)
+
+// docsPage страница Swagger UI, открывающая описание API из openapi.json
+//
+//go:embed docs.html
+var docsPage []byte
+
+// swaggerUI статические файлы Swagger UI, встроенные в сборку
+var swaggerUI = http.StripPrefix(Prefix+"/docs", http.FileServer(http.FS(swaggerfiles.FS)))
+
+// docs отдает страницу Swagger UI и ее статические файлы
+func docs(c *gin.Context) {
+	if file := c.Param("file"); file == "/" || file == "/index.html" {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", docsPage)
+		return
+	}
+	swaggerUI.ServeHTTP(c.Writer, c.Request)
+}
@@ -0,0 +1,418 @@
+// Package routes описывает маршруты HTTP API версии 1. По одной и той же таблице
+// маршруты регистрируются в gin и строится описание OpenAPI, поэтому они не расходятся.
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"service-poll/migrations"
+	"service-poll/pkg/api"
+	"service-poll/pkg/definition"
+	"service-poll/pkg/handlers"
+	"service-poll/pkg/models"
+	"service-poll/pkg/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Prefix префикс маршрутов API версии 1
+const Prefix = "/api/v1"
+
+// ifNoneMatch заголовок условного запроса операций, которые отвечают с ETag
+var ifNoneMatch = openapi.Param{
+	Name:        "If-None-Match",
+	Description: "ETag of a previously received response; 304 Not Modified is returned if the response has not changed",
+	Schema:      &openapi.Schema{Type: "string"},
+}
+
+// Route маршрут API: обработчик и сведения для описания OpenAPI.
+// Идентификатор операции — имя функции-обработчика.
+type Route struct {
+	openapi.Operation
+	Handler gin.HandlerFunc
+}
+
+// Типы содержимого описаний опросов
+var definitionTypes = []string{"application/json", "application/yaml"}
+
+// Options маршруты API, которые регистрируются только по настройке
+type Options struct {
+	Seed bool // POST /seed (config.ServerConfig.SeedEndpoint)
+}
+
+// Routes возвращает маршруты API версии 1; пути указаны относительно Prefix
+func Routes(opts Options) []Route {
+	routes := []Route{
+		// POST /migrate
+		// Создаёт базу данных и таблицы + наполняет их фикстурами
+		{Handler: migrations.Migrate, Operation: openapi.Operation{
+			Method: http.MethodPost, Path: "/migrate", Tag: "system",
+			Summary:  "Create tables and seed fixtures",
+			Response: api.MessageResponse{},
+		}},
+
+		// GET /db/stats
+		// Проверяет доступность базы данных и возвращает статистику пула соединений.
+		{Handler: handlers.GetDBStats, Operation: openapi.Operation{
+			Method: http.MethodGet, Path: "/db/stats", Tag: "system",
+			Summary:   "Check the database and return connection pool statistics",
+			Response:  api.DBStatsResponse{},
+			Responses: map[int]interface{}{http.StatusServiceUnavailable: api.DBStatsResponse{}},
+		}},
+
+		// POST /poll/create
+		// Создает новый опрос.
+		{Handler: handlers.CreatePoll, Operation: openapi.Operation{
+			Method: http.MethodPost, Path: "/poll/create", Tag: "polls",
+			Summary:  "Create a poll",
+			Request:  api.CreatePollRequest{},
+			Response: models.Poll{},
+			Errors:   []int{http.StatusBadRequest, http.StatusInternalServerError},
+		}},
+
+		// GET /polls
+		// Возвращает страницу списка опросов.
+		{Handler: handlers.ListPolls, Operation: openapi.Operation{
+			Method: http.MethodGet, Path: "/polls", Tag: "polls",
+			Summary: "List polls ordered by identifier",
+			Query: []openapi.Param{{
+				Name: "limit", Description: "Maximum number of polls to return (1-100, default 20)",
+				Schema: &openapi.Schema{Type: "integer", Minimum: float(1), Maximum: float(100)},
+			}, {
+				Name: "offset", Description: "Number of polls to skip",
+				Schema: &openapi.Schema{Type: "integer", Minimum: float(0)},
+			}},
+			Response: api.PollListResponse{},
+			Errors:   []int{http.StatusBadRequest, http.StatusInternalServerError},
+		}},
+
+		// POST /polls/import
+		// Создает опросы по описаниям в формате JSON или YAML в одной транзакции.
+		{Handler: handlers.ImportPolls, Operation: openapi.Operation{
+			Method: http.MethodPost, Path: "/polls/import", Tag: "definitions",
+			Summary: "Import one or more poll definitions in a single transaction",
+			Query: []openapi.Param{{
+				Name: "format", Description: "Definition format; defaults to the Content-Type header",
+				Schema: &openapi.Schema{Type: "string", Enum: []string{definition.FormatJSON, definition.FormatYAML}},
+			}},
+			Request:      openapi.OneOf{definition.Definition{}, []definition.Definition{}},
+			RequestTypes: definitionTypes,
+			Response:     api.ImportPollsResponse{},
+			Errors:       []int{http.StatusBadRequest, http.StatusInternalServerError},
+		}},
+
+		// GET /poll/:id
+		// Получает опрос с вопросами по указанному идентификатору.
+		{Handler: handlers.GetPoll, Operation: openapi.Operation{
+			Method: http.MethodGet, Path: "/poll/:id", Tag: "polls",
+			Summary:   "Get a poll with its questions and options",
+			Headers:   []openapi.Param{ifNoneMatch},
+			Response:  api.PollResponse{},
+			Responses: map[int]interface{}{http.StatusNotModified: nil},
+			Errors:    []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+		}},
+
+		// PATCH /poll/:id
+		// Изменяет опрос по указанному идентификатору.
+		{Handler: handlers.UpdatePoll, Operation: openapi.Operation{
+			Method: http.MethodPatch, Path: "/poll/:id", Tag: "polls",
+			Summary:  "Update the title or URL of a poll",
+			Request:  api.UpdatePollRequest{},
+			Response: models.Poll{},
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+		}},
+
+		// DELETE /poll/:id
+		// Удаляет опрос по указанному идентификатору.
+		{Handler: handlers.DeletePoll, Operation: openapi.Operation{
+			Method: http.MethodDelete, Path: "/poll/:id", Tag: "polls",
+			Summary:  "Delete a poll with its questions and answers",
+			Response: api.MessageResponse{},
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+		}},
+
+		// GET /poll/:id/results
+		// Получает результаты опроса по указанному идентификатору.
+		// ?revision=N — результаты по ревизии, ?revision=merged — объединенные по вариантам ответа.
+		{Handler: handlers.GetPollResults, Operation: openapi.Operation{
+			Method: http.MethodGet, Path: "/poll/:id/results", Tag: "results",
+			Summary: "Get poll results",
+			Query: []openapi.Param{{
+				Name: "revision", Description: "Revision number, or 'merged' to combine all revisions by option identity",
+				Schema: &openapi.Schema{Type: "string", Pattern: "^([1-9][0-9]*|merged)$"},
+			}},
+			Headers:   []openapi.Param{ifNoneMatch},
+			Response:  openapi.OneOf{api.PollResultsResponse{}, api.RevisionResultsResponse{}},
+			Responses: map[int]interface{}{http.StatusNotModified: nil},
+			Errors:    []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+		}},
+
+		// POST /poll/:id/publish
+		// Публикует опрос, создавая новую неизменяемую ревизию вопросов и вариантов ответов.
+		{Handler: handlers.PublishPoll, Operation: openapi.Operation{
+			Method: http.MethodPost, Path: "/poll/:id/publish", Tag: "revisions",
+			Summary:  "Publish a poll as a new immutable revision",
+			Response: api.Revision{},
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+		}},
+
+		// GET /poll/:id/revisions
+		// Получает список ревизий опроса по указанному идентификатору.
+		{Handler: handlers.GetPollRevisions, Operation: openapi.Operation{
+			Method: http.MethodGet, Path: "/poll/:id/revisions", Tag: "revisions",
+			Summary:  "List poll revisions",
+			Response: api.RevisionsResponse{},
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+		}},
+
+		// GET /poll/:id/revisions/:rev
+		// Получает снимок ревизии опроса по номеру ревизии.
+		{Handler: handlers.GetPollRevision, Operation: openapi.Operation{
+			Method: http.MethodGet, Path: "/poll/:id/revisions/:rev", Tag: "revisions",
+			Summary:  "Get a poll revision snapshot",
+			Response: api.Revision{},
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+		}},
+
+		// POST /poll/:id/clone
+		// Создает копию опроса с вопросами и вариантами ответов в виде черновика с новым адресом.
+		{Handler: handlers.ClonePoll, Operation: openapi.Operation{
+			Method: http.MethodPost, Path: "/poll/:id/clone", Tag: "definitions",
+			Summary:  "Clone a poll as an unpublished draft",
+			Request:  api.ClonePollRequest{},
+			Response: api.PollSummary{},
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+		}},
+
+		// GET /poll/:id/definition
+		// Получает описание опроса в формате JSON или YAML (?format=yaml).
+		{Handler: handlers.GetPollDefinition, Operation: openapi.Operation{
+			Method: http.MethodGet, Path: "/poll/:id/definition", Tag: "definitions",
+			Summary: "Export a poll definition",
+			Query: []openapi.Param{{
+				Name: "format", Description: "Definition format; defaults to the Accept header",
+				Schema: &openapi.Schema{Type: "string", Enum: []string{definition.FormatJSON, definition.FormatYAML}},
+			}},
+			Response:      definition.Definition{},
+			ResponseTypes: definitionTypes,
+			Errors:        []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+		}},
+
+		// GET /poll/:id/audit
+		// Получает журнал изменений опроса и его вопросов по указанному идентификатору.
+		{Handler: handlers.GetPollAudit, Operation: openapi.Operation{
+			Method: http.MethodGet, Path: "/poll/:id/audit", Tag: "polls",
+			Summary: "Get the change log of a poll and its questions",
+			Query: []openapi.Param{{
+				Name: "question_id", Description: "Only entries of this question",
+				Schema: &openapi.Schema{Type: "integer"},
+			}},
+			Response: api.AuditResponse{},
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+		}},
+
+		// POST /poll/:id/question
+		// Добавляет вопрос к опросу по указанному идентификатору.
+		{Handler: handlers.AddQuestion, Operation: openapi.Operation{
+			Method: http.MethodPost, Path: "/poll/:id/question", Tag: "questions",
+			Summary:  "Add a question with options to a poll",
+			Request:  api.AddQuestionRequest{},
+			Response: models.Question{},
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+		}},
+
+		// PATCH /poll/:id/question/:qid
+		// Изменяет вопрос к опросу по указанному идентификатору.
+		{Handler: handlers.UpdateQuestion, Operation: openapi.Operation{
+			Method: http.MethodPatch, Path: "/poll/:id/question/:qid", Tag: "questions",
+			Summary: "Update a question and its options",
+			Query: []openapi.Param{{
+				Name: "force", Description: "Remove options that already have votes",
+				Schema: &openapi.Schema{Type: "boolean"},
+			}},
+			Request:  api.UpdateQuestionRequest{},
+			Response: models.Question{},
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError},
+		}},
+
+		// DELETE /poll/:id/question/:qid
+		// Удаляет вопрос к опросу по указанному идентификатору.
+		{Handler: handlers.DeleteQuestion, Operation: openapi.Operation{
+			Method: http.MethodDelete, Path: "/poll/:id/question/:qid", Tag: "questions",
+			Summary:  "Delete a question with its options and votes",
+			Response: api.MessageResponse{},
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+		}},
+
+		// POST /poll/:id/answer
+		// Регистрирует ответ на вопрос к опросу по указанному идентификатору.
+		// При асинхронном приеме отвечает 202 с квитанцией.
+		{Handler: handlers.RegisterAnswer, Operation: openapi.Operation{
+			Method: http.MethodPost, Path: "/poll/:id/answer", Tag: "answers",
+			Summary:   "Register a user's answers to poll questions",
+			Request:   api.AnswerRequest{},
+			Response:  api.AnswerResponse{},
+			Responses: map[int]interface{}{http.StatusAccepted: api.ReceiptResponse{}},
+			Errors:    []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError, http.StatusServiceUnavailable},
+		}},
+
+		// GET /receipts/:receipt
+		// Получает состояние квитанции асинхронно принятого ответа.
+		{Handler: handlers.GetReceipt, Operation: openapi.Operation{
+			Method: http.MethodGet, Path: "/receipts/:receipt", Tag: "answers",
+			Summary: "Get the status of an asynchronously accepted answer",
+			PathParams: []openapi.Param{{
+				Name:        "receipt",
+				Description: "receipt ID returned with 202 Accepted",
+				Schema:      &openapi.Schema{Type: "string", Pattern: "^[0-9a-f]{32}$"},
+			}},
+			Response: api.ReceiptResponse{},
+			Errors:   []int{http.StatusNotFound, http.StatusInternalServerError},
+		}},
+
+		// GET /templates
+		// Получает список шаблонов опросов.
+		{Handler: handlers.GetTemplates, Operation: openapi.Operation{
+			Method: http.MethodGet, Path: "/templates", Tag: "templates",
+			Summary:  "List poll templates",
+			Response: api.TemplatesResponse{},
+			Errors:   []int{http.StatusInternalServerError},
+		}},
+
+		// POST /templates
+		// Создает шаблон опроса из описания или из существующего опроса.
+		{Handler: handlers.CreateTemplate, Operation: openapi.Operation{
+			Method: http.MethodPost, Path: "/templates", Tag: "templates",
+			Summary:  "Create a template from a definition or an existing poll",
+			Request:  api.CreateTemplateRequest{},
+			Response: api.Template{},
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError},
+		}},
+
+		// GET /templates/:tid
+		// Получает шаблон опроса по указанному идентификатору.
+		{Handler: handlers.GetTemplate, Operation: openapi.Operation{
+			Method: http.MethodGet, Path: "/templates/:tid", Tag: "templates",
+			Summary:  "Get a poll template",
+			Response: api.Template{},
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+		}},
+
+		// DELETE /templates/:tid
+		// Удаляет шаблон опроса по указанному идентификатору.
+		{Handler: handlers.DeleteTemplate, Operation: openapi.Operation{
+			Method: http.MethodDelete, Path: "/templates/:tid", Tag: "templates",
+			Summary:  "Delete a poll template",
+			Response: api.MessageResponse{},
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+		}},
+
+		// GET /templates/:tid/audit
+		// Получает журнал изменений шаблона опроса, в том числе удаленного.
+		{Handler: handlers.GetTemplateAudit, Operation: openapi.Operation{
+			Method: http.MethodGet, Path: "/templates/:tid/audit", Tag: "templates",
+			Summary:  "Get the change log of a poll template, including a deleted one",
+			Response: api.TemplateAuditResponse{},
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+		}},
+
+		// POST /templates/:tid/instantiate
+		// Создает опрос из шаблона с подстановкой параметров в заголовок и тексты вопросов.
+		{Handler: handlers.InstantiateTemplate, Operation: openapi.Operation{
+			Method: http.MethodPost, Path: "/templates/:tid/instantiate", Tag: "templates",
+			Summary:  "Create a poll from a template with parameter substitution",
+			Request:  api.InstantiateTemplateRequest{},
+			Response: api.PollSummary{},
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+		}},
+	}
+
+	if opts.Seed {
+		routes = append(routes,
+			// POST /seed
+			// Создает опросы с синтетическими респондентами детерминированным генератором.
+			Route{Handler: handlers.Seed, Operation: openapi.Operation{
+				Method: http.MethodPost, Path: "/seed", Tag: "system",
+				Summary:  "Generate polls with synthetic respondents from a fixed seed",
+				Request:  api.SeedRequest{},
+				Response: api.SeedResponse{},
+				Errors:   []int{http.StatusBadRequest, http.StatusInternalServerError},
+			}})
+	}
+	return routes
+}
+
+// Spec строит описание OpenAPI маршрутов API версии 1
+func Spec(opts Options) (*openapi.Document, error) {
+	routes := Routes(opts)
+	operations := make([]openapi.Operation, 0, len(routes))
+	for _, route := range routes {
+		operation := route.Operation
+		operation.ID = handlerName(route.Handler)
+		operations = append(operations, operation)
+	}
+
+	return openapi.Build(openapi.Info{
+		Title:       "service-poll API",
+		Description: "Polls, questions, answers and results. Errors are returned as application/problem+json (RFC 7807).",
+		Version:     "1",
+	}, Prefix, operations)
+}
+
+// Register регистрирует маршруты API версии 1 под Prefix, описание OpenAPI
+// (GET /api/v1/openapi.json) и Swagger UI (GET /api/v1/docs/).
+// Прежние маршруты без версии остаются доступными как устаревшие.
+func Register(router *gin.Engine, opts Options) error {
+	doc, err := Spec(opts)
+	if err != nil {
+		return err
+	}
+	spec, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	v1 := router.Group(Prefix)
+	legacy := router.Group("/", deprecated())
+	for _, route := range Routes(opts) {
+		v1.Handle(route.Method, route.Path, route.Handler)
+		legacy.Handle(route.Method, route.Path, route.Handler)
+	}
+
+	// GET /api/v1/openapi.json
+	// Отдает описание API в формате OpenAPI 3.
+	v1.GET("/openapi.json", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", spec)
+	})
+
+	// GET /api/v1/docs/
+	// Отдает Swagger UI с описанием API.
+	v1.GET("/docs/*file", docs)
+
+	return nil
+}
+
+// deprecated помечает ответы маршрутов без версии как устаревшие (заголовок Deprecation)
+// и указывает адрес того же маршрута в API версии 1 (заголовок Link)
+func deprecated() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		c.Header("Link", "<"+Prefix+c.Request.URL.Path+`>; rel="successor-version"`)
+		c.Next()
+	}
+}
+
+// float возвращает указатель на число для границ в схемах параметров
+func float(value float64) *float64 {
+	return &value
+}
+
+// handlerName возвращает имя функции-обработчика без пакета, например "CreatePoll"
+func handlerName(handler gin.HandlerFunc) string {
+	name := runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
+	return name[strings.LastIndex(name, ".")+1:]
+}
@@ -0,0 +1,360 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"service-poll/pkg/api"
+	"service-poll/pkg/config"
+	"service-poll/pkg/db"
+	"service-poll/pkg/handlers"
+	"service-poll/pkg/logger"
+	"service-poll/pkg/openapi"
+	"service-poll/pkg/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// contractCase запрос к API и ожидаемый код ответа
+type contractCase struct {
+	name        string
+	method      string
+	path        string
+	contentType string
+	body        string
+	status      int
+	// async запрос выполняется при включенном асинхронном приеме ответов;
+	// после запроса прием выключается, и принятые ответы сохраняются
+	async bool
+}
+
+// contractCases сценарий, который проходит по всем операциям API: сначала фикстуры,
+// затем создание, изменение, чтение и удаление записей, включая ответы с ошибками
+var contractCases = []contractCase{
+	{name: "migrate", method: http.MethodPost, path: "/migrate", status: http.StatusOK},
+	{name: "db stats", method: http.MethodGet, path: "/db/stats", status: http.StatusOK},
+
+	{name: "create poll", method: http.MethodPost, path: "/poll/create", body: `{"title":"Contract","url":"contract"}`, status: http.StatusOK},
+	{name: "create poll without title", method: http.MethodPost, path: "/poll/create", body: `{"url":"contract"}`, status: http.StatusBadRequest},
+	{name: "create poll with malformed body", method: http.MethodPost, path: "/poll/create", body: `{`, status: http.StatusBadRequest},
+	{name: "get seeded poll", method: http.MethodGet, path: "/poll/1", status: http.StatusOK},
+	{name: "get missing poll", method: http.MethodGet, path: "/poll/999", status: http.StatusNotFound},
+	{name: "get poll with invalid id", method: http.MethodGet, path: "/poll/abc", status: http.StatusBadRequest},
+	{name: "list polls", method: http.MethodGet, path: "/polls?limit=2&offset=1", status: http.StatusOK},
+	{name: "list polls with invalid limit", method: http.MethodGet, path: "/polls?limit=500", status: http.StatusBadRequest},
+	{name: "update poll", method: http.MethodPatch, path: "/poll/4", body: `{"title":"Contract poll"}`, status: http.StatusOK},
+
+	{name: "add question", method: http.MethodPost, path: "/poll/4/question", body: `{"text":"Color","type":"single","answers":["Red","Green"]}`, status: http.StatusOK},
+	{name: "add question with invalid type", method: http.MethodPost, path: "/poll/4/question", body: `{"text":"Size","type":"any","answers":["S"]}`, status: http.StatusBadRequest},
+	{name: "get poll with questions", method: http.MethodGet, path: "/poll/4", status: http.StatusOK},
+
+	{name: "register answer", method: http.MethodPost, path: "/poll/4/answer", body: `{"attributes":{"access_token":"t","user_id":1,"results":[{"question":"Color","answer":"Red"}]}}`, status: http.StatusOK},
+	{name: "register answer to unknown question", method: http.MethodPost, path: "/poll/4/answer", body: `{"attributes":{"access_token":"t","user_id":1,"results":[{"question":"Size","answer":"S"}]}}`, status: http.StatusBadRequest},
+	{name: "register answer to missing poll", method: http.MethodPost, path: "/poll/999/answer", body: `{}`, status: http.StatusNotFound},
+	{name: "results", method: http.MethodGet, path: "/poll/4/results", status: http.StatusOK},
+
+	{name: "publish", method: http.MethodPost, path: "/poll/4/publish", status: http.StatusOK},
+	{name: "register answer to revision", method: http.MethodPost, path: "/poll/4/answer", body: `{"attributes":{"access_token":"t","user_id":2,"results":[{"question":"Color","answer":"Green"}]}}`, status: http.StatusOK},
+	{name: "register answer asynchronously", method: http.MethodPost, path: "/poll/4/answer", body: `{"attributes":{"access_token":"t","user_id":3,"results":[{"question":"Color","answer":"Red"}]}}`, status: http.StatusAccepted, async: true},
+	{name: "register answer asynchronously to missing poll", method: http.MethodPost, path: "/poll/999/answer", body: `{}`, status: http.StatusNotFound, async: true},
+	{name: "receipt", method: http.MethodGet, path: "/receipts/{receipt}", status: http.StatusOK},
+	{name: "missing receipt", method: http.MethodGet, path: "/receipts/00000000000000000000000000000000", status: http.StatusNotFound},
+	{name: "revisions", method: http.MethodGet, path: "/poll/4/revisions", status: http.StatusOK},
+	{name: "revision", method: http.MethodGet, path: "/poll/4/revisions/1", status: http.StatusOK},
+	{name: "missing revision", method: http.MethodGet, path: "/poll/4/revisions/9", status: http.StatusNotFound},
+	{name: "revision results", method: http.MethodGet, path: "/poll/4/results?revision=1", status: http.StatusOK},
+	{name: "merged results", method: http.MethodGet, path: "/poll/4/results?revision=merged", status: http.StatusOK},
+	{name: "results with invalid revision", method: http.MethodGet, path: "/poll/4/results?revision=x", status: http.StatusBadRequest},
+
+	{name: "remove voted option", method: http.MethodPatch, path: "/poll/4/question/10", body: `{"text":"Color","type":"single","options":[{"id":29}]}`, status: http.StatusConflict},
+	{name: "remove voted option with force", method: http.MethodPatch, path: "/poll/4/question/10?force=true", body: `{"text":"Color","type":"single","options":[{"id":29},{"text":"Blue"}]}`, status: http.StatusOK},
+	{name: "update missing question", method: http.MethodPatch, path: "/poll/4/question/1", body: `{"text":"Color","type":"single","answers":["Red"]}`, status: http.StatusNotFound},
+	{name: "audit", method: http.MethodGet, path: "/poll/4/audit", status: http.StatusOK},
+
+	{name: "export definition", method: http.MethodGet, path: "/poll/4/definition", status: http.StatusOK},
+	{name: "export definition as yaml", method: http.MethodGet, path: "/poll/4/definition?format=yaml", status: http.StatusOK},
+	{name: "export definition in unknown format", method: http.MethodGet, path: "/poll/4/definition?format=xml", status: http.StatusBadRequest},
+	{name: "import definition", method: http.MethodPost, path: "/polls/import", body: `{"title":"Imported","url":"imported","questions":[{"text":"Q","type":"single","options":["A","B"]}]}`, status: http.StatusOK},
+	{name: "import yaml definitions", method: http.MethodPost, path: "/polls/import", contentType: "application/yaml", body: "- title: Y\n  url: y\n  settings:\n    publish: true\n", status: http.StatusOK},
+	{name: "import invalid definition", method: http.MethodPost, path: "/polls/import", body: `[{"title":""}]`, status: http.StatusBadRequest},
+	{name: "clone", method: http.MethodPost, path: "/poll/4/clone", body: `{"url":"contract-copy"}`, status: http.StatusOK},
+
+	{name: "create template", method: http.MethodPost, path: "/templates", body: `{"name":"contract","poll_id":4}`, status: http.StatusOK},
+	{name: "create duplicate template", method: http.MethodPost, path: "/templates", body: `{"name":"contract","poll_id":4}`, status: http.StatusConflict},
+	{name: "templates", method: http.MethodGet, path: "/templates", status: http.StatusOK},
+	{name: "template", method: http.MethodGet, path: "/templates/1", status: http.StatusOK},
+	{name: "instantiate template", method: http.MethodPost, path: "/templates/1/instantiate", body: `{"url":"from-template","publish":true}`, status: http.StatusOK},
+	{name: "delete template", method: http.MethodDelete, path: "/templates/1", status: http.StatusOK},
+	{name: "missing template", method: http.MethodGet, path: "/templates/1", status: http.StatusNotFound},
+	{name: "deleted template audit", method: http.MethodGet, path: "/templates/1/audit", status: http.StatusOK},
+	{name: "missing template audit", method: http.MethodGet, path: "/templates/2/audit", status: http.StatusNotFound},
+
+	{name: "delete question", method: http.MethodDelete, path: "/poll/4/question/10", status: http.StatusOK},
+	{name: "delete poll", method: http.MethodDelete, path: "/poll/4", status: http.StatusOK},
+
+	{name: "seed", method: http.MethodPost, path: "/seed", body: `{"seed":7,"polls":1,"questions":2,"options":3,"respondents":4,"distribution":"skewed","publish":true}`, status: http.StatusOK},
+	{name: "seed with too few options", method: http.MethodPost, path: "/seed", body: `{"options":1}`, status: http.StatusBadRequest},
+}
+
+// startIngest включает асинхронный прием ответов и возвращает функцию, которая
+// выключает его, дождавшись сохранения принятых ответов
+func startIngest() func() {
+	cfg := config.Default().Ingest
+	cfg.Enabled = true
+	run := service.StartIngest(cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		run(ctx)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// allRoutes включает все маршруты, которые регистрируются по настройкам
+var allRoutes = Options{Seed: true}
+
+// newTestRouter создает маршрутизатор API поверх пустой базы SQLite во временном каталоге.
+// route получает шаблон пути последнего обработанного запроса.
+func newTestRouter(t *testing.T, route *string, opts Options) *gin.Engine {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "poll.db") + "?_pragma=busy_timeout(5000)&_txlock=immediate"
+	database, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: gormlogger.Discard, TranslateError: true})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	sqlDB, err := database.DB()
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	db.DB = database
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(logger.RequestID(), func(c *gin.Context) {
+		*route = c.FullPath()
+		c.Next()
+	})
+	router.Use(handlers.ValidateIDs())
+	if err := Register(router, opts); err != nil {
+		t.Fatalf("register routes: %v", err)
+	}
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(handlers.NoRoute)
+	router.NoMethod(handlers.NoMethod)
+
+	return router
+}
+
+// TestRoutesMatchSpec проверяет, что каждая операция описания зарегистрирована под /api/v1
+// и каждый маршрут /api/v1 описан
+func TestRoutesMatchSpec(t *testing.T) {
+	doc, err := Spec(allRoutes)
+	if err != nil {
+		t.Fatalf("build spec: %v", err)
+	}
+
+	var route string
+	router := newTestRouter(t, &route, allRoutes)
+
+	var registered []string
+	for _, info := range router.Routes() {
+		path, ok := strings.CutPrefix(info.Path, Prefix)
+		if !ok || path == "/openapi.json" || strings.HasPrefix(path, "/docs/") {
+			continue
+		}
+		registered = append(registered, info.Method+" "+openapi.Path(path))
+	}
+	sort.Strings(registered)
+
+	documented := doc.Methods()
+	if strings.Join(registered, "\n") != strings.Join(documented, "\n") {
+		t.Fatalf("registered routes differ from the spec\nregistered:\n%s\n\ndocumented:\n%s",
+			strings.Join(registered, "\n"), strings.Join(documented, "\n"))
+	}
+}
+
+// TestContract выполняет запросы ко всем операциям API и проверяет, что код ответа
+// задокументирован, а тело ответа соответствует схеме описания без лишних полей
+func TestContract(t *testing.T) {
+	doc, err := Spec(allRoutes)
+	if err != nil {
+		t.Fatalf("build spec: %v", err)
+	}
+
+	var route string
+	router := newTestRouter(t, &route, allRoutes)
+
+	covered := make(map[string]bool)
+	// receipt квитанция последнего ответа 202, подставляется в путь вместо {receipt}
+	var receipt string
+	for _, tc := range contractCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.async {
+				defer startIngest()()
+			}
+
+			path := strings.ReplaceAll(tc.path, "{receipt}", receipt)
+			req := httptest.NewRequest(tc.method, Prefix+path, strings.NewReader(tc.body))
+			if tc.body != "" {
+				contentType := tc.contentType
+				if contentType == "" {
+					contentType = openapi.JSON
+				}
+				req.Header.Set("Content-Type", contentType)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tc.status {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tc.status, rec.Body.String())
+			}
+
+			ginPath := strings.TrimPrefix(route, Prefix)
+			schema, err := doc.ResponseSchema(tc.method, ginPath, rec.Code, rec.Header().Get("Content-Type"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if schema != nil {
+				body, err := responseJSON(rec)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := doc.Validate(schema, body); err != nil {
+					t.Fatalf("response does not match the spec: %v\nbody: %s", err, rec.Body.String())
+				}
+			}
+
+			if rec.Code < http.StatusBadRequest {
+				covered[tc.method+" "+openapi.Path(ginPath)] = true
+			}
+			if rec.Code == http.StatusAccepted {
+				var accepted api.ReceiptResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &accepted); err != nil {
+					t.Fatal(err)
+				}
+				receipt = accepted.ID
+			}
+		})
+	}
+
+	for _, operation := range doc.Methods() {
+		if !covered[operation] {
+			t.Errorf("operation %s has no successful contract case", operation)
+		}
+	}
+}
+
+// TestSeedRouteDisabled проверяет, что без настройки POST /seed не регистрируется и не описывается
+func TestSeedRouteDisabled(t *testing.T) {
+	doc, err := Spec(Options{})
+	if err != nil {
+		t.Fatalf("build spec: %v", err)
+	}
+	for _, operation := range doc.Methods() {
+		if operation == "POST /seed" {
+			t.Errorf("spec documents %s", operation)
+		}
+	}
+
+	var route string
+	router := newTestRouter(t, &route, Options{})
+	for _, path := range []string{Prefix + "/seed", "/seed"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{}`)))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("POST %s status = %d, want %d", path, rec.Code, http.StatusNotFound)
+		}
+	}
+}
+
+// TestLegacyRoutes проверяет, что маршруты без версии работают и помечены устаревшими
+func TestLegacyRoutes(t *testing.T) {
+	var route string
+	router := newTestRouter(t, &route, allRoutes)
+
+	for _, tc := range []struct {
+		method string
+		path   string
+		status int
+	}{
+		{method: http.MethodPost, path: "/migrate", status: http.StatusOK},
+		{method: http.MethodGet, path: "/poll/1", status: http.StatusOK},
+		{method: http.MethodGet, path: "/poll/999", status: http.StatusNotFound},
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+
+		if rec.Code != tc.status {
+			t.Fatalf("%s %s: status = %d, want %d", tc.method, tc.path, rec.Code, tc.status)
+		}
+		if got := rec.Header().Get("Deprecation"); got != "true" {
+			t.Errorf("%s %s: Deprecation = %q, want true", tc.method, tc.path, got)
+		}
+		if got, want := rec.Header().Get("Link"), "<"+Prefix+tc.path+`>; rel="successor-version"`; got != want {
+			t.Errorf("%s %s: Link = %q, want %q", tc.method, tc.path, got, want)
+		}
+	}
+
+	// Маршруты API версии 1 устаревшими не помечаются
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Prefix+"/poll/1", nil))
+	if got := rec.Header().Get("Deprecation"); got != "" {
+		t.Errorf("GET %s/poll/1: Deprecation = %q, want none", Prefix, got)
+	}
+}
+
+// TestSpecAndDocs проверяет, что описание и Swagger UI отдаются
+func TestSpecAndDocs(t *testing.T) {
+	var route string
+	router := newTestRouter(t, &route, allRoutes)
+
+	for _, tc := range []struct {
+		path        string
+		contentType string
+	}{
+		{path: Prefix + "/openapi.json", contentType: "application/json"},
+		{path: Prefix + "/docs/", contentType: "text/html"},
+		{path: Prefix + "/docs/swagger-ui-bundle.js", contentType: "javascript"},
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s: status = %d, want %d", tc.path, rec.Code, http.StatusOK)
+			continue
+		}
+		if !strings.Contains(rec.Header().Get("Content-Type"), tc.contentType) {
+			t.Errorf("GET %s: Content-Type = %q, want %s", tc.path, rec.Header().Get("Content-Type"), tc.contentType)
+		}
+	}
+}
+
+// responseJSON возвращает тело ответа в JSON; ответ в YAML переводится в JSON
+func responseJSON(rec *httptest.ResponseRecorder) ([]byte, error) {
+	if !strings.Contains(rec.Header().Get("Content-Type"), "yaml") {
+		return rec.Body.Bytes(), nil
+	}
+
+	var value interface{}
+	if err := yaml.Unmarshal(rec.Body.Bytes(), &value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
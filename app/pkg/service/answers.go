@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"service-poll/pkg/api"
+	"service-poll/pkg/apperror"
+	"service-poll/pkg/db"
+	"service-poll/pkg/metrics"
+	"service-poll/pkg/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RegisterAnswer регистрирует ответы пользователя на вопросы опроса.
+// Ответы проверяются целиком и сохраняются в одной транзакции.
+func RegisterAnswer(ctx context.Context, pollID uint, request api.AnswerRequest) (api.AnswerResponse, error) {
+	// Проверяем, существует ли опрос с указанным идентификатором
+	existingPoll, err := findPoll(ctx, pollID)
+	if err != nil {
+		metrics.AnswerValidationFailed(metrics.ReasonPollNotFound)
+		return api.AnswerResponse{}, err
+	}
+
+	if err := Validate(request); err != nil {
+		metrics.AnswerValidationFailed(metrics.ReasonInvalidRequest)
+		return api.AnswerResponse{}, err
+	}
+
+	// Загружаем вопросы, на которые принимаются ответы: из актуальной ревизии или из опроса
+	questions, revisionID, err := loadAnswerQuestions(ctx, existingPoll)
+	if err != nil {
+		return api.AnswerResponse{}, apperror.Internal("Failed to load poll questions", err)
+	}
+
+	// Сначала проверяем все ответы, чтобы не сохранить их частично
+	newAnswers, err := buildAnswers(existingPoll.ID, questions, revisionID, request.Attributes)
+	if err != nil {
+		return api.AnswerResponse{}, err
+	}
+
+	// Сохраняем ответы и их связи с вариантами ответов в одной транзакции. Вопросы
+	// проверены по ревизии опроса, поэтому в транзакции повторно проверяем, что опрос,
+	// вопросы и варианты не удалены, и блокируем их до сохранения ответов.
+	if err := db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		targets, err := lockAnswerTargets(tx, []uint{existingPoll.ID}, newAnswers)
+		if err != nil {
+			return err
+		}
+		if err := targets.check(existingPoll.ID, newAnswers); err != nil {
+			return err
+		}
+		return saveAnswers(tx, newAnswers)
+	}); err != nil {
+		var appErr *apperror.Error
+		if errors.As(err, &appErr) {
+			return api.AnswerResponse{}, appErr
+		}
+		return api.AnswerResponse{}, apperror.Internal("Failed to register answer", err)
+	}
+
+	metrics.AnswersRegistered(existingPoll.ID, metrics.AnswersSync, len(newAnswers))
+	invalidateResults(ctx, existingPoll.ID, existingPoll.Revision)
+	notifyResults(existingPoll.ID)
+
+	var answerResponse api.AnswerResponse
+	answerResponse.AccessToken = request.Attributes.AccessToken
+	answerResponse.PollID = existingPoll.ID
+	answerResponse.UserID = request.Attributes.UserID
+
+	// Добавляем результаты в структуру ответа
+	for _, newAnswer := range newAnswers {
+		answerResponse.Results = append(answerResponse.Results, api.RegisteredAnswer{
+			QuestionID: newAnswer.QuestionID,
+			Answers:    newAnswer.possibleAnswerIDs,
+		})
+	}
+
+	return answerResponse, nil
+}
+
+// newAnswer ответ на вопрос с выбранными вариантами, проверенный, но еще не сохраненный
+type newAnswer struct {
+	models.Answer
+	possibleAnswerIDs []uint
+}
+
+// buildAnswers проверяет ответы пользователя по вопросам опроса и составляет записи ответов.
+// Проверка не обращается к базе данных, поэтому ее выполняет и асинхронный прием
+// по структуре опроса из кэша.
+func buildAnswers(pollID uint, questions map[string]answerQuestion, revisionID *uint, attributes api.AnswerAttributes) ([]newAnswer, error) {
+	answers := make([]newAnswer, 0, len(attributes.Results))
+	for i, result := range attributes.Results {
+		field := fmt.Sprintf("attributes.results[%d]", i)
+
+		// Проверяем, существует ли вопрос с указанным текстом в опросе
+		existingQuestion, ok := questions[result.Question]
+		if !ok {
+			metrics.AnswerValidationFailed(metrics.ReasonQuestionNotFound)
+			return nil, apperror.Invalid(field+".question", "not_found", "Question not found (%s)", result.Question)
+		}
+
+		// Проверяем тип вопроса
+		if existingQuestion.Type == "single" && strings.Count(result.Answer, ",") > 0 {
+			metrics.AnswerValidationFailed(metrics.ReasonSingleChoice)
+			return nil, apperror.Invalid(field+".answer", "single_choice", "For single type question, only one answer is allowed")
+		} else if existingQuestion.Type == "multiple" && strings.Count(result.Answer, ",") == 0 {
+			metrics.AnswerValidationFailed(metrics.ReasonMultipleChoice)
+			return nil, apperror.Invalid(field+".answer", "multiple_choice", "For multiple type question, at least two answers are required")
+		}
+
+		// Собираем все возможные ответы для данного вопроса; несколько вариантов разделены запятыми
+		var possibleAnswerIDs []uint
+		for _, answerText := range strings.Split(result.Answer, ",") {
+			answerText = strings.TrimSpace(answerText)
+			// Проверяем, существует ли вариант ответа для данного вопроса
+			possibleAnswerID, ok := existingQuestion.Options[answerText]
+			if !ok {
+				metrics.AnswerValidationFailed(metrics.ReasonOptionNotFound)
+				return nil, apperror.Invalid(field+".answer", "not_found", "Possible Answer (%s) not found for the specified question (%s)", answerText, result.Question)
+			}
+			possibleAnswerIDs = append(possibleAnswerIDs, possibleAnswerID)
+		}
+
+		// Ответ связан с ревизией опроса, по которой он был дан
+		answers = append(answers, newAnswer{
+			Answer: models.Answer{
+				UserID:      attributes.UserID,
+				AccessToken: attributes.AccessToken,
+				QuestionID:  existingQuestion.ID,
+				PollID:      pollID,
+				RevisionID:  revisionID,
+			},
+			possibleAnswerIDs: possibleAnswerIDs,
+		})
+	}
+	return answers, nil
+}
+
+// answerTargets неудаленные опросы, вопросы и варианты, на которые ссылаются ответы
+type answerTargets struct {
+	polls     map[uint]bool
+	questions map[uint]bool
+	options   map[uint]bool
+}
+
+// lockAnswerTargets находит неудаленные опросы pollIDs, вопросы и варианты ответов answers
+// и блокирует их от изменения до конца транзакции tx
+func lockAnswerTargets(tx *gorm.DB, pollIDs []uint, answers []newAnswer) (answerTargets, error) {
+	var questionIDs, optionIDs []uint
+	for _, answer := range answers {
+		questionIDs = append(questionIDs, answer.QuestionID)
+		optionIDs = append(optionIDs, answer.possibleAnswerIDs...)
+	}
+
+	var targets answerTargets
+	var err error
+	if targets.polls, err = existingIDs(tx, &models.Poll{}, pollIDs); err != nil {
+		return answerTargets{}, err
+	}
+	if targets.questions, err = existingIDs(tx, &models.Question{}, questionIDs); err != nil {
+		return answerTargets{}, err
+	}
+	if targets.options, err = existingIDs(tx, &models.PossibleAnswer{}, optionIDs); err != nil {
+		return answerTargets{}, err
+	}
+	return targets, nil
+}
+
+// check проверяет, что опрос pollID и вопросы и варианты ответов answers не удалены.
+// Ответы идут в порядке attributes.results запроса, по нему составляются поля ошибки.
+func (t answerTargets) check(pollID uint, answers []newAnswer) error {
+	if !t.polls[pollID] {
+		metrics.AnswerValidationFailed(metrics.ReasonPollNotFound)
+		return apperror.ErrPollNotFound
+	}
+	for i, answer := range answers {
+		field := fmt.Sprintf("attributes.results[%d]", i)
+		if !t.questions[answer.QuestionID] {
+			metrics.AnswerValidationFailed(metrics.ReasonQuestionNotFound)
+			return apperror.Invalid(field+".question", "not_found", "Question was deleted")
+		}
+		for _, possibleAnswerID := range answer.possibleAnswerIDs {
+			if !t.options[possibleAnswerID] {
+				metrics.AnswerValidationFailed(metrics.ReasonOptionNotFound)
+				return apperror.Invalid(field+".answer", "not_found", "Possible Answer was deleted from the question")
+			}
+		}
+	}
+	return nil
+}
+
+// existingIDs возвращает идентификаторы неудаленных записей модели model из ids
+// и блокирует эти записи от изменения до конца транзакции tx
+func existingIDs(tx *gorm.DB, model interface{}, ids []uint) (map[uint]bool, error) {
+	existing := make(map[uint]bool, len(ids))
+	if len(ids) == 0 {
+		return existing, nil
+	}
+
+	var found []uint
+	if err := tx.Model(model).Clauses(clause.Locking{Strength: "SHARE"}).Where("id IN ?", ids).Pluck("id", &found).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range found {
+		existing[id] = true
+	}
+	return existing, nil
+}
+
+// saveAnswers сохраняет ответы и их связи с вариантами ответа пачками в транзакции tx
+// и увеличивает счетчики результатов
+func saveAnswers(tx *gorm.DB, answers []newAnswer) error {
+	if len(answers) == 0 {
+		return nil
+	}
+
+	records := make([]models.Answer, len(answers))
+	for i := range answers {
+		records[i] = answers[i].Answer
+	}
+	if err := tx.CreateInBatches(&records, answerBatchSize).Error; err != nil {
+		return err
+	}
+
+	var links []models.AnswerPossibleAnswer
+	deltas := newCounterDeltas()
+	for i := range answers {
+		for _, possibleAnswerID := range answers[i].possibleAnswerIDs {
+			links = append(links, models.AnswerPossibleAnswer{AnswerID: records[i].ID, PossibleAnswerID: possibleAnswerID})
+		}
+		deltas.add(answers[i].PollID, answers[i].QuestionID, answers[i].possibleAnswerIDs)
+	}
+	if len(links) > 0 {
+		if err := tx.CreateInBatches(&links, answerBatchSize).Error; err != nil {
+			return err
+		}
+	}
+	return deltas.apply(tx)
+}
+
+// answerBatchSize количество строк в одном запросе INSERT при сохранении ответов
+const answerBatchSize = 500
+
+// answerQuestion вопрос, на который принимаются ответы
+type answerQuestion struct {
+	ID      uint
+	Type    string
+	Options map[string]uint // текст варианта ответа -> идентификатор варианта
+}
+
+// loadAnswerQuestions возвращает вопросы опроса по их тексту. Если опрос опубликован,
+// вопросы и варианты берутся из актуальной ревизии, и возвращается ее идентификатор,
+// иначе — из текущего состояния опроса.
+func loadAnswerQuestions(ctx context.Context, poll models.Poll) (map[string]answerQuestion, *uint, error) {
+	questions := make(map[string]answerQuestion)
+
+	if poll.Revision > 0 {
+		revision, err := findRevision(ctx, poll.ID, poll.Revision)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, question := range revision.Questions {
+			if _, ok := questions[question.Text]; ok {
+				continue
+			}
+			options := make(map[string]uint, len(question.Options))
+			for _, option := range question.Options {
+				if _, ok := options[option.Text]; !ok {
+					options[option.Text] = option.PossibleAnswerID
+				}
+			}
+			questions[question.Text] = answerQuestion{ID: question.QuestionID, Type: question.Type, Options: options}
+		}
+
+		return questions, &revision.ID, nil
+	}
+
+	var existingQuestions []models.Question
+	if err := db.DB.WithContext(ctx).Preload("PossibleAnswer", func(tx *gorm.DB) *gorm.DB { return tx.Order("position, id") }).
+		Where("poll_id = ?", poll.ID).Order("id").Find(&existingQuestions).Error; err != nil {
+		return nil, nil, err
+	}
+
+	for _, question := range existingQuestions {
+		if _, ok := questions[question.Text]; ok {
+			continue
+		}
+		options := make(map[string]uint, len(question.PossibleAnswer))
+		for _, possibleAnswer := range question.PossibleAnswer {
+			if _, ok := options[possibleAnswer.Text]; !ok {
+				options[possibleAnswer.Text] = possibleAnswer.ID
+			}
+		}
+		questions[question.Text] = answerQuestion{ID: question.ID, Type: question.Type, Options: options}
+	}
+
+	return questions, nil, nil
+}
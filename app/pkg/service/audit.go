@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"service-poll/pkg/api"
+	"service-poll/pkg/apperror"
+	"service-poll/pkg/db"
+	"service-poll/pkg/models"
+)
+
+// PollAudit возвращает журнал изменений опроса, в том числе удаленного, от новых записей
+// к старым. Если questionID не nil, возвращаются только записи этого вопроса.
+func PollAudit(ctx context.Context, pollID uint, questionID *uint) (api.AuditResponse, error) {
+	// Проверяем, существует ли опрос с указанным идентификатором (в том числе удаленный)
+	var existingPoll models.Poll
+	if err := db.DB.WithContext(ctx).Unscoped().First(&existingPoll, pollID).Error; err != nil {
+		return api.AuditResponse{}, LookupError(err, apperror.ErrPollNotFound)
+	}
+
+	// Выбираем записи журнала, при необходимости только по одному вопросу
+	query := db.DB.WithContext(ctx).Where("poll_id = ?", existingPoll.ID)
+	if questionID != nil {
+		query = query.Where("question_id = ?", *questionID)
+	}
+
+	var auditLogs []models.AuditLog
+	if err := query.Order("created_at DESC, id DESC").Find(&auditLogs).Error; err != nil {
+		return api.AuditResponse{}, apperror.Internal("Failed to fetch audit log", err)
+	}
+
+	return api.AuditResponse{PollID: existingPoll.ID, Entries: auditEntries(auditLogs)}, nil
+}
+
+// auditEntries формирует записи журнала для ответа
+func auditEntries(auditLogs []models.AuditLog) []api.AuditEntry {
+	entries := make([]api.AuditEntry, 0, len(auditLogs))
+	for _, auditLog := range auditLogs {
+		entries = append(entries, api.AuditEntry{
+			ID:         auditLog.ID,
+			QuestionID: auditLog.QuestionID,
+			Actor:      auditLog.Actor,
+			Method:     auditLog.Method,
+			Endpoint:   auditLog.Endpoint,
+			Action:     auditLog.Action,
+			Diff:       json.RawMessage(auditLog.Diff),
+			CreatedAt:  auditLog.CreatedAt,
+		})
+	}
+	return entries
+}
+
+// auditQuestion вопрос в записи журнала аудита
+type auditQuestion struct {
+	ID      uint          `json:"id"`
+	Text    string        `json:"text"`
+	Type    string        `json:"type"`
+	Options []auditOption `json:"options"`
+}
+
+// auditPoll формирует описание созданного опроса для журнала аудита: заголовок, адрес,
+// вопросы с вариантами ответов и номер ревизии, если опрос сразу опубликован
+func auditPoll(poll models.Poll) map[string]interface{} {
+	questions := make([]auditQuestion, 0, len(poll.Questions))
+	for _, question := range poll.Questions {
+		questions = append(questions, auditQuestion{
+			ID:      question.ID,
+			Text:    question.Text,
+			Type:    question.Type,
+			Options: auditOptions(question.PossibleAnswer),
+		})
+	}
+
+	fields := map[string]interface{}{
+		"title":     poll.Title,
+		"url":       poll.URL,
+		"questions": questions,
+	}
+	if poll.Revision > 0 {
+		fields["revision"] = poll.Revision
+	}
+	return fields
+}
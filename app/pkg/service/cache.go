@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"service-poll/pkg/cache"
+	"service-poll/pkg/logger"
+	"service-poll/pkg/metrics"
+	"service-poll/pkg/models"
+)
+
+// responseCache кэш описаний опросов и результатов
+type responseCache struct {
+	store cache.Cache
+	ttl   time.Duration
+}
+
+// caching текущий кэш; nil — кэш выключен
+var caching atomic.Pointer[responseCache]
+
+// invalidations количество сбросов кэша в этом процессе. Значение, вычисленное
+// до сброса, не сохраняется в кэш, чтобы не вернуть устаревшие данные.
+var invalidations atomic.Uint64
+
+// SetCache включает кэш описаний опросов и результатов в хранилище store со сроком
+// хранения ttl; nil выключает кэш. Ошибки хранилища не прерывают запросы: при ошибке
+// данные читаются из базы.
+func SetCache(store cache.Cache, ttl time.Duration) {
+	if store == nil {
+		caching.Store(nil)
+		return
+	}
+	caching.Store(&responseCache{store: store, ttl: ttl})
+}
+
+// cached возвращает значение по ключу key из кэша или вычисляет его load и сохраняет
+// в кэш. Ошибки load не кэшируются.
+func cached[T any](ctx context.Context, kind, key string, load func() (T, error)) (T, error) {
+	c := caching.Load()
+	if c == nil {
+		return load()
+	}
+
+	body, ok, err := c.store.Get(ctx, key)
+	if err != nil {
+		cacheError(ctx, "read", key, err)
+	} else if ok {
+		var value T
+		if err := json.Unmarshal(body, &value); err == nil {
+			metrics.CacheRequest(kind, true)
+			return value, nil
+		}
+	}
+	metrics.CacheRequest(kind, false)
+
+	generation := invalidations.Load()
+	value, err := load()
+	if err != nil {
+		return value, err
+	}
+	if body, err := json.Marshal(value); err == nil && invalidations.Load() == generation {
+		if err := c.store.Set(ctx, key, body, c.ttl); err != nil {
+			cacheError(ctx, "write", key, err)
+		}
+	}
+	return value, nil
+}
+
+// Ключи кэша
+func pollCacheKey(pollID uint) string {
+	return fmt.Sprintf("poll:%d", pollID)
+}
+
+// resultsCacheKey ключ результатов по текущим вопросам (revision пусто), по ревизии
+// с номером revision или объединенных по ревизиям (revision равно MergedRevision)
+func resultsCacheKey(pollID uint, revision string) string {
+	if revision == "" {
+		return fmt.Sprintf("results:%d", pollID)
+	}
+	return fmt.Sprintf("results:%d:%s", pollID, revision)
+}
+
+// invalidatePoll сбрасывает описание опроса и все его результаты после изменения
+// опроса, его вопросов или публикации ревизии
+func invalidatePoll(ctx context.Context, poll models.Poll) {
+	keys := []string{pollCacheKey(poll.ID), resultsCacheKey(poll.ID, ""), resultsCacheKey(poll.ID, MergedRevision)}
+	for revision := uint(1); revision <= poll.Revision; revision++ {
+		keys = append(keys, resultsCacheKey(poll.ID, strconv.FormatUint(uint64(revision), 10)))
+	}
+	invalidate(ctx, keys...)
+}
+
+// invalidateResults сбрасывает результаты, которые меняют новые ответы: по текущим
+// вопросам, объединенные и по ревизии revision, на которую отвечали (0 — опрос не опубликован)
+func invalidateResults(ctx context.Context, pollID uint, revision uint) {
+	keys := []string{resultsCacheKey(pollID, ""), resultsCacheKey(pollID, MergedRevision)}
+	if revision > 0 {
+		keys = append(keys, resultsCacheKey(pollID, strconv.FormatUint(uint64(revision), 10)))
+	}
+	invalidate(ctx, keys...)
+}
+
+// invalidate удаляет ключи из кэша. Если удалить не удалось, устаревшие данные
+// возвращаются до истечения срока хранения.
+func invalidate(ctx context.Context, keys ...string) {
+	invalidations.Add(1)
+
+	c := caching.Load()
+	if c == nil {
+		return
+	}
+	if err := c.store.Delete(ctx, keys...); err != nil {
+		cacheError(ctx, "invalidate", keys[0], err)
+	}
+}
+
+// cacheError пишет в журнал ошибку хранилища кэша. Недоступность Redis не пишется
+// на каждый запрос: хранилище само сообщает, когда сервер пропал и когда вернулся.
+func cacheError(ctx context.Context, operation, key string, err error) {
+	if errors.Is(err, cache.ErrUnavailable) {
+		return
+	}
+	logger.FromContext(ctx).WarnContext(ctx, "cache "+operation+" failed",
+		slog.String("key", key), slog.String("error", err.Error()))
+}
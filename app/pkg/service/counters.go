@@ -0,0 +1,373 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sort"
+	"time"
+
+	"service-poll/pkg/config"
+	"service-poll/pkg/db"
+	"service-poll/pkg/health"
+	"service-poll/pkg/logger"
+	"service-poll/pkg/metrics"
+	"service-poll/pkg/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Счетчики результатов (question_counters и option_counters) хранят количество ответов
+// на вопросы и голосов за варианты. Они увеличиваются в одной транзакции с ответами
+// и удаляются вместе с вопросами и вариантами, поэтому результаты опроса читаются
+// из счетчиков, а не пересчитываются по answer_possible_answers. ReconcileCounters
+// пересчитывает счетчики по сырым данным и исправляет расхождения.
+
+// counterDeltas прирост счетчиков результатов
+type counterDeltas struct {
+	questions map[uint]models.QuestionCounter // вопрос -> количество новых ответов
+	options   map[uint]models.OptionCounter   // вариант ответа -> количество новых голосов
+}
+
+func newCounterDeltas() counterDeltas {
+	return counterDeltas{questions: make(map[uint]models.QuestionCounter), options: make(map[uint]models.OptionCounter)}
+}
+
+// add учитывает ответ на вопрос questionID опроса pollID с выбранными вариантами
+func (d counterDeltas) add(pollID, questionID uint, possibleAnswerIDs []uint) {
+	question := d.questions[questionID]
+	question.QuestionID, question.PollID = questionID, pollID
+	question.Answers++
+	d.questions[questionID] = question
+
+	for _, possibleAnswerID := range possibleAnswerIDs {
+		option := d.options[possibleAnswerID]
+		option.PossibleAnswerID, option.QuestionID = possibleAnswerID, questionID
+		option.Votes++
+		d.options[possibleAnswerID] = option
+	}
+}
+
+// apply прибавляет прирост к счетчикам в транзакции tx; недостающие счетчики создаются.
+// Счетчики обновляются в порядке идентификаторов, чтобы параллельные транзакции
+// не блокировали друг друга. Прирост применяется последним в транзакции, поэтому строки
+// счетчиков заблокированы только до ее фиксации. Одновременные ответы на один опрос
+// ждут друг друга на этих строках; асинхронный прием сохраняет ответы пачками и обновляет
+// каждый счетчик один раз на пачку, поэтому для популярных опросов его стоит включать.
+func (d counterDeltas) apply(tx *gorm.DB) error {
+	if len(d.questions) > 0 {
+		questions := sortedCounters(d.questions)
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "question_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"answers": gorm.Expr("question_counters.answers + excluded.answers"), "updated_at": gorm.Expr("excluded.updated_at")}),
+		}).CreateInBatches(&questions, answerBatchSize).Error; err != nil {
+			return err
+		}
+	}
+
+	if len(d.options) > 0 {
+		options := sortedCounters(d.options)
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "possible_answer_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"votes": gorm.Expr("option_counters.votes + excluded.votes"), "updated_at": gorm.Expr("excluded.updated_at")}),
+		}).CreateInBatches(&options, answerBatchSize).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortedCounters возвращает счетчики в порядке идентификаторов
+func sortedCounters[T any](counters map[uint]T) []T {
+	ids := make([]uint, 0, len(counters))
+	for id := range counters {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	sorted := make([]T, len(ids))
+	for i, id := range ids {
+		sorted[i] = counters[id]
+	}
+	return sorted
+}
+
+// CountAnswers увеличивает счетчики результатов на сохраненные ответы answers с выбранными
+// вариантами (PossibleAnswers) в транзакции tx. Используется там, где ответы создаются
+// в обход RegisterAnswer, например фикстурами.
+func CountAnswers(tx *gorm.DB, answers []models.Answer) error {
+	deltas := newCounterDeltas()
+	for _, answer := range answers {
+		possibleAnswerIDs := make([]uint, len(answer.PossibleAnswers))
+		for i, possibleAnswer := range answer.PossibleAnswers {
+			possibleAnswerIDs[i] = possibleAnswer.ID
+		}
+		deltas.add(answer.PollID, answer.QuestionID, possibleAnswerIDs)
+	}
+	return deltas.apply(tx)
+}
+
+// deleteQuestionCounters удаляет счетчики вопросов и их вариантов в транзакции tx
+func deleteQuestionCounters(tx *gorm.DB, questionIDs []uint) error {
+	if len(questionIDs) == 0 {
+		return nil
+	}
+	if err := tx.Where("question_id IN ?", questionIDs).Delete(&models.OptionCounter{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("question_id IN ?", questionIDs).Delete(&models.QuestionCounter{}).Error
+}
+
+// deleteOptionCounters удаляет счетчики вариантов ответа в транзакции tx
+func deleteOptionCounters(tx *gorm.DB, possibleAnswerIDs []uint) error {
+	if len(possibleAnswerIDs) == 0 {
+		return nil
+	}
+	return tx.Where("possible_answer_id IN ?", possibleAnswerIDs).Delete(&models.OptionCounter{}).Error
+}
+
+// QuestionCounts количество ответов на вопрос и голосов за его варианты
+type QuestionCounts struct {
+	Answers int
+	Votes   map[uint]int // вариант ответа -> количество голосов
+}
+
+// LoadCounts возвращает счетчики результатов вопросов questionIDs; у вопросов без ответов
+// счетчики нулевые
+func LoadCounts(ctx context.Context, questionIDs []uint) (map[uint]QuestionCounts, error) {
+	counts := make(map[uint]QuestionCounts, len(questionIDs))
+	for _, id := range questionIDs {
+		counts[id] = QuestionCounts{Votes: map[uint]int{}}
+	}
+	if len(questionIDs) == 0 {
+		return counts, nil
+	}
+
+	var questions []models.QuestionCounter
+	if err := db.DB.WithContext(ctx).Where("question_id IN ?", questionIDs).Find(&questions).Error; err != nil {
+		return nil, err
+	}
+	var options []models.OptionCounter
+	if err := db.DB.WithContext(ctx).Where("question_id IN ?", questionIDs).Find(&options).Error; err != nil {
+		return nil, err
+	}
+
+	for _, counter := range questions {
+		questionCounts := counts[counter.QuestionID]
+		questionCounts.Answers = counter.Answers
+		counts[counter.QuestionID] = questionCounts
+	}
+	for _, counter := range options {
+		if questionCounts, ok := counts[counter.QuestionID]; ok {
+			questionCounts.Votes[counter.PossibleAnswerID] = counter.Votes
+		}
+	}
+	return counts, nil
+}
+
+// CounterDrift расхождение счетчика с пересчетом по сырым данным
+type CounterDrift struct {
+	Counter    string // metrics.CounterQuestion или metrics.CounterOption
+	ID         uint   // идентификатор вопроса или варианта ответа
+	QuestionID uint   // вопрос, к которому относится счетчик
+	Stored     int    // значение счетчика
+	Actual     int    // значение по ответам
+}
+
+// ReconcileReport результат сверки счетчиков
+type ReconcileReport struct {
+	Questions int // количество проверенных счетчиков вопросов
+	Options   int // количество проверенных счетчиков вариантов ответа
+	Drift     []CounterDrift
+}
+
+// ReconcileCounters пересчитывает счетчики результатов по ответам и связям
+// answer_possible_answers, исправляет расхождения и возвращает их. Сырые данные
+// и счетчики читаются в одной транзакции из одного снимка базы, поэтому ответы,
+// сохраненные во время сверки, не считаются расхождением: транзакция, изменившая
+// счетчик после снимка, прерывает сверку ошибкой, и она повторяется в следующий раз.
+func ReconcileCounters(ctx context.Context) (ReconcileReport, error) {
+	var report ReconcileReport
+	err := db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		report, err = reconcileCounters(tx)
+		return err
+	}, snapshotTx())
+	if err != nil {
+		return ReconcileReport{}, err
+	}
+
+	if len(report.Drift) == 0 {
+		return report, nil
+	}
+
+	// Сбрасываем результаты опросов, счетчики которых исправлены
+	questionIDs := make([]uint, 0, len(report.Drift))
+	for _, drift := range report.Drift {
+		metrics.CounterDrift(drift.Counter)
+		questionIDs = append(questionIDs, drift.QuestionID)
+	}
+	var pollIDs []uint
+	if err := db.DB.WithContext(ctx).Model(&models.Question{}).Unscoped().Distinct("poll_id").
+		Where("id IN ?", questionIDs).Pluck("poll_id", &pollIDs).Error; err != nil {
+		return report, err
+	}
+	for _, pollID := range pollIDs {
+		invalidate(ctx, resultsCacheKey(pollID, ""))
+	}
+
+	logger.FromContext(ctx).WarnContext(ctx, "result counters drifted from answers",
+		slog.Int("questions", report.Questions), slog.Int("options", report.Options),
+		slog.Int("drift", len(report.Drift)), slog.Any("first", report.Drift[0]))
+	return report, nil
+}
+
+// reconcileCounters сверяет и исправляет счетчики в транзакции tx
+func reconcileCounters(tx *gorm.DB) (ReconcileReport, error) {
+	// Ответы на существующие вопросы
+	var actualQuestions []models.QuestionCounter
+	if err := tx.Table("answers").
+		Select("answers.question_id AS question_id, questions.poll_id AS poll_id, COUNT(*) AS answers").
+		Joins("JOIN questions ON questions.id = answers.question_id AND questions.deleted_at IS NULL").
+		Where("answers.deleted_at IS NULL").
+		Group("answers.question_id, questions.poll_id").
+		Scan(&actualQuestions).Error; err != nil {
+		return ReconcileReport{}, err
+	}
+
+	// Голоса за существующие варианты в существующих ответах
+	var actualOptions []models.OptionCounter
+	if err := tx.Table("answer_possible_answers").
+		Select("answer_possible_answers.possible_answer_id AS possible_answer_id, possible_answers.question_id AS question_id, COUNT(*) AS votes").
+		Joins("JOIN answers ON answers.id = answer_possible_answers.answer_id AND answers.deleted_at IS NULL").
+		Joins("JOIN possible_answers ON possible_answers.id = answer_possible_answers.possible_answer_id AND possible_answers.deleted_at IS NULL").
+		Where("answer_possible_answers.deleted_at IS NULL").
+		Group("answer_possible_answers.possible_answer_id, possible_answers.question_id").
+		Scan(&actualOptions).Error; err != nil {
+		return ReconcileReport{}, err
+	}
+
+	var storedQuestions []models.QuestionCounter
+	if err := tx.Find(&storedQuestions).Error; err != nil {
+		return ReconcileReport{}, err
+	}
+	var storedOptions []models.OptionCounter
+	if err := tx.Find(&storedOptions).Error; err != nil {
+		return ReconcileReport{}, err
+	}
+
+	report := ReconcileReport{Questions: len(storedQuestions), Options: len(storedOptions)}
+
+	// Счетчики вопросов
+	questions := make(map[uint]models.QuestionCounter, len(storedQuestions))
+	for _, counter := range storedQuestions {
+		questions[counter.QuestionID] = counter
+	}
+	var fixQuestions []models.QuestionCounter
+	for _, counter := range actualQuestions {
+		if stored := questions[counter.QuestionID]; stored.Answers != counter.Answers {
+			report.Drift = append(report.Drift, CounterDrift{Counter: metrics.CounterQuestion, ID: counter.QuestionID, QuestionID: counter.QuestionID, Stored: stored.Answers, Actual: counter.Answers})
+			fixQuestions = append(fixQuestions, counter)
+		}
+		delete(questions, counter.QuestionID)
+	}
+	var staleQuestions []uint
+	for _, counter := range sortedCounters(questions) {
+		staleQuestions = append(staleQuestions, counter.QuestionID)
+		if counter.Answers != 0 {
+			report.Drift = append(report.Drift, CounterDrift{Counter: metrics.CounterQuestion, ID: counter.QuestionID, QuestionID: counter.QuestionID, Stored: counter.Answers})
+		}
+	}
+
+	// Счетчики вариантов ответа
+	options := make(map[uint]models.OptionCounter, len(storedOptions))
+	for _, counter := range storedOptions {
+		options[counter.PossibleAnswerID] = counter
+	}
+	var fixOptions []models.OptionCounter
+	for _, counter := range actualOptions {
+		if stored := options[counter.PossibleAnswerID]; stored.Votes != counter.Votes {
+			report.Drift = append(report.Drift, CounterDrift{Counter: metrics.CounterOption, ID: counter.PossibleAnswerID, QuestionID: counter.QuestionID, Stored: stored.Votes, Actual: counter.Votes})
+			fixOptions = append(fixOptions, counter)
+		}
+		delete(options, counter.PossibleAnswerID)
+	}
+	var staleOptions []uint
+	for _, counter := range sortedCounters(options) {
+		staleOptions = append(staleOptions, counter.PossibleAnswerID)
+		if counter.Votes != 0 {
+			report.Drift = append(report.Drift, CounterDrift{Counter: metrics.CounterOption, ID: counter.PossibleAnswerID, QuestionID: counter.QuestionID, Stored: counter.Votes})
+		}
+	}
+
+	// Исправляем расхождения: записываем пересчитанные значения и удаляем счетчики
+	// вопросов и вариантов, у которых ответов нет
+	if len(fixQuestions) > 0 {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "question_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"poll_id", "answers", "updated_at"}),
+		}).CreateInBatches(&fixQuestions, answerBatchSize).Error; err != nil {
+			return ReconcileReport{}, err
+		}
+	}
+	if len(staleQuestions) > 0 {
+		if err := tx.Where("question_id IN ?", staleQuestions).Delete(&models.QuestionCounter{}).Error; err != nil {
+			return ReconcileReport{}, err
+		}
+	}
+	if len(fixOptions) > 0 {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "possible_answer_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"question_id", "votes", "updated_at"}),
+		}).CreateInBatches(&fixOptions, answerBatchSize).Error; err != nil {
+			return ReconcileReport{}, err
+		}
+	}
+	if err := deleteOptionCounters(tx, staleOptions); err != nil {
+		return ReconcileReport{}, err
+	}
+
+	return report, nil
+}
+
+// snapshotTx параметры транзакции сверки. В PostgreSQL уровень REPEATABLE READ дает
+// один снимок для всех запросов; в SQLite транзакции и так сериализуются.
+func snapshotTx() *sql.TxOptions {
+	if db.DB.Dialector.Name() == "postgres" {
+		return &sql.TxOptions{Isolation: sql.LevelRepeatableRead}
+	}
+	return nil
+}
+
+// StartCounterReconciliation возвращает фоновую задачу, которая сверяет счетчики
+// результатов с ответами каждые cfg.ReconcileInterval. Задача учитывается в проверке
+// готовности: она считается зависшей, если сверка не завершалась дольше двух интервалов.
+// Время последней успешной сверки отдается в метрике.
+func StartCounterReconciliation(cfg config.CountersConfig) func(ctx context.Context) {
+	worker := health.RegisterWorker("counters", 2*cfg.ReconcileInterval.Duration)
+	return func(ctx context.Context) {
+		worker.Start()
+		defer func() {
+			worker.Stop()
+			health.UnregisterWorker(worker)
+		}()
+
+		ticker := time.NewTicker(cfg.ReconcileInterval.Duration)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := ReconcileCounters(ctx); err == nil {
+					metrics.CountersReconciled(time.Now())
+				} else if ctx.Err() == nil {
+					slog.Warn("failed to reconcile result counters", slog.String("error", err.Error()))
+				}
+				worker.Beat()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
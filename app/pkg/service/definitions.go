@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"service-poll/pkg/api"
+	"service-poll/pkg/apperror"
+	"service-poll/pkg/audit"
+	"service-poll/pkg/db"
+	"service-poll/pkg/definition"
+	"service-poll/pkg/models"
+
+	"gorm.io/gorm"
+)
+
+// NewPollSummary формирует краткий ответ об опросе с загруженными вопросами
+func NewPollSummary(poll models.Poll) api.PollSummary {
+	return api.PollSummary{
+		ID:        poll.ID,
+		Title:     poll.Title,
+		URL:       poll.URL,
+		Revision:  poll.Revision,
+		Questions: len(poll.Questions),
+	}
+}
+
+// PollDefinition возвращает описание опроса с вопросами и вариантами ответов в порядке их следования.
+func PollDefinition(ctx context.Context, pollID uint) (definition.Definition, error) {
+	var existingPoll models.Poll
+	if err := db.DB.WithContext(ctx).
+		Preload("Questions", func(tx *gorm.DB) *gorm.DB { return tx.Order("id") }).
+		Preload("Questions.PossibleAnswer", func(tx *gorm.DB) *gorm.DB { return tx.Order("position, id") }).
+		First(&existingPoll, pollID).Error; err != nil {
+		return definition.Definition{}, LookupError(err, apperror.ErrPollNotFound)
+	}
+
+	return definition.FromPoll(existingPoll), nil
+}
+
+// ImportPolls создает опросы по описаниям в одной транзакции и публикует те,
+// у которых указано settings.publish.
+func ImportPolls(ctx context.Context, definitions []definition.Definition) (api.ImportPollsResponse, error) {
+	if len(definitions) == 0 {
+		return api.ImportPollsResponse{}, apperror.InvalidRequest("At least one poll definition is required")
+	}
+
+	// Собираем ошибки всех описаний до записи в базу данных; путь к полю начинается с номера описания
+	var fields []apperror.FieldError
+	for i, pollDefinition := range definitions {
+		if err := pollDefinition.Validate(); err != nil {
+			fields = append(fields, apperror.As(err).WithFieldPrefix(fmt.Sprintf("polls[%d]", i)).Fields...)
+		}
+	}
+	if len(fields) > 0 {
+		return api.ImportPollsResponse{}, apperror.Validation(fields...)
+	}
+
+	// Создаем опросы и при необходимости публикуем их
+	var importedPolls []models.Poll
+	if err := db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, pollDefinition := range definitions {
+			poll, err := definition.Create(tx, pollDefinition)
+			if err != nil {
+				return err
+			}
+
+			if pollDefinition.Settings.Publish {
+				if _, err := PublishRevision(tx, &poll); err != nil {
+					return err
+				}
+			}
+
+			if err := audit.Record(ctx, tx, poll.ID, nil, audit.ActionImportPoll, audit.Diff(nil, auditPoll(poll))); err != nil {
+				return err
+			}
+
+			importedPolls = append(importedPolls, poll)
+		}
+		return nil
+	}); err != nil {
+		return api.ImportPollsResponse{}, apperror.Internal("Failed to import polls", err)
+	}
+
+	var importResponse api.ImportPollsResponse
+
+	for _, poll := range importedPolls {
+		importResponse.Polls = append(importResponse.Polls, NewPollSummary(poll))
+	}
+
+	return importResponse, nil
+}
+
+// ClonePoll создает копию опроса с вопросами и вариантами ответов (без ответов) в виде
+// неопубликованного черновика с новым адресом.
+func ClonePoll(ctx context.Context, pollID uint, request api.ClonePollRequest) (api.PollSummary, error) {
+	// Проверяем, существует ли опрос с указанным идентификатором
+	pollDefinition, err := PollDefinition(ctx, pollID)
+	if err != nil {
+		return api.PollSummary{}, err
+	}
+
+	if err := Validate(request); err != nil {
+		return api.PollSummary{}, err
+	}
+
+	// Копия всегда создается черновиком с новым адресом
+	pollDefinition.URL = request.URL
+	pollDefinition.Settings.Publish = false
+	if request.Title != "" {
+		pollDefinition.Title = request.Title
+	}
+
+	var clonedPoll models.Poll
+	if err := db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if clonedPoll, err = definition.Create(tx, pollDefinition); err != nil {
+			return err
+		}
+
+		// Запись журнала аудита ссылается на исходный опрос
+		after := auditPoll(clonedPoll)
+		after["cloned_from"] = pollID
+		return audit.Record(ctx, tx, clonedPoll.ID, nil, audit.ActionClonePoll, audit.Diff(nil, after))
+	}); err != nil {
+		return api.PollSummary{}, apperror.Internal("Failed to clone poll", err)
+	}
+
+	return NewPollSummary(clonedPoll), nil
+}
@@ -0,0 +1,487 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"service-poll/pkg/api"
+	"service-poll/pkg/apperror"
+	"service-poll/pkg/config"
+	"service-poll/pkg/db"
+	"service-poll/pkg/health"
+	"service-poll/pkg/metrics"
+	"service-poll/pkg/models"
+
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+)
+
+// ingest конвейер асинхронного приема ответов; nil, если прием выключен
+var ingest atomic.Pointer[ingestPipeline]
+
+// ingestMaxIdle время без сигналов фонового обработчика, после которого он считается зависшим
+const ingestMaxIdle = time.Minute
+
+// ingestPipeline очередь проверенных ответов, которые фоновый обработчик сохраняет пачками
+type ingestPipeline struct {
+	cfg    config.IngestConfig
+	worker *health.Worker
+	queue  chan submission
+
+	// mu защищает closed: отправка в очередь идет под RLock, закрытие очереди — под Lock
+	mu     sync.RWMutex
+	closed bool
+
+	structures *structureCache
+	receipts   *receiptStore
+}
+
+// submission проверенные ответы пользователя вместе с квитанцией
+type submission struct {
+	receipt  models.AnswerReceipt
+	revision uint // номер ревизии, по которой даны ответы, 0 — опрос не опубликован
+	answers  []newAnswer
+}
+
+// StartIngest включает асинхронный прием ответов и возвращает фоновый обработчик очереди.
+// Обработчик должен работать, пока прием нужен сервису: после отмены контекста
+// он перестает принимать ответы, сохраняет оставшиеся в очереди и выключает прием.
+func StartIngest(cfg config.IngestConfig) func(ctx context.Context) {
+	p := &ingestPipeline{
+		cfg:        cfg,
+		worker:     health.RegisterWorker("ingest", ingestMaxIdle),
+		queue:      make(chan submission, cfg.QueueSize),
+		structures: &structureCache{ttl: cfg.CacheTTL.Duration, entries: make(map[uint]pollStructure)},
+		receipts:   &receiptStore{ttl: cfg.FailedReceiptTTL.Duration, receipts: make(map[string]storedReceipt)},
+	}
+	ingest.Store(p)
+	return p.run
+}
+
+// IngestEnabled сообщает, что ответы принимаются асинхронно
+func IngestEnabled() bool {
+	return ingest.Load() != nil
+}
+
+// SubmitAnswer проверяет ответы пользователя по структуре опроса из кэша и ставит их
+// в очередь на сохранение. Возвращает квитанцию в состоянии pending или
+// ErrIngestUnavailable, если очередь заполнена или прием выключен.
+func SubmitAnswer(ctx context.Context, pollID uint, request api.AnswerRequest) (api.ReceiptResponse, error) {
+	p := ingest.Load()
+	if p == nil {
+		return api.ReceiptResponse{}, apperror.ErrIngestUnavailable
+	}
+
+	structure, err := p.structures.get(ctx, pollID)
+	if err != nil {
+		if err == apperror.ErrPollNotFound {
+			metrics.AnswerValidationFailed(metrics.ReasonPollNotFound)
+		}
+		return api.ReceiptResponse{}, err
+	}
+
+	if err := Validate(request); err != nil {
+		metrics.AnswerValidationFailed(metrics.ReasonInvalidRequest)
+		return api.ReceiptResponse{}, err
+	}
+
+	answers, err := buildAnswers(pollID, structure.questions, structure.revisionID, request.Attributes)
+	if err != nil {
+		return api.ReceiptResponse{}, err
+	}
+
+	id, err := newReceiptID()
+	if err != nil {
+		return api.ReceiptResponse{}, apperror.Internal("Failed to create receipt", err)
+	}
+	receipt := models.AnswerReceipt{
+		ID:         id,
+		PollID:     pollID,
+		UserID:     request.Attributes.UserID,
+		Answers:    len(answers),
+		AcceptedAt: time.Now(),
+	}
+
+	response, err := p.enqueue(submission{receipt: receipt, revision: structure.revision, answers: answers})
+	if err != nil {
+		metrics.IngestSubmissions(metrics.IngestRejected, 1)
+		return api.ReceiptResponse{}, err
+	}
+	metrics.IngestSubmissions(metrics.IngestAccepted, 1)
+	return response, nil
+}
+
+// Receipt возвращает состояние квитанции: pending и failed — из памяти этого экземпляра
+// сервиса, persisted — из базы данных
+func Receipt(ctx context.Context, id string) (api.ReceiptResponse, error) {
+	if p := ingest.Load(); p != nil {
+		if receipt, ok := p.receipts.get(id); ok {
+			return receipt, nil
+		}
+	}
+
+	var receipt models.AnswerReceipt
+	if err := db.DB.WithContext(ctx).First(&receipt, "id = ?", id).Error; err != nil {
+		return api.ReceiptResponse{}, LookupError(err, apperror.ErrReceiptNotFound)
+	}
+
+	response := newReceiptResponse(receipt, api.ReceiptPersisted)
+	response.PersistedAt = &receipt.CreatedAt
+	return response, nil
+}
+
+// forgetPollStructure сбрасывает структуру опроса в кэше приема ответов после изменения
+// вопросов, публикации или удаления опроса
+func forgetPollStructure(pollID uint) {
+	if p := ingest.Load(); p != nil {
+		p.structures.forget(pollID)
+	}
+}
+
+// enqueue ставит ответы в очередь без ожидания. Квитанция добавляется до отправки,
+// чтобы обработчик не сохранил ответы раньше, чем она появится.
+func (p *ingestPipeline) enqueue(s submission) (api.ReceiptResponse, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return api.ReceiptResponse{}, apperror.ErrIngestUnavailable
+	}
+
+	response := newReceiptResponse(s.receipt, api.ReceiptPending)
+	p.receipts.put(response, time.Time{})
+	select {
+	case p.queue <- s:
+		metrics.IngestQueueLength(len(p.queue))
+		return response, nil
+	default:
+		p.receipts.remove(response.ID)
+		return api.ReceiptResponse{}, apperror.ErrIngestUnavailable
+	}
+}
+
+// run сохраняет ответы из очереди пачками по BatchSize или по истечении FlushInterval
+func (p *ingestPipeline) run(ctx context.Context) {
+	p.worker.Start()
+	defer func() {
+		p.worker.Stop()
+		health.UnregisterWorker(p.worker)
+		ingest.CompareAndSwap(p, nil)
+	}()
+
+	ticker := time.NewTicker(p.cfg.FlushInterval.Duration)
+	defer ticker.Stop()
+
+	batch := make([]submission, 0, p.cfg.BatchSize)
+	flush := func() {
+		if len(batch) > 0 {
+			p.persist(batch)
+			batch = batch[:0]
+		}
+	}
+
+	for {
+		select {
+		case s := <-p.queue:
+			batch = append(batch, s)
+			if len(batch) >= p.cfg.BatchSize {
+				flush()
+			}
+		case now := <-ticker.C:
+			p.worker.Beat()
+			flush()
+			p.receipts.expire(now)
+			p.structures.expire(now)
+		case <-ctx.Done():
+			// Новые ответы отклоняются, а уже принятые сохраняются до выхода
+			p.mu.Lock()
+			p.closed = true
+			p.mu.Unlock()
+			close(p.queue)
+
+			for s := range p.queue {
+				batch = append(batch, s)
+				if len(batch) >= p.cfg.BatchSize {
+					flush()
+				}
+			}
+			flush()
+			return
+		}
+	}
+}
+
+// persist сохраняет пачку ответов в одной транзакции. Если транзакция не удалась,
+// ответы сохраняются по одному, чтобы ошибка одного ответа не отменила остальные.
+func (p *ingestPipeline) persist(batch []submission) {
+	metrics.IngestBatch(len(batch))
+	metrics.IngestQueueLength(len(p.queue))
+
+	if saved, rejected, err := save(batch); err == nil {
+		p.rejected(rejected)
+		p.persisted(saved)
+		return
+	}
+
+	for i := range batch {
+		saved, rejected, err := save(batch[i : i+1])
+		if err != nil {
+			slog.Error("failed to save answers",
+				slog.String("receipt", batch[i].receipt.ID),
+				slog.Uint64("poll_id", uint64(batch[i].receipt.PollID)),
+				slog.String("error", err.Error()))
+			p.failed(batch[i], "Failed to save answers")
+			continue
+		}
+		p.rejected(rejected)
+		p.persisted(saved)
+	}
+}
+
+// save в одной транзакции повторно проверяет, что опросы, вопросы и варианты ответов
+// пачки существуют, и сохраняет ответы, которые прошли проверку. Ответы проверялись
+// по структуре опроса из кэша при приеме, а опрос или вопрос могли удалить, пока они
+// ждали в очереди. Возвращает сохраненные и отклоненные ответы.
+func save(batch []submission) (saved, rejected []submission, err error) {
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		saved, rejected, err = currentSubmissions(tx, batch)
+		if err != nil || len(saved) == 0 {
+			return err
+		}
+		return saveSubmissions(tx, saved)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return saved, rejected, nil
+}
+
+// currentSubmissions делит пачку на ответы, опрос, вопросы и варианты которых не удалены,
+// и остальные. Найденные опросы, вопросы и варианты блокируются от изменения
+// до конца транзакции tx, чтобы их не удалили до сохранения ответов.
+func currentSubmissions(tx *gorm.DB, batch []submission) (current, rejected []submission, err error) {
+	var pollIDs []uint
+	var answers []newAnswer
+	for _, s := range batch {
+		pollIDs = append(pollIDs, s.receipt.PollID)
+		answers = append(answers, s.answers...)
+	}
+	targets, err := lockAnswerTargets(tx, pollIDs, answers)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, s := range batch {
+		if targets.check(s.receipt.PollID, s.answers) == nil {
+			current = append(current, s)
+		} else {
+			rejected = append(rejected, s)
+		}
+	}
+	return current, rejected, nil
+}
+
+// failed сохраняет квитанцию failed с сообщением об ошибке на время FailedReceiptTTL
+func (p *ingestPipeline) failed(s submission, message string) {
+	response := newReceiptResponse(s.receipt, api.ReceiptFailed)
+	response.Error = message
+	p.receipts.put(response, time.Now().Add(p.receipts.ttl))
+	metrics.IngestSubmissions(metrics.IngestFailed, 1)
+}
+
+// rejected помечает квитанции ответов, опрос или вопросы которых удалены, как failed
+func (p *ingestPipeline) rejected(batch []submission) {
+	for _, s := range batch {
+		slog.Warn("answers rejected: poll or question was deleted",
+			slog.String("receipt", s.receipt.ID),
+			slog.Uint64("poll_id", uint64(s.receipt.PollID)))
+		p.failed(s, "Poll or question was deleted before the answers were saved")
+	}
+}
+
+// persisted убирает квитанции сохраненных ответов из памяти, сбрасывает кэш результатов
+// и сообщает об их изменении
+func (p *ingestPipeline) persisted(batch []submission) {
+	answers := make(map[uint]int)
+	revisions := make(map[[2]uint]bool) // опрос и ревизия
+	for _, s := range batch {
+		p.receipts.remove(s.receipt.ID)
+		answers[s.receipt.PollID] += len(s.answers)
+		revisions[[2]uint{s.receipt.PollID, s.revision}] = true
+	}
+	for revision := range revisions {
+		invalidateResults(context.Background(), revision[0], revision[1])
+	}
+	for pollID, count := range answers {
+		metrics.AnswersRegistered(pollID, metrics.AnswersAsync, count)
+		notifyResults(pollID)
+	}
+	metrics.IngestSubmissions(metrics.IngestPersisted, len(batch))
+}
+
+// saveSubmissions сохраняет ответы и квитанции в транзакции tx
+func saveSubmissions(tx *gorm.DB, batch []submission) error {
+	var answers []newAnswer
+	receipts := make([]models.AnswerReceipt, len(batch))
+	for i, s := range batch {
+		answers = append(answers, s.answers...)
+		receipts[i] = s.receipt
+	}
+	if err := saveAnswers(tx, answers); err != nil {
+		return err
+	}
+	return tx.CreateInBatches(&receipts, answerBatchSize).Error
+}
+
+// newReceiptID возвращает случайный идентификатор квитанции из 32 шестнадцатеричных символов
+func newReceiptID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+// newReceiptResponse квитанция в указанном состоянии
+func newReceiptResponse(receipt models.AnswerReceipt, status string) api.ReceiptResponse {
+	return api.ReceiptResponse{
+		ID:         receipt.ID,
+		Status:     status,
+		PollID:     receipt.PollID,
+		UserID:     receipt.UserID,
+		AcceptedAt: receipt.AcceptedAt,
+	}
+}
+
+// pollStructure вопросы опроса, на которые принимаются ответы
+type pollStructure struct {
+	questions  map[string]answerQuestion
+	revisionID *uint
+	revision   uint // номер актуальной ревизии
+	expires    time.Time
+}
+
+// structureCache кэш структуры опросов для проверки ответов без запросов к базе данных
+type structureCache struct {
+	ttl   time.Duration
+	group singleflight.Group
+
+	mu      sync.Mutex
+	entries map[uint]pollStructure
+	// generation увеличивается при каждом сбросе, чтобы загрузка, начатая до сброса,
+	// не сохранила в кэш устаревшую структуру
+	generation uint64
+}
+
+// get возвращает структуру опроса из кэша или загружает ее; одновременные загрузки
+// одного опроса объединяются в одну
+func (c *structureCache) get(ctx context.Context, pollID uint) (pollStructure, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[pollID]
+	generation := c.generation
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry, nil
+	}
+
+	// Загрузку используют все ожидающие запросы, поэтому отмена первого из них ее не прерывает
+	loadCtx := context.WithoutCancel(ctx)
+	value, err, _ := c.group.Do(fmt.Sprintf("%d/%d", pollID, generation), func() (interface{}, error) {
+		poll, err := findPoll(loadCtx, pollID)
+		if err != nil {
+			return nil, err
+		}
+		questions, revisionID, err := loadAnswerQuestions(loadCtx, poll)
+		if err != nil {
+			return nil, apperror.Internal("Failed to load poll questions", err)
+		}
+
+		entry := pollStructure{questions: questions, revisionID: revisionID, revision: poll.Revision, expires: time.Now().Add(c.ttl)}
+		c.mu.Lock()
+		if c.generation == generation {
+			c.entries[pollID] = entry
+		}
+		c.mu.Unlock()
+		return entry, nil
+	})
+	if err != nil {
+		return pollStructure{}, err
+	}
+	return value.(pollStructure), nil
+}
+
+// forget сбрасывает структуру опроса
+func (c *structureCache) forget(pollID uint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, pollID)
+	c.generation++
+}
+
+// expire удаляет устаревшие структуры
+func (c *structureCache) expire(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for pollID, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, pollID)
+		}
+	}
+}
+
+// storedReceipt квитанция в памяти; expires задан только у квитанций failed
+type storedReceipt struct {
+	receipt api.ReceiptResponse
+	expires time.Time
+}
+
+// receiptStore квитанции ответов, которые еще не сохранены или которые не удалось сохранить
+type receiptStore struct {
+	ttl time.Duration
+
+	mu       sync.Mutex
+	receipts map[string]storedReceipt
+}
+
+func (s *receiptStore) put(receipt api.ReceiptResponse, expires time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.receipts[receipt.ID] = storedReceipt{receipt: receipt, expires: expires}
+}
+
+func (s *receiptStore) remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.receipts, id)
+}
+
+func (s *receiptStore) get(id string) (api.ReceiptResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.receipts[id]
+	if !ok || !stored.expires.IsZero() && !time.Now().Before(stored.expires) {
+		return api.ReceiptResponse{}, false
+	}
+	return stored.receipt, true
+}
+
+// expire удаляет квитанции failed, срок хранения которых истек
+func (s *receiptStore) expire(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, stored := range s.receipts {
+		if !stored.expires.IsZero() && !now.Before(stored.expires) {
+			delete(s.receipts, id)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package service
+
+import "sync"
+
+// resultSubscribers подписчики на изменения результатов по опросам
+var resultSubscribers = struct {
+	sync.Mutex
+	byPoll map[uint]map[chan struct{}]struct{}
+}{byPoll: make(map[uint]map[chan struct{}]struct{})}
+
+// SubscribeResults подписывается на изменения результатов опроса в этом процессе сервиса:
+// новые ответы, изменение вопросов и публикацию ревизий. Канал получает сигнал после
+// изменения; несколько изменений подряд могут прийти одним сигналом.
+// Возвращаемая функция отменяет подписку.
+func SubscribeResults(pollID uint) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	resultSubscribers.Lock()
+	if resultSubscribers.byPoll[pollID] == nil {
+		resultSubscribers.byPoll[pollID] = make(map[chan struct{}]struct{})
+	}
+	resultSubscribers.byPoll[pollID][ch] = struct{}{}
+	resultSubscribers.Unlock()
+
+	return ch, func() {
+		resultSubscribers.Lock()
+		delete(resultSubscribers.byPoll[pollID], ch)
+		if len(resultSubscribers.byPoll[pollID]) == 0 {
+			delete(resultSubscribers.byPoll, pollID)
+		}
+		resultSubscribers.Unlock()
+	}
+}
+
+// notifyResults сообщает подписчикам, что результаты опроса изменились
+func notifyResults(pollID uint) {
+	resultSubscribers.Lock()
+	defer resultSubscribers.Unlock()
+
+	for ch := range resultSubscribers.byPoll[pollID] {
+		// Подписчик, который еще не обработал прошлый сигнал, получит изменения вместе с ним
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"service-poll/pkg/api"
+	"service-poll/pkg/apperror"
+	"service-poll/pkg/audit"
+	"service-poll/pkg/db"
+	"service-poll/pkg/metrics"
+	"service-poll/pkg/models"
+
+	"gorm.io/gorm"
+)
+
+// findPoll возвращает опрос по идентификатору или ErrPollNotFound
+func findPoll(ctx context.Context, pollID uint) (models.Poll, error) {
+	var poll models.Poll
+	if err := db.DB.WithContext(ctx).First(&poll, pollID).Error; err != nil {
+		return models.Poll{}, LookupError(err, apperror.ErrPollNotFound)
+	}
+	return poll, nil
+}
+
+// CreatePoll создает новый опрос.
+func CreatePoll(ctx context.Context, request api.CreatePollRequest) (models.Poll, error) {
+	if err := Validate(request); err != nil {
+		return models.Poll{}, err
+	}
+
+	// Создаем новый объект Poll
+	newPoll := models.Poll{
+		Title: request.Title,
+		URL:   request.URL,
+	}
+
+	// Сохраняем новый опрос и запись журнала аудита в одной транзакции
+	if err := db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&newPoll).Error; err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, newPoll.ID, nil, audit.ActionCreatePoll, audit.Diff(nil, auditPoll(newPoll)))
+	}); err != nil {
+		return models.Poll{}, apperror.Internal("Failed to create poll", err)
+	}
+
+	return newPoll, nil
+}
+
+// DefaultPollsPageSize количество опросов на странице списка по умолчанию
+const DefaultPollsPageSize = 20
+
+// ListPolls возвращает страницу опросов по возрастанию идентификатора с количеством вопросов.
+func ListPolls(ctx context.Context, request api.ListPollsRequest) (api.PollListResponse, error) {
+	if err := Validate(request); err != nil {
+		return api.PollListResponse{}, err
+	}
+
+	listResponse := api.PollListResponse{Polls: []api.PollSummary{}}
+	if err := db.DB.WithContext(ctx).Model(&models.Poll{}).Count(&listResponse.Total).Error; err != nil {
+		return api.PollListResponse{}, apperror.Internal("Failed to count polls", err)
+	}
+
+	var polls []models.Poll
+	if err := db.DB.WithContext(ctx).Order("id").Limit(request.Limit).Offset(request.Offset).Find(&polls).Error; err != nil {
+		return api.PollListResponse{}, apperror.Internal("Failed to fetch polls", err)
+	}
+	if len(polls) == 0 {
+		return listResponse, nil
+	}
+
+	// Количество вопросов всех опросов страницы считаем одним запросом
+	pollIDs := make([]uint, 0, len(polls))
+	for _, poll := range polls {
+		pollIDs = append(pollIDs, poll.ID)
+	}
+	var counts []struct {
+		PollID uint
+		Count  int
+	}
+	if err := db.DB.WithContext(ctx).Model(&models.Question{}).
+		Select("poll_id, COUNT(*) AS count").
+		Where("poll_id IN ?", pollIDs).
+		Group("poll_id").
+		Scan(&counts).Error; err != nil {
+		return api.PollListResponse{}, apperror.Internal("Failed to count questions", err)
+	}
+	questionCounts := make(map[uint]int, len(counts))
+	for _, count := range counts {
+		questionCounts[count.PollID] = count.Count
+	}
+
+	for _, poll := range polls {
+		summary := NewPollSummary(poll)
+		summary.Questions = questionCounts[poll.ID]
+		listResponse.Polls = append(listResponse.Polls, summary)
+	}
+
+	return listResponse, nil
+}
+
+// GetPoll возвращает опрос с вопросами и вариантами ответов.
+func GetPoll(ctx context.Context, pollID uint) (api.PollResponse, error) {
+	return cached(ctx, metrics.CachePoll, pollCacheKey(pollID), func() (api.PollResponse, error) {
+		return loadPoll(ctx, pollID)
+	})
+}
+
+// loadPoll загружает опрос с вопросами и вариантами ответов из базы данных
+func loadPoll(ctx context.Context, pollID uint) (api.PollResponse, error) {
+	// Проверяем, существует ли опрос с указанным идентификатором
+	var existingPoll models.Poll
+	if err := db.DB.WithContext(ctx).Preload("Questions.PossibleAnswer", func(tx *gorm.DB) *gorm.DB { return tx.Order("position, id") }).First(&existingPoll, pollID).Error; err != nil {
+		return api.PollResponse{}, LookupError(err, apperror.ErrPollNotFound)
+	}
+
+	// Формируем ответ с данными об опросе
+	pollResponse := api.PollResponse{
+		ID:       existingPoll.ID,
+		Title:    existingPoll.Title,
+		URL:      existingPoll.URL,
+		Revision: existingPoll.Revision,
+	}
+
+	for _, question := range existingPoll.Questions {
+		questionResponse := api.PollQuestion{
+			ID:   question.ID,
+			Text: question.Text,
+			Type: question.Type,
+		}
+
+		for _, possibleAnswer := range question.PossibleAnswer {
+			questionResponse.PossibleAnswers = append(questionResponse.PossibleAnswers, api.PollOption{
+				ID:   possibleAnswer.ID,
+				Text: possibleAnswer.Text,
+			})
+		}
+
+		pollResponse.Questions = append(pollResponse.Questions, questionResponse)
+	}
+
+	return pollResponse, nil
+}
+
+// UpdatePoll изменяет название и (или) URL опроса.
+func UpdatePoll(ctx context.Context, pollID uint, request api.UpdatePollRequest) (models.Poll, error) {
+	// Проверяем, существует ли опрос с указанным идентификатором
+	existingPoll, err := findPoll(ctx, pollID)
+	if err != nil {
+		return models.Poll{}, err
+	}
+
+	if err := Validate(request); err != nil {
+		return models.Poll{}, err
+	}
+
+	// Запоминаем состояние опроса до изменения для журнала аудита
+	before := map[string]interface{}{
+		"title": existingPoll.Title,
+		"url":   existingPoll.URL,
+	}
+
+	// Обновляем данные опроса, если они предоставлены
+	if request.Title != "" {
+		existingPoll.Title = request.Title
+	}
+
+	if request.URL != "" {
+		existingPoll.URL = request.URL
+	}
+
+	after := map[string]interface{}{
+		"title": existingPoll.Title,
+		"url":   existingPoll.URL,
+	}
+
+	// Сохраняем обновленный опрос и запись журнала аудита в одной транзакции
+	if err := db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&existingPoll).Error; err != nil {
+			return err
+		}
+		return audit.Record(ctx, tx, existingPoll.ID, nil, audit.ActionUpdatePoll, audit.Diff(before, after))
+	}); err != nil {
+		return models.Poll{}, apperror.Internal("Failed to update poll", err)
+	}
+	invalidatePoll(ctx, existingPoll)
+
+	return existingPoll, nil
+}
+
+// DeletePoll удаляет опрос вместе с вопросами, вариантами ответов и ответами.
+func DeletePoll(ctx context.Context, pollID uint) (api.MessageResponse, error) {
+	// Проверяем, существует ли опрос с указанным идентификатором
+	var existingPoll models.Poll
+	if err := db.DB.WithContext(ctx).Preload("Questions.PossibleAnswer.Answers").Preload("Answers").First(&existingPoll, pollID).Error; err != nil {
+		return api.MessageResponse{}, LookupError(err, apperror.ErrPollNotFound)
+	}
+
+	// Ответы, вопросы, варианты ответов, счетчики результатов и опрос удаляются в одной
+	// транзакции с записью журнала аудита
+	if err := db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Удаляем связанные ответы
+		for _, answer := range existingPoll.Answers {
+			if err := tx.Delete(&answer).Error; err != nil {
+				return err
+			}
+		}
+
+		// Удаляем связанные вопросы и их варианты ответов
+		questionIDs := make([]uint, 0, len(existingPoll.Questions))
+		for _, question := range existingPoll.Questions {
+			// Удаляем варианты ответов
+			for _, possibleAnswer := range question.PossibleAnswer {
+				if err := tx.Delete(&possibleAnswer).Error; err != nil {
+					return err
+				}
+			}
+
+			// Удаляем вопрос
+			if err := tx.Delete(&question).Error; err != nil {
+				return err
+			}
+			questionIDs = append(questionIDs, question.ID)
+		}
+
+		if err := deleteQuestionCounters(tx, questionIDs); err != nil {
+			return err
+		}
+
+		// Удаляем опрос
+		if err := tx.Delete(&existingPoll).Error; err != nil {
+			return err
+		}
+
+		// Записываем удаление в журнал аудита
+		before := map[string]interface{}{
+			"title": existingPoll.Title,
+			"url":   existingPoll.URL,
+		}
+		return audit.Record(ctx, tx, existingPoll.ID, nil, audit.ActionDeletePoll, audit.Diff(before, nil))
+	}); err != nil {
+		return api.MessageResponse{}, apperror.Internal("Failed to delete poll", err)
+	}
+	forgetPollStructure(existingPoll.ID)
+	invalidatePoll(ctx, existingPoll)
+	metrics.ForgetPoll(existingPoll.ID)
+
+	return api.MessageResponse{Message: fmt.Sprintf("Poll '%s' deleted successfully", existingPoll.Title)}, nil
+}
+
+// PollResults возвращает результаты опроса по текущим вопросам.
+func PollResults(ctx context.Context, pollID uint) (api.PollResultsResponse, error) {
+	return cached(ctx, metrics.CacheResults, resultsCacheKey(pollID, ""), func() (api.PollResultsResponse, error) {
+		return calculatePollResults(ctx, pollID)
+	})
+}
+
+// calculatePollResults собирает результаты опроса по текущим вопросам из счетчиков
+// результатов. В результаты попадают варианты, за которые голосовали, в порядке
+// вопросов и вариантов; доля считается от количества ответов на вопрос.
+func calculatePollResults(ctx context.Context, pollID uint) (api.PollResultsResponse, error) {
+	// Проверяем, существует ли опрос с указанным идентификатором
+	existingPoll, err := findPoll(ctx, pollID)
+	if err != nil {
+		return api.PollResultsResponse{}, err
+	}
+
+	// Загружаем вопросы и варианты ответов в порядке их следования
+	var questions []models.Question
+	if err := db.DB.WithContext(ctx).Where("poll_id = ?", existingPoll.ID).Order("id").
+		Preload("PossibleAnswer", func(tx *gorm.DB) *gorm.DB { return tx.Order("position, id") }).
+		Find(&questions).Error; err != nil {
+		return api.PollResultsResponse{}, apperror.Internal("Failed to fetch questions", err)
+	}
+
+	questionIDs := make([]uint, len(questions))
+	for i, question := range questions {
+		questionIDs[i] = question.ID
+	}
+	counts, err := LoadCounts(ctx, questionIDs)
+	if err != nil {
+		return api.PollResultsResponse{}, apperror.Internal("Failed to fetch result counters", err)
+	}
+
+	pollResults := api.PollResultsResponse{ID: existingPoll.ID}
+	for _, question := range questions {
+		questionCounts := counts[question.ID]
+		for _, possibleAnswer := range question.PossibleAnswer {
+			answerCount := questionCounts.Votes[possibleAnswer.ID]
+			if answerCount == 0 {
+				continue
+			}
+			pollResults.Results = append(pollResults.Results, api.PollResult{
+				Question:         question.Text,
+				Answer:           possibleAnswer.Text,
+				AnswerCnt:        answerCount,
+				AnswerPercentage: AnswerPercentage(answerCount, questionCounts.Answers),
+			})
+		}
+	}
+
+	return pollResults, nil
+}
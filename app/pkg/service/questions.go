@@ -0,0 +1,425 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"service-poll/pkg/api"
+	"service-poll/pkg/apperror"
+	"service-poll/pkg/audit"
+	"service-poll/pkg/db"
+	"service-poll/pkg/models"
+
+	"gorm.io/gorm"
+)
+
+// findQuestion возвращает вопрос опроса по идентификатору или ErrQuestionNotFound
+func findQuestion(ctx context.Context, pollID, questionID uint) (models.Question, error) {
+	var question models.Question
+	if err := db.DB.WithContext(ctx).Where("id = ? AND poll_id = ?", questionID, pollID).First(&question).Error; err != nil {
+		return models.Question{}, LookupError(err, apperror.ErrQuestionNotFound)
+	}
+	return question, nil
+}
+
+// validateQuestionType проверяет тип вопроса
+func validateQuestionType(questionType string) error {
+	if questionType != "single" && questionType != "multiple" {
+		return apperror.Invalid("type", "oneof", "must be one of: single, multiple")
+	}
+	return nil
+}
+
+// AddQuestion добавляет вопрос с вариантами ответов к опросу.
+func AddQuestion(ctx context.Context, pollID uint, request api.AddQuestionRequest) (models.Question, error) {
+	// Проверяем, существует ли опрос с указанным идентификатором
+	existingPoll, err := findPoll(ctx, pollID)
+	if err != nil {
+		return models.Question{}, err
+	}
+
+	if err := Validate(request); err != nil {
+		return models.Question{}, err
+	}
+
+	// Проверка значения Type
+	if err := validateQuestionType(request.Type); err != nil {
+		return models.Question{}, err
+	}
+
+	// Создаем новый объект Question
+	newQuestion := models.Question{
+		Text:   request.Text,
+		Type:   request.Type,
+		PollID: existingPoll.ID,
+	}
+
+	// Сохраняем вопрос, варианты ответов и запись журнала аудита в одной транзакции
+	var createdAnswers []models.PossibleAnswer
+	if err := db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&newQuestion).Error; err != nil {
+			return err
+		}
+
+		// Сохраняем варианты ответов
+		for position, answerText := range request.Answers {
+			possibleAnswer := models.PossibleAnswer{
+				Text:       answerText,
+				QuestionID: newQuestion.ID,
+				Position:   position,
+			}
+
+			if err := tx.Create(&possibleAnswer).Error; err != nil {
+				return err
+			}
+			createdAnswers = append(createdAnswers, possibleAnswer)
+		}
+
+		// Записываем добавление вопроса в журнал аудита
+		after := map[string]interface{}{
+			"text":    newQuestion.Text,
+			"type":    newQuestion.Type,
+			"options": auditOptions(createdAnswers),
+		}
+		return audit.Record(ctx, tx, existingPoll.ID, &newQuestion.ID, audit.ActionAddQuestion, audit.Diff(nil, after))
+	}); err != nil {
+		return models.Question{}, apperror.Internal("Failed to create question", err)
+	}
+
+	forgetPollStructure(existingPoll.ID)
+	invalidatePoll(ctx, existingPoll)
+	notifyResults(existingPoll.ID)
+
+	// newQuestion.Answers = createdAnswers
+	return newQuestion, nil
+}
+
+// UpdateQuestion изменяет вопрос и его варианты ответов.
+// Варианты, за которые уже голосовали, удаляются только при force.
+func UpdateQuestion(ctx context.Context, pollID, questionID uint, request api.UpdateQuestionRequest, force bool) (models.Question, error) {
+	// Проверяем, существует ли опрос с указанным идентификатором
+	existingPoll, err := findPoll(ctx, pollID)
+	if err != nil {
+		return models.Question{}, err
+	}
+
+	// Проверяем, существует ли вопрос с указанным идентификатором в рамках данного опроса
+	existingQuestion, err := findQuestion(ctx, existingPoll.ID, questionID)
+	if err != nil {
+		return models.Question{}, err
+	}
+
+	// Получаем текущие варианты ответов вопроса в порядке их следования
+	var possibleAnswers []models.PossibleAnswer
+	if err := db.DB.WithContext(ctx).Where("question_id = ?", existingQuestion.ID).Order("position, id").Find(&possibleAnswers).Error; err != nil {
+		return models.Question{}, apperror.Internal("Failed to fetch possible answers", err)
+	}
+
+	// Новый список вариантов (options) или прежний (answers)
+	if err := Validate(request); err != nil {
+		return models.Question{}, err
+	}
+
+	// Проверка значения Type
+	if err := validateQuestionType(request.Type); err != nil {
+		return models.Question{}, err
+	}
+
+	// Варианты ответов передаются либо в новом формате, либо в прежнем
+	if len(request.Options) > 0 && len(request.Answers) > 0 {
+		return models.Question{}, apperror.Invalid("answers", "exclusive", "only one of 'options' or 'answers' may be specified")
+	}
+
+	options, optionsField := request.Options, "options"
+	if len(options) == 0 {
+		if len(request.Answers) == 0 {
+			return models.Question{}, apperror.Invalid("options", "required", "either 'options' or 'answers' is required")
+		}
+		options, optionsField = optionUpdatesFromAnswers(possibleAnswers, request.Answers), "answers"
+	}
+
+	// Разбираем, какие варианты переименовываются, добавляются и удаляются
+	plan, err := planOptionUpdates(possibleAnswers, options, optionsField)
+	if err != nil {
+		return models.Question{}, err
+	}
+
+	// Считаем голоса за удаляемые варианты
+	removedVotes, err := countOptionVotes(ctx, plan.removed)
+	if err != nil {
+		return models.Question{}, apperror.Internal("Failed to count votes", err)
+	}
+
+	// Варианты, за которые уже голосовали, удаляются только при force=true
+	var votedOptions []string
+	var totalRemovedVotes int64
+	for _, possibleAnswer := range plan.removed {
+		if votes := removedVotes[possibleAnswer.ID]; votes > 0 {
+			votedOptions = append(votedOptions, possibleAnswer.Text)
+			totalRemovedVotes += votes
+		}
+	}
+	if len(votedOptions) > 0 && !force {
+		return models.Question{}, apperror.Conflict(apperror.CodeOptionsHaveVotes, "Options with votes cannot be removed without force=true").
+			With("options", votedOptions)
+	}
+
+	// Запоминаем состояние вопроса до изменения для журнала аудита
+	before := map[string]interface{}{
+		"text":    existingQuestion.Text,
+		"type":    existingQuestion.Type,
+		"options": auditOptions(possibleAnswers),
+	}
+
+	// Обновляем данные вопроса
+	existingQuestion.Text = request.Text
+	existingQuestion.Type = request.Type
+
+	// Сохраняем вопрос, варианты ответов и запись журнала аудита в одной транзакции
+	var updatedAnswers []models.PossibleAnswer
+	if err := db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&existingQuestion).Error; err != nil {
+			return err
+		}
+
+		// Удаляем варианты, отсутствующие в запросе, вместе с голосами за них и их счетчиками
+		removedIDs := make([]uint, 0, len(plan.removed))
+		for _, possibleAnswer := range plan.removed {
+			if err := tx.Delete(&possibleAnswer).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("possible_answer_id = ?", possibleAnswer.ID).Delete(&models.AnswerPossibleAnswer{}).Error; err != nil {
+				return err
+			}
+			removedIDs = append(removedIDs, possibleAnswer.ID)
+		}
+		if err := deleteOptionCounters(tx, removedIDs); err != nil {
+			return err
+		}
+
+		// Переименовываем, переставляем и добавляем варианты в порядке запроса
+		for position, possibleAnswer := range plan.ordered {
+			possibleAnswer.Position = position
+			possibleAnswer.QuestionID = existingQuestion.ID
+			if err := tx.Save(&possibleAnswer).Error; err != nil {
+				return err
+			}
+			updatedAnswers = append(updatedAnswers, possibleAnswer)
+		}
+
+		// Записываем изменения в журнал аудита вместе с удаленными вариантами и голосами
+		after := map[string]interface{}{
+			"text":    existingQuestion.Text,
+			"type":    existingQuestion.Type,
+			"options": auditOptions(updatedAnswers),
+		}
+		diff := audit.Diff(before, after)
+		if len(votedOptions) > 0 {
+			diff["removed_votes"] = audit.Change{Before: totalRemovedVotes, After: 0}
+		}
+		return audit.Record(ctx, tx, existingPoll.ID, &existingQuestion.ID, audit.ActionUpdateQuestion, diff)
+	}); err != nil {
+		return models.Question{}, apperror.Internal("Failed to update question", err)
+	}
+
+	forgetPollStructure(existingPoll.ID)
+	invalidatePoll(ctx, existingPoll)
+	notifyResults(existingPoll.ID)
+
+	// Возвращаем вопрос вместе с вариантами ответов
+	existingQuestion.PossibleAnswer = updatedAnswers
+	return existingQuestion, nil
+}
+
+// optionUpdatePlan результат сопоставления текущих вариантов ответа с запрошенными
+type optionUpdatePlan struct {
+	ordered []models.PossibleAnswer // итоговые варианты в новом порядке; новые — без ID
+	removed []models.PossibleAnswer // варианты, которые нужно удалить
+}
+
+// optionUpdatesFromAnswers переводит прежний формат (список текстов) в список вариантов,
+// сопоставляя существующие варианты по тексту.
+func optionUpdatesFromAnswers(possibleAnswers []models.PossibleAnswer, answers []string) []api.OptionUpdate {
+	idsByText := make(map[string]uint, len(possibleAnswers))
+	for _, possibleAnswer := range possibleAnswers {
+		if _, ok := idsByText[possibleAnswer.Text]; !ok {
+			idsByText[possibleAnswer.Text] = possibleAnswer.ID
+		}
+	}
+
+	options := make([]api.OptionUpdate, 0, len(answers))
+	for _, answerText := range answers {
+		option := api.OptionUpdate{Text: answerText}
+		if id, ok := idsByText[answerText]; ok {
+			option.ID = id
+			delete(idsByText, answerText)
+		}
+		options = append(options, option)
+	}
+
+	return options
+}
+
+// planOptionUpdates проверяет запрошенные варианты и определяет итоговый порядок и удаляемые варианты.
+// field — имя списка в запросе, по нему формируются пути к полям в ошибках.
+func planOptionUpdates(possibleAnswers []models.PossibleAnswer, options []api.OptionUpdate, field string) (optionUpdatePlan, error) {
+	existing := make(map[uint]models.PossibleAnswer, len(possibleAnswers))
+	for _, possibleAnswer := range possibleAnswers {
+		existing[possibleAnswer.ID] = possibleAnswer
+	}
+
+	var plan optionUpdatePlan
+	seenIDs := make(map[uint]bool, len(options))
+	seenTexts := make(map[string]bool, len(options))
+
+	for i, option := range options {
+		possibleAnswer := models.PossibleAnswer{Text: strings.TrimSpace(option.Text)}
+		optionField := fmt.Sprintf("%s[%d]", field, i)
+		// В прежнем формате элемент списка — сам текст варианта
+		textField := optionField + ".text"
+		if field == "answers" {
+			textField = optionField
+		}
+
+		if option.ID != 0 {
+			current, ok := existing[option.ID]
+			if !ok {
+				return optionUpdatePlan{}, apperror.Invalid(optionField+".id", "not_found", "Possible answer %d does not belong to the question", option.ID)
+			}
+			if seenIDs[option.ID] {
+				return optionUpdatePlan{}, apperror.Invalid(optionField+".id", "duplicate", "Possible answer %d is specified more than once", option.ID)
+			}
+			seenIDs[option.ID] = true
+
+			if possibleAnswer.Text == "" {
+				possibleAnswer.Text = current.Text
+			}
+			current.Text = possibleAnswer.Text
+			possibleAnswer = current
+		} else if possibleAnswer.Text == "" {
+			return optionUpdatePlan{}, apperror.Invalid(textField, "required", "Text is required for a new possible answer")
+		}
+
+		if seenTexts[possibleAnswer.Text] {
+			return optionUpdatePlan{}, apperror.Invalid(textField, "duplicate", "Possible answer (%s) is specified more than once", possibleAnswer.Text)
+		}
+		seenTexts[possibleAnswer.Text] = true
+
+		plan.ordered = append(plan.ordered, possibleAnswer)
+	}
+
+	for _, possibleAnswer := range possibleAnswers {
+		if !seenIDs[possibleAnswer.ID] {
+			plan.removed = append(plan.removed, possibleAnswer)
+		}
+	}
+
+	return plan, nil
+}
+
+// countOptionVotes возвращает количество голосов за каждый из указанных вариантов ответа
+func countOptionVotes(ctx context.Context, possibleAnswers []models.PossibleAnswer) (map[uint]int64, error) {
+	votes := make(map[uint]int64, len(possibleAnswers))
+	if len(possibleAnswers) == 0 {
+		return votes, nil
+	}
+
+	ids := make([]uint, 0, len(possibleAnswers))
+	for _, possibleAnswer := range possibleAnswers {
+		ids = append(ids, possibleAnswer.ID)
+	}
+
+	var rows []struct {
+		PossibleAnswerID uint
+		Count            int64
+	}
+	if err := db.DB.WithContext(ctx).Model(&models.AnswerPossibleAnswer{}).
+		Select("possible_answer_id, COUNT(*) AS count").
+		Where("possible_answer_id IN ?", ids).
+		Group("possible_answer_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		votes[row.PossibleAnswerID] = row.Count
+	}
+
+	return votes, nil
+}
+
+// auditOption вариант ответа в записи журнала аудита
+type auditOption struct {
+	ID   uint   `json:"id"`
+	Text string `json:"text"`
+}
+
+// auditOptions формирует список вариантов ответа для журнала аудита
+func auditOptions(possibleAnswers []models.PossibleAnswer) []auditOption {
+	options := make([]auditOption, 0, len(possibleAnswers))
+	for _, possibleAnswer := range possibleAnswers {
+		options = append(options, auditOption{ID: possibleAnswer.ID, Text: possibleAnswer.Text})
+	}
+	return options
+}
+
+// DeleteQuestion удаляет вопрос опроса вместе с вариантами ответов и голосами за них.
+func DeleteQuestion(ctx context.Context, pollID, questionID uint) (api.MessageResponse, error) {
+	// Проверяем, существует ли опрос с указанным идентификатором
+	existingPoll, err := findPoll(ctx, pollID)
+	if err != nil {
+		return api.MessageResponse{}, err
+	}
+
+	// Проверяем, существует ли вопрос с указанным идентификатором в рамках данного опроса
+	existingQuestion, err := findQuestion(ctx, existingPoll.ID, questionID)
+	if err != nil {
+		return api.MessageResponse{}, err
+	}
+
+	// Собираем все идентификаторы возможных вариантов ответа для данного вопроса
+	var possibleAnswerIDs []uint
+	if err := db.DB.WithContext(ctx).Model(&models.PossibleAnswer{}).Where("question_id = ?", existingQuestion.ID).Pluck("id", &possibleAnswerIDs).Error; err != nil {
+		return api.MessageResponse{}, apperror.Internal("Failed to fetch possible answer IDs", err)
+	}
+
+	// Голоса, варианты ответов, вопрос и его счетчики результатов удаляются в одной
+	// транзакции с записью журнала аудита
+	if err := db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Удаляем связанные записи из таблицы answer_possible_answers
+		if err := tx.Where("possible_answer_id IN ?", possibleAnswerIDs).Delete(&models.AnswerPossibleAnswer{}).Error; err != nil {
+			return err
+		}
+
+		// Удаляем варианты ответов
+		if err := tx.Where("question_id = ?", existingQuestion.ID).Delete(&models.PossibleAnswer{}).Error; err != nil {
+			return err
+		}
+
+		// Удаляем вопрос
+		if err := tx.Delete(&existingQuestion).Error; err != nil {
+			return err
+		}
+
+		if err := deleteQuestionCounters(tx, []uint{existingQuestion.ID}); err != nil {
+			return err
+		}
+
+		// Записываем удаление вопроса в журнал аудита
+		before := map[string]interface{}{
+			"text": existingQuestion.Text,
+			"type": existingQuestion.Type,
+		}
+		return audit.Record(ctx, tx, existingPoll.ID, &existingQuestion.ID, audit.ActionDeleteQuestion, audit.Diff(before, nil))
+	}); err != nil {
+		return api.MessageResponse{}, apperror.Internal("Failed to delete question", err)
+	}
+
+	forgetPollStructure(existingPoll.ID)
+	invalidatePoll(ctx, existingPoll)
+	notifyResults(existingPoll.ID)
+
+	// Возвращаем сообщение с текстом удаленного вопроса
+	return api.MessageResponse{Message: "Question '" + existingQuestion.Text + "' deleted successfully"}, nil
+}
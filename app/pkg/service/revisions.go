@@ -0,0 +1,471 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"service-poll/pkg/api"
+	"service-poll/pkg/apperror"
+	"service-poll/pkg/audit"
+	"service-poll/pkg/db"
+	"service-poll/pkg/metrics"
+	"service-poll/pkg/models"
+
+	"gorm.io/gorm"
+)
+
+// MergedRevision значение параметра revision для результатов, объединенных по всем ревизиям
+const MergedRevision = "merged"
+
+// newRevisionResponse формирует ответ из ревизии с загруженными вопросами и вариантами ответов
+func newRevisionResponse(revision models.PollRevision) api.Revision {
+	response := api.Revision{
+		ID:        revision.ID,
+		PollID:    revision.PollID,
+		Number:    revision.Number,
+		Title:     revision.Title,
+		URL:       revision.URL,
+		CreatedAt: revision.CreatedAt,
+		Questions: make([]api.RevisionQuestion, 0, len(revision.Questions)),
+	}
+
+	for _, question := range revision.Questions {
+		questionResponse := api.RevisionQuestion{
+			QuestionID: question.QuestionID,
+			Text:       question.Text,
+			Type:       question.Type,
+			Options:    make([]api.RevisionOption, 0, len(question.Options)),
+		}
+
+		for _, option := range question.Options {
+			questionResponse.Options = append(questionResponse.Options, api.RevisionOption{
+				PossibleAnswerID: option.PossibleAnswerID,
+				Text:             option.Text,
+			})
+		}
+
+		response.Questions = append(response.Questions, questionResponse)
+	}
+
+	return response
+}
+
+// preloadRevisionStructure загружает вопросы и варианты ответов ревизии в порядке их следования
+func preloadRevisionStructure(tx *gorm.DB) *gorm.DB {
+	return tx.
+		Preload("Questions", func(tx *gorm.DB) *gorm.DB { return tx.Order("position") }).
+		Preload("Questions.Options", func(tx *gorm.DB) *gorm.DB { return tx.Order("position") })
+}
+
+// PublishRevision создает новую ревизию опроса со снимком текущих вопросов и вариантов ответов
+// и делает ее актуальной. Должна вызываться внутри транзакции.
+func PublishRevision(tx *gorm.DB, poll *models.Poll) (models.PollRevision, error) {
+	var questions []models.Question
+	if err := tx.Preload("PossibleAnswer", func(tx *gorm.DB) *gorm.DB { return tx.Order("position, id") }).
+		Where("poll_id = ?", poll.ID).Order("id").Find(&questions).Error; err != nil {
+		return models.PollRevision{}, err
+	}
+
+	revision := models.PollRevision{
+		PollID: poll.ID,
+		Number: poll.Revision + 1,
+		Title:  poll.Title,
+		URL:    poll.URL,
+	}
+
+	for questionPosition, question := range questions {
+		revisionQuestion := models.RevisionQuestion{
+			QuestionID: question.ID,
+			Position:   questionPosition,
+			Text:       question.Text,
+			Type:       question.Type,
+		}
+
+		for optionPosition, possibleAnswer := range question.PossibleAnswer {
+			revisionQuestion.Options = append(revisionQuestion.Options, models.RevisionOption{
+				PossibleAnswerID: possibleAnswer.ID,
+				Position:         optionPosition,
+				Text:             possibleAnswer.Text,
+			})
+		}
+
+		revision.Questions = append(revision.Questions, revisionQuestion)
+	}
+
+	// Ревизия сохраняется вместе со снимками вопросов и вариантов ответов
+	if err := tx.Create(&revision).Error; err != nil {
+		return models.PollRevision{}, err
+	}
+
+	if err := tx.Model(poll).Update("revision", revision.Number).Error; err != nil {
+		return models.PollRevision{}, err
+	}
+	poll.Revision = revision.Number
+
+	return revision, nil
+}
+
+// findRevision возвращает ревизию опроса с указанным номером вместе с вопросами и вариантами ответов
+func findRevision(ctx context.Context, pollID uint, number uint) (models.PollRevision, error) {
+	var revision models.PollRevision
+	err := preloadRevisionStructure(db.DB.WithContext(ctx)).
+		Where("poll_id = ? AND number = ?", pollID, number).
+		First(&revision).Error
+	return revision, err
+}
+
+// PublishPoll публикует опрос, создавая новую неизменяемую ревизию его вопросов и вариантов ответов.
+func PublishPoll(ctx context.Context, pollID uint) (api.Revision, error) {
+	// Проверяем, существует ли опрос с указанным идентификатором
+	existingPoll, err := findPoll(ctx, pollID)
+	if err != nil {
+		return api.Revision{}, err
+	}
+
+	// Создаем ревизию в транзакции вместе с записью журнала аудита, чтобы снимок не оказался частичным
+	var revision models.PollRevision
+	before := map[string]interface{}{"revision": existingPoll.Revision}
+	if err := db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if revision, err = PublishRevision(tx, &existingPoll); err != nil {
+			return err
+		}
+		after := map[string]interface{}{"revision": revision.Number}
+		return audit.Record(ctx, tx, existingPoll.ID, nil, audit.ActionPublishPoll, audit.Diff(before, after))
+	}); err != nil {
+		return api.Revision{}, apperror.Internal("Failed to publish poll", err)
+	}
+
+	forgetPollStructure(existingPoll.ID)
+	invalidatePoll(ctx, existingPoll)
+	notifyResults(existingPoll.ID)
+
+	return newRevisionResponse(revision), nil
+}
+
+// PollRevisions возвращает список ревизий опроса.
+func PollRevisions(ctx context.Context, pollID uint) (api.RevisionsResponse, error) {
+	// Проверяем, существует ли опрос с указанным идентификатором
+	existingPoll, err := findPoll(ctx, pollID)
+	if err != nil {
+		return api.RevisionsResponse{}, err
+	}
+
+	var revisions []models.PollRevision
+	if err := db.DB.WithContext(ctx).Where("poll_id = ?", existingPoll.ID).Order("number").Find(&revisions).Error; err != nil {
+		return api.RevisionsResponse{}, apperror.Internal("Failed to fetch revisions", err)
+	}
+
+	revisionsResponse := api.RevisionsResponse{
+		PollID:    existingPoll.ID,
+		Current:   existingPoll.Revision,
+		Revisions: make([]api.RevisionSummary, 0, len(revisions)),
+	}
+
+	for _, revision := range revisions {
+		revisionsResponse.Revisions = append(revisionsResponse.Revisions, api.RevisionSummary{
+			ID:        revision.ID,
+			Number:    revision.Number,
+			Title:     revision.Title,
+			CreatedAt: revision.CreatedAt,
+		})
+	}
+
+	return revisionsResponse, nil
+}
+
+// PollRevision возвращает снимок ревизии опроса; rev — номер ревизии из запроса.
+func PollRevision(ctx context.Context, pollID uint, rev string) (api.Revision, error) {
+	// Проверяем, существует ли опрос с указанным идентификатором
+	existingPoll, err := findPoll(ctx, pollID)
+	if err != nil {
+		return api.Revision{}, err
+	}
+
+	number, ok := parseRevisionNumber(rev)
+	if !ok {
+		return api.Revision{}, apperror.Invalid("rev", "positive", "Revision must be a positive number")
+	}
+
+	revision, err := findRevision(ctx, existingPoll.ID, number)
+	if err != nil {
+		return api.Revision{}, LookupError(err, apperror.ErrRevisionNotFound)
+	}
+
+	return newRevisionResponse(revision), nil
+}
+
+// parseRevisionNumber разбирает номер ревизии из параметра запроса
+func parseRevisionNumber(value string) (uint, bool) {
+	number, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || number == 0 {
+		return 0, false
+	}
+	return uint(number), true
+}
+
+// voteKey идентичность варианта ответа: вопрос и исходный вариант ответа
+type voteKey struct {
+	QuestionID       uint
+	PossibleAnswerID uint
+}
+
+// countVotes подсчитывает голоса по вариантам ответа и количество ответов по вопросам.
+// Если revisionID не nil, учитываются только ответы, данные по этой ревизии.
+// Связи с вариантами ответов учитываются и после их удаления, чтобы история не менялась.
+func countVotes(ctx context.Context, pollID uint, revisionID *uint) (map[voteKey]int, map[uint]int, error) {
+	votesQuery := db.DB.WithContext(ctx).Table("answer_possible_answers").
+		Select("answers.question_id AS question_id, answer_possible_answers.possible_answer_id AS possible_answer_id, COUNT(*) AS count").
+		Joins("JOIN answers ON answers.id = answer_possible_answers.answer_id").
+		Where("answers.poll_id = ? AND answers.deleted_at IS NULL", pollID).
+		Group("answers.question_id, answer_possible_answers.possible_answer_id")
+
+	totalsQuery := db.DB.WithContext(ctx).Model(&models.Answer{}).
+		Select("question_id, COUNT(*) AS count").
+		Where("poll_id = ?", pollID).
+		Group("question_id")
+
+	if revisionID != nil {
+		votesQuery = votesQuery.Where("answers.revision_id = ?", *revisionID)
+		totalsQuery = totalsQuery.Where("revision_id = ?", *revisionID)
+	}
+
+	var voteRows []struct {
+		QuestionID       uint
+		PossibleAnswerID uint
+		Count            int
+	}
+	if err := votesQuery.Scan(&voteRows).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var totalRows []struct {
+		QuestionID uint
+		Count      int
+	}
+	if err := totalsQuery.Scan(&totalRows).Error; err != nil {
+		return nil, nil, err
+	}
+
+	votes := make(map[voteKey]int, len(voteRows))
+	for _, row := range voteRows {
+		votes[voteKey{QuestionID: row.QuestionID, PossibleAnswerID: row.PossibleAnswerID}] = row.Count
+	}
+
+	totals := make(map[uint]int, len(totalRows))
+	for _, row := range totalRows {
+		totals[row.QuestionID] = row.Count
+	}
+
+	return votes, totals, nil
+}
+
+// AnswerPercentage форматирует долю голосов за вариант от общего числа ответов на вопрос
+func AnswerPercentage(count int, total int) string {
+	if total == 0 {
+		return fmt.Sprintf("%.3f", 0.0)
+	}
+	return fmt.Sprintf("%.3f", (float64(count)/float64(total))*100)
+}
+
+// RevisionResults возвращает результаты опроса по одной ревизии (revision — номер)
+// либо объединенные по всем ревизиям по идентичности вариантов ответа (revision — "merged").
+func RevisionResults(ctx context.Context, pollID uint, revisionParam string) (api.RevisionResultsResponse, error) {
+	// Кэшируются только номера в обычной записи: ответ повторяет параметр, а сбрасываются
+	// ключи с номерами ревизий без ведущих нулей
+	number, ok := parseRevisionNumber(revisionParam)
+	if revisionParam != MergedRevision && (!ok || strconv.FormatUint(uint64(number), 10) != revisionParam) {
+		return revisionResults(ctx, pollID, revisionParam)
+	}
+	return cached(ctx, metrics.CacheResults, resultsCacheKey(pollID, revisionParam), func() (api.RevisionResultsResponse, error) {
+		return revisionResults(ctx, pollID, revisionParam)
+	})
+}
+
+// revisionResults подсчитывает результаты опроса по ревизии или объединенные по ревизиям
+func revisionResults(ctx context.Context, pollID uint, revisionParam string) (api.RevisionResultsResponse, error) {
+	// Проверяем, существует ли опрос с указанным идентификатором
+	existingPoll, err := findPoll(ctx, pollID)
+	if err != nil {
+		return api.RevisionResultsResponse{}, err
+	}
+
+	pollResults := api.RevisionResultsResponse{
+		ID:       existingPoll.ID,
+		Revision: revisionParam,
+		Results:  []api.RevisionResult{},
+	}
+
+	if revisionParam == MergedRevision {
+		results, err := mergedResults(ctx, existingPoll.ID)
+		if err != nil {
+			return api.RevisionResultsResponse{}, apperror.Internal("Failed to calculate results", err)
+		}
+		pollResults.Results = append(pollResults.Results, results...)
+		return pollResults, nil
+	}
+
+	number, ok := parseRevisionNumber(revisionParam)
+	if !ok {
+		return api.RevisionResultsResponse{}, apperror.Invalid("revision", "positive", "Revision must be a positive number or 'merged'")
+	}
+
+	revision, err := findRevision(ctx, existingPoll.ID, number)
+	if err != nil {
+		return api.RevisionResultsResponse{}, LookupError(err, apperror.ErrRevisionNotFound)
+	}
+
+	votes, totals, err := countVotes(ctx, existingPoll.ID, &revision.ID)
+	if err != nil {
+		return api.RevisionResultsResponse{}, apperror.Internal("Failed to calculate results", err)
+	}
+
+	// Подписи вопросов и вариантов берем из снимка ревизии, а не из текущего состояния опроса
+	for _, question := range revision.Questions {
+		for _, option := range question.Options {
+			count := votes[voteKey{QuestionID: question.QuestionID, PossibleAnswerID: option.PossibleAnswerID}]
+			pollResults.Results = append(pollResults.Results, api.RevisionResult{
+				QuestionID:       question.QuestionID,
+				Question:         question.Text,
+				PossibleAnswerID: option.PossibleAnswerID,
+				Answer:           option.Text,
+				AnswerCnt:        count,
+				AnswerPercentage: AnswerPercentage(count, totals[question.QuestionID]),
+			})
+		}
+	}
+
+	return pollResults, nil
+}
+
+// mergedResults объединяет голоса всех ревизий по идентичности вариантов ответа.
+// Подписи берутся из последней ревизии, где встречается вариант, а для неопубликованных
+// вариантов — из текущего (или удаленного) варианта ответа.
+func mergedResults(ctx context.Context, pollID uint) ([]api.RevisionResult, error) {
+	var revisions []models.PollRevision
+	if err := preloadRevisionStructure(db.DB.WithContext(ctx)).Where("poll_id = ?", pollID).Order("number").Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+
+	votes, totals, err := countVotes(ctx, pollID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Порядок вопросов и вариантов определяется первым появлением, подписи — последним
+	var questionOrder []uint
+	optionOrder := make(map[uint][]uint)
+	questionTexts := make(map[uint]string)
+	optionTexts := make(map[voteKey]string)
+
+	addOption := func(questionID uint, questionText string, possibleAnswerID uint, optionText string) {
+		if _, ok := questionTexts[questionID]; !ok {
+			questionOrder = append(questionOrder, questionID)
+		}
+		questionTexts[questionID] = questionText
+
+		key := voteKey{QuestionID: questionID, PossibleAnswerID: possibleAnswerID}
+		if _, ok := optionTexts[key]; !ok {
+			optionOrder[questionID] = append(optionOrder[questionID], possibleAnswerID)
+		}
+		optionTexts[key] = optionText
+	}
+
+	for _, revision := range revisions {
+		for _, question := range revision.Questions {
+			for _, option := range question.Options {
+				addOption(question.QuestionID, question.Text, option.PossibleAnswerID, option.Text)
+			}
+		}
+	}
+
+	// Варианты, за которые голосовали, но которых нет ни в одной ревизии. Они добавляются
+	// по возрастанию идентификаторов, чтобы порядок результатов (а с ним ETag и дедупликация
+	// в потоке gRPC) не зависел от порядка обхода карты.
+	var missing []voteKey
+	for key := range votes {
+		if _, ok := optionTexts[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		if missing[i].QuestionID != missing[j].QuestionID {
+			return missing[i].QuestionID < missing[j].QuestionID
+		}
+		return missing[i].PossibleAnswerID < missing[j].PossibleAnswerID
+	})
+
+	if len(missing) > 0 {
+		questions, options, err := loadMissingOptions(ctx, missing)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range missing {
+			question, ok := questions[key.QuestionID]
+			if !ok {
+				return nil, fmt.Errorf("question %d: %w", key.QuestionID, gorm.ErrRecordNotFound)
+			}
+			possibleAnswer, ok := options[key.PossibleAnswerID]
+			if !ok {
+				return nil, fmt.Errorf("possible answer %d: %w", key.PossibleAnswerID, gorm.ErrRecordNotFound)
+			}
+
+			questionText := question.Text
+			if existingText, ok := questionTexts[key.QuestionID]; ok {
+				questionText = existingText
+			}
+			addOption(key.QuestionID, questionText, key.PossibleAnswerID, possibleAnswer.Text)
+		}
+	}
+
+	var results []api.RevisionResult
+	for _, questionID := range questionOrder {
+		for _, possibleAnswerID := range optionOrder[questionID] {
+			key := voteKey{QuestionID: questionID, PossibleAnswerID: possibleAnswerID}
+			count := votes[key]
+			results = append(results, api.RevisionResult{
+				QuestionID:       questionID,
+				Question:         questionTexts[questionID],
+				PossibleAnswerID: possibleAnswerID,
+				Answer:           optionTexts[key],
+				AnswerCnt:        count,
+				AnswerPercentage: AnswerPercentage(count, totals[questionID]),
+			})
+		}
+	}
+
+	return results, nil
+}
+
+// loadMissingOptions загружает вопросы и варианты ответов, в том числе удаленные,
+// одним запросом для вопросов и одним для вариантов
+func loadMissingOptions(ctx context.Context, keys []voteKey) (map[uint]models.Question, map[uint]models.PossibleAnswer, error) {
+	questionIDs := make([]uint, 0, len(keys))
+	possibleAnswerIDs := make([]uint, 0, len(keys))
+	for _, key := range keys {
+		questionIDs = append(questionIDs, key.QuestionID)
+		possibleAnswerIDs = append(possibleAnswerIDs, key.PossibleAnswerID)
+	}
+
+	var questions []models.Question
+	if err := db.DB.WithContext(ctx).Unscoped().Where("id IN ?", questionIDs).Find(&questions).Error; err != nil {
+		return nil, nil, err
+	}
+	var possibleAnswers []models.PossibleAnswer
+	if err := db.DB.WithContext(ctx).Unscoped().Where("id IN ?", possibleAnswerIDs).Find(&possibleAnswers).Error; err != nil {
+		return nil, nil, err
+	}
+
+	questionsByID := make(map[uint]models.Question, len(questions))
+	for _, question := range questions {
+		questionsByID[question.ID] = question
+	}
+	possibleAnswersByID := make(map[uint]models.PossibleAnswer, len(possibleAnswers))
+	for _, possibleAnswer := range possibleAnswers {
+		possibleAnswersByID[possibleAnswer.ID] = possibleAnswer
+	}
+	return questionsByID, possibleAnswersByID, nil
+}
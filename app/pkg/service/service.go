@@ -0,0 +1,95 @@
+// Package service предметная логика сервиса опросов: опросы, вопросы, ответы, ревизии и результаты.
+// Функции пакета не зависят от транспорта: их вызывают и обработчики HTTP (pkg/handlers),
+// и сервер gRPC (pkg/grpcserver), поэтому оба API ведут себя одинаково.
+// Ошибки возвращаются как *apperror.Error; автор изменений для журнала аудита
+// передается в контексте (audit.NewContext).
+package service
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"service-poll/pkg/apperror"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+func init() {
+	// В ошибках проверки поля называются так же, как в JSON-теле запроса
+	if validate, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return field.Name
+			}
+			return name
+		})
+	}
+}
+
+// Validate проверяет запрос по правилам binding его полей
+func Validate(request interface{}) error {
+	if err := binding.Validator.ValidateStruct(request); err != nil {
+		if appErr, ok := ValidationError(err); ok {
+			return appErr
+		}
+		return apperror.InvalidRequest("Request is invalid")
+	}
+	return nil
+}
+
+// ValidationError переводит ошибку проверки полей в ошибку с перечнем полей.
+// Возвращает false, если err не является ошибкой проверки.
+func ValidationError(err error) (*apperror.Error, bool) {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return nil, false
+	}
+
+	fields := make([]apperror.FieldError, 0, len(validationErrors))
+	for _, fieldErr := range validationErrors {
+		fields = append(fields, apperror.Field(fieldPath(fieldErr), fieldErr.Tag(), fieldMessage(fieldErr)))
+	}
+	return apperror.Validation(fields...), true
+}
+
+// LookupError переводит ошибку поиска записи: отсутствие записи — notFound,
+// любая другая ошибка базы данных — внутренняя ошибка
+func LookupError(err error, notFound *apperror.Error) *apperror.Error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return notFound
+	}
+	return apperror.Internal("Failed to fetch data", err)
+}
+
+// fieldPath возвращает путь к полю без имени корневой структуры, например "attributes.results[0].question"
+func fieldPath(fieldErr validator.FieldError) string {
+	namespace := fieldErr.Namespace()
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+// fieldMessage формирует описание нарушенного правила проверки
+func fieldMessage(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "oneof":
+		return "must be one of: " + strings.Join(strings.Fields(fieldErr.Param()), ", ")
+	case "min":
+		if fieldErr.Kind() == reflect.Slice || fieldErr.Kind() == reflect.Map {
+			return fmt.Sprintf("must contain at least %s item(s)", fieldErr.Param())
+		}
+		return "must be at least " + fieldErr.Param()
+	}
+	return fmt.Sprintf("failed the '%s' check", fieldErr.Tag())
+}
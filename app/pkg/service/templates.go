@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"service-poll/pkg/api"
+	"service-poll/pkg/apperror"
+	"service-poll/pkg/audit"
+	"service-poll/pkg/db"
+	"service-poll/pkg/definition"
+	"service-poll/pkg/models"
+
+	"gorm.io/gorm"
+)
+
+// decodeTemplate разбирает описание опроса, сохраненное в шаблоне
+func decodeTemplate(template models.Template) (api.Template, error) {
+	var pollDefinition definition.Definition
+	if err := json.Unmarshal([]byte(template.Definition), &pollDefinition); err != nil {
+		return api.Template{}, apperror.Internal("Failed to decode template", err)
+	}
+	return newTemplate(template, pollDefinition), nil
+}
+
+// newTemplate формирует ответ о шаблоне с описанием опроса pollDefinition
+func newTemplate(template models.Template, pollDefinition definition.Definition) api.Template {
+	return api.Template{
+		TemplateSummary: api.TemplateSummary{
+			ID:          template.ID,
+			Name:        template.Name,
+			Description: template.Description,
+			Parameters:  pollDefinition.Placeholders(),
+			Questions:   len(pollDefinition.Questions),
+			CreatedAt:   template.CreatedAt,
+		},
+		Definition: pollDefinition,
+	}
+}
+
+// auditTemplate формирует описание шаблона для журнала аудита
+func auditTemplate(template api.Template) map[string]interface{} {
+	return map[string]interface{}{
+		"name":        template.Name,
+		"description": template.Description,
+		"title":       template.Definition.Title,
+		"parameters":  template.Parameters,
+		"questions":   template.Questions,
+	}
+}
+
+// CreateTemplate создает шаблон опроса из описания или из существующего опроса.
+// Адрес и публикация в шаблоне не сохраняются: они задаются при создании опроса из шаблона.
+func CreateTemplate(ctx context.Context, request api.CreateTemplateRequest) (api.Template, error) {
+	if err := Validate(request); err != nil {
+		return api.Template{}, err
+	}
+
+	// Шаблон создается либо из описания, либо из существующего опроса
+	if (request.PollID == 0) == (request.Definition == nil) {
+		return api.Template{}, apperror.Invalid("definition", "exclusive", "exactly one of 'poll_id' or 'definition' is required")
+	}
+
+	var pollDefinition definition.Definition
+	if request.Definition != nil {
+		pollDefinition = *request.Definition
+	} else {
+		var err error
+		if pollDefinition, err = PollDefinition(ctx, request.PollID); err != nil {
+			return api.Template{}, err
+		}
+	}
+
+	pollDefinition.URL = ""
+	pollDefinition.Settings.Publish = false
+
+	if strings.TrimSpace(pollDefinition.Title) == "" {
+		return api.Template{}, apperror.Invalid("definition.title", "required", "is required")
+	}
+	if err := pollDefinition.ValidateQuestions(); err != nil {
+		return api.Template{}, apperror.As(err).WithFieldPrefix("definition")
+	}
+
+	definitionJSON, err := json.Marshal(pollDefinition)
+	if err != nil {
+		return api.Template{}, apperror.Internal("Failed to create template", err)
+	}
+
+	createdTemplate := models.Template{
+		Name:        request.Name,
+		Description: request.Description,
+		Definition:  string(definitionJSON),
+	}
+
+	// Повторное имя отклоняет уникальный индекс, а не предварительная проверка:
+	// так одновременные запросы не создадут два шаблона с одним именем
+	var template api.Template
+	if err := db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&createdTemplate).Error; err != nil {
+			return err
+		}
+		template = newTemplate(createdTemplate, pollDefinition)
+		return audit.RecordTemplate(ctx, tx, createdTemplate.ID, audit.ActionCreateTemplate, audit.Diff(nil, auditTemplate(template)))
+	}); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return api.Template{}, apperror.Conflict(apperror.CodeTemplateExists, fmt.Sprintf("Template (%s) already exists", request.Name))
+		}
+		return api.Template{}, apperror.Internal("Failed to create template", err)
+	}
+
+	return template, nil
+}
+
+// ListTemplates возвращает список шаблонов опросов по имени
+func ListTemplates(ctx context.Context) (api.TemplatesResponse, error) {
+	var templates []models.Template
+	if err := db.DB.WithContext(ctx).Order("name").Find(&templates).Error; err != nil {
+		return api.TemplatesResponse{}, apperror.Internal("Failed to fetch templates", err)
+	}
+
+	templatesResponse := api.TemplatesResponse{
+		Templates: make([]api.TemplateSummary, 0, len(templates)),
+	}
+	for _, template := range templates {
+		response, err := decodeTemplate(template)
+		if err != nil {
+			return api.TemplatesResponse{}, err
+		}
+		templatesResponse.Templates = append(templatesResponse.Templates, response.TemplateSummary)
+	}
+
+	return templatesResponse, nil
+}
+
+// GetTemplate возвращает шаблон опроса с описанием опроса
+func GetTemplate(ctx context.Context, templateID uint) (api.Template, error) {
+	var existingTemplate models.Template
+	if err := db.DB.WithContext(ctx).First(&existingTemplate, templateID).Error; err != nil {
+		return api.Template{}, LookupError(err, apperror.ErrTemplateNotFound)
+	}
+
+	return decodeTemplate(existingTemplate)
+}
+
+// DeleteTemplate удаляет шаблон опроса безвозвратно, чтобы его имя можно было использовать снова.
+// Опросы, созданные из шаблона, не меняются.
+func DeleteTemplate(ctx context.Context, templateID uint) (api.MessageResponse, error) {
+	var existingTemplate models.Template
+	if err := db.DB.WithContext(ctx).First(&existingTemplate, templateID).Error; err != nil {
+		return api.MessageResponse{}, LookupError(err, apperror.ErrTemplateNotFound)
+	}
+
+	template, err := decodeTemplate(existingTemplate)
+	if err != nil {
+		return api.MessageResponse{}, err
+	}
+
+	// Шаблон удаляется в одной транзакции с записью журнала аудита
+	if err := db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Delete(&existingTemplate).Error; err != nil {
+			return err
+		}
+		return audit.RecordTemplate(ctx, tx, existingTemplate.ID, audit.ActionDeleteTemplate, audit.Diff(auditTemplate(template), nil))
+	}); err != nil {
+		return api.MessageResponse{}, apperror.Internal("Failed to delete template", err)
+	}
+
+	return api.MessageResponse{Message: fmt.Sprintf("Template '%s' deleted successfully", existingTemplate.Name)}, nil
+}
+
+// InstantiateTemplate создает опрос из шаблона, подставляя параметры в заголовок и тексты
+// вопросов, и публикует его, если указано request.Publish.
+func InstantiateTemplate(ctx context.Context, templateID uint, request api.InstantiateTemplateRequest) (api.PollSummary, error) {
+	if err := Validate(request); err != nil {
+		return api.PollSummary{}, err
+	}
+
+	template, err := GetTemplate(ctx, templateID)
+	if err != nil {
+		return api.PollSummary{}, err
+	}
+
+	// Подставляем параметры и проверяем получившееся описание опроса
+	pollDefinition, err := template.Definition.Substitute(request.Params)
+	if err != nil {
+		return api.PollSummary{}, err
+	}
+	pollDefinition.URL = request.URL
+	pollDefinition.Settings.Publish = request.Publish
+
+	if err := pollDefinition.Validate(); err != nil {
+		return api.PollSummary{}, err
+	}
+
+	var newPoll models.Poll
+	if err := db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if newPoll, err = definition.Create(tx, pollDefinition); err != nil {
+			return err
+		}
+
+		if pollDefinition.Settings.Publish {
+			if _, err := PublishRevision(tx, &newPoll); err != nil {
+				return err
+			}
+		}
+
+		// Запись журнала аудита ссылается на шаблон
+		after := auditPoll(newPoll)
+		after["template_id"] = template.ID
+		return audit.Record(ctx, tx, newPoll.ID, nil, audit.ActionInstantiateTemplate, audit.Diff(nil, after))
+	}); err != nil {
+		return api.PollSummary{}, apperror.Internal("Failed to create poll from template", err)
+	}
+
+	return NewPollSummary(newPoll), nil
+}
+
+// TemplateAudit возвращает журнал изменений шаблона от новых записей к старым.
+// Шаблон удаляется безвозвратно, поэтому журнал удаленного шаблона ищется только по записям.
+func TemplateAudit(ctx context.Context, templateID uint) (api.TemplateAuditResponse, error) {
+	var auditLogs []models.AuditLog
+	if err := db.DB.WithContext(ctx).Where("template_id = ?", templateID).
+		Order("created_at DESC, id DESC").Find(&auditLogs).Error; err != nil {
+		return api.TemplateAuditResponse{}, apperror.Internal("Failed to fetch audit log", err)
+	}
+
+	// Без записей шаблон должен существовать: он мог быть создан до появления журнала шаблонов
+	if len(auditLogs) == 0 {
+		var existingTemplate models.Template
+		if err := db.DB.WithContext(ctx).First(&existingTemplate, templateID).Error; err != nil {
+			return api.TemplateAuditResponse{}, LookupError(err, apperror.ErrTemplateNotFound)
+		}
+	}
+
+	return api.TemplateAuditResponse{TemplateID: templateID, Entries: auditEntries(auditLogs)}, nil
+}